@@ -0,0 +1,361 @@
+/*******************************************************************************
+** @Author:					Thomas Bouder <Tbouder>
+** @Email:					Tbouder@protonmail.com
+** @Date:					Sunday 03 May 2020 - 10:12:47
+** @Filename:				Audit.go
+**
+** @Last modified by:		Tbouder
+** @Last modified time:		Saturday 09 May 2020 - 18:12:40
+*******************************************************************************/
+
+
+package			main
+
+import			"fmt"
+import			"net"
+import			"sync"
+import			"time"
+import			"errors"
+import			"context"
+import			"strings"
+import			"crypto/sha256"
+import			"encoding/hex"
+import			"encoding/json"
+import			"google.golang.org/grpc/peer"
+import			"google.golang.org/grpc/metadata"
+import			"github.com/prometheus/client_golang/prometheus"
+import			"github.com/prometheus/client_golang/prometheus/promauto"
+import			"github.com/panghostlin/Members/Extended"
+
+/******************************************************************************
+**	The security events are appended to the audit_events table, which can
+**	not be updated nor deleted. Each event carries the hash of the previous
+**	one : rewriting or removing an event breaks the chain, which is checked
+**	by `members audit verify`.
+**	The RPCs never wait for the database to record an event : the events are
+**	queued in an outbox and written in batches, in the background. The
+**	outbox is flushed on shutdown, once the in-flight RPCs are drained.
+******************************************************************************/
+const	AUDIT_SIGNUP = `signup`
+const	AUDIT_LOGIN_SUCCEEDED = `login.succeeded`
+const	AUDIT_LOGIN_FAILED = `login.failed`
+const	AUDIT_TOKEN_REFRESHED = `token.refreshed`
+const	AUDIT_PASSWORD_CHANGED = `password.changed`
+const	AUDIT_SESSION_REVOKED = `session.revoked`
+const	AUDIT_ADMIN_ACTION = `admin.action`
+const	AUDIT_STORAGE_RECONCILED = `storage.reconciled`
+
+const	AUDIT_OUTBOX_SIZE = 4096
+const	AUDIT_BATCH_SIZE = 100
+const	AUDIT_MAX_PENDING = 16384
+const	AUDIT_FLUSH_INTERVAL = time.Second
+const	AUDIT_WRITE_TIMEOUT = 5 * time.Second
+const	DEFAULT_AUDIT_PAGE_SIZE = 50
+const	MAX_AUDIT_PAGE_SIZE = 500
+
+/******************************************************************************
+**	The proxy forwards the address and the user agent of the member
+******************************************************************************/
+const	AUDIT_IP_HEADER = `x-forwarded-for`
+const	AUDIT_USER_AGENT_HEADER = `x-forwarded-user-agent`
+
+var		auditEventTypes = map[string]bool{
+	AUDIT_SIGNUP: true, AUDIT_LOGIN_SUCCEEDED: true, AUDIT_LOGIN_FAILED: true, AUDIT_TOKEN_REFRESHED: true,
+	AUDIT_PASSWORD_CHANGED: true, AUDIT_SESSION_REVOKED: true, AUDIT_ADMIN_ACTION: true,
+	AUDIT_STORAGE_RECONCILED: true,
+}
+
+var		ErrAuditChainBroken = errors.New("the audit chain is broken")
+
+var		auditEventsDropped = promauto.NewCounter(prometheus.CounterOpts{
+	Name:		`members_audit_events_dropped_total`,
+	Help:		`Audit events lost because the outbox was full, or could not be written on shutdown.`,
+})
+var		auditWriteErrors = promauto.NewCounter(prometheus.CounterOpts{
+	Name:		`members_audit_write_errors_total`,
+	Help:		`Failed writes of a batch of audit events. The batch is retried.`,
+})
+
+/******************************************************************************
+**	Hash of an event, chained to the hash of the previous one. The fields are
+**	encoded as a JSON array so that no two events share the same input.
+******************************************************************************/
+func	hashAuditEvent(event *sAuditEvent) (string) {
+	encoded, _ := json.Marshal([]interface{}{
+		event.PreviousHash, event.Type, event.ActorID, event.TargetID, event.Reason,
+		event.IP, event.UserAgent, event.CreatedAt,
+	})
+	hash := sha256.Sum256(encoded)
+	return hex.EncodeToString(hash[:])
+}
+
+/******************************************************************************
+**	Outbox of the audit events waiting to be written
+******************************************************************************/
+type	sAuditOutbox struct {
+	store	AuditStore
+	mutex	sync.Mutex
+	closed	bool
+	events	chan *sAuditEvent
+	pending	[]*sAuditEvent
+	done	chan struct{}
+}
+
+var		auditOutbox *sAuditOutbox
+
+func	startAuditOutbox(store AuditStore) (*sAuditOutbox) {
+	outbox := &sAuditOutbox{
+		store:	store,
+		events:	make(chan *sAuditEvent, AUDIT_OUTBOX_SIZE),
+		done:	make(chan struct{}),
+	}
+	go outbox.run()
+	onShutdown(`audit`, outbox.shutdown)
+	return outbox
+}
+
+func	(o *sAuditOutbox) push(event *sAuditEvent) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	if (!o.closed) {
+		select {
+		case o.events <- event:
+			return
+		default:
+		}
+	}
+	auditEventsDropped.Inc()
+	logError(context.Background(), `Audit event dropped`, `type`, event.Type, `target_id`, event.TargetID)
+}
+
+func	(o *sAuditOutbox) run() {
+	defer close(o.done)
+	ticker := time.NewTicker(AUDIT_FLUSH_INTERVAL)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case event, ok := <-o.events:
+			if (!ok) {
+				return
+			}
+			o.pending = append(o.pending, event)
+			if (len(o.pending) >= AUDIT_BATCH_SIZE) {
+				o.flushWithTimeout()
+			}
+		case <-ticker.C:
+			o.flushWithTimeout()
+		}
+	}
+}
+
+func	(o *sAuditOutbox) flushWithTimeout() {
+	ctx, cancel := context.WithTimeout(context.Background(), AUDIT_WRITE_TIMEOUT)
+	defer cancel()
+	o.flush(ctx)
+}
+
+/******************************************************************************
+**	Write the pending events, in batches. A failed batch stays pending and
+**	is retried on the next flush. Only the worker, or the shutdown once the
+**	worker stopped, touches the pending events.
+******************************************************************************/
+func	(o *sAuditOutbox) flush(ctx context.Context) (error) {
+	for len(o.pending) > 0 {
+		batch := o.pending
+		if (len(batch) > AUDIT_BATCH_SIZE) {
+			batch = batch[:AUDIT_BATCH_SIZE]
+		}
+		if err := o.store.AppendAuditEvents(ctx, batch); err != nil {
+			auditWriteErrors.Inc()
+			logError(ctx, `Could not write the audit events`, `pending`, len(o.pending), `error`, err)
+			if (len(o.pending) > AUDIT_MAX_PENDING) {
+				auditEventsDropped.Add(float64(len(o.pending) - AUDIT_MAX_PENDING))
+				o.pending = o.pending[len(o.pending) - AUDIT_MAX_PENDING:]
+			}
+			return err
+		}
+		o.pending = o.pending[len(batch):]
+	}
+	o.pending = nil
+	return nil
+}
+
+func	(o *sAuditOutbox) shutdown(ctx context.Context) (error) {
+	o.mutex.Lock()
+	o.closed = true
+	close(o.events)
+	o.mutex.Unlock()
+
+	select {
+	case <-o.done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	if err := o.flush(ctx); err != nil {
+		auditEventsDropped.Add(float64(len(o.pending)))
+		return fmt.Errorf("%d audit events lost: %v", len(o.pending), err)
+	}
+	return nil
+}
+
+/******************************************************************************
+**	Queue an event, with the address and the user agent of the member.
+**	Without the forwarded headers, the address is the one of the caller.
+******************************************************************************/
+func	recordAuditEvent(ctx context.Context, eventType, actorID, targetID, reason string) {
+	if (auditOutbox == nil) {
+		return
+	}
+	auditOutbox.push(&sAuditEvent{
+		Type:		eventType,
+		ActorID:	actorID,
+		TargetID:	targetID,
+		Reason:		reason,
+		IP:			auditClientIP(ctx),
+		UserAgent:	auditUserAgent(ctx),
+		CreatedAt:	time.Now().Unix(),
+	})
+}
+
+func	auditClientIP(ctx context.Context) (string) {
+	if incoming, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := incoming.Get(AUDIT_IP_HEADER); len(values) > 0 {
+			return strings.TrimSpace(strings.Split(values[0], `,`)[0])
+		}
+	}
+	if caller, ok := peer.FromContext(ctx); ok {
+		if host, _, err := net.SplitHostPort(caller.Addr.String()); err == nil {
+			return host
+		}
+		return caller.Addr.String()
+	}
+	return ``
+}
+
+func	auditUserAgent(ctx context.Context) (string) {
+	if incoming, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := incoming.Get(AUDIT_USER_AGENT_HEADER); len(values) > 0 {
+			return values[0]
+		}
+		if values := incoming.Get(`user-agent`); len(values) > 0 {
+			return values[0]
+		}
+	}
+	return ``
+}
+
+/******************************************************************************
+**	Login attempts, from the reason counted by the metrics
+******************************************************************************/
+func	recordLoginAttempt(ctx context.Context, memberID, reason string) {
+	if (reason == LOGIN_SUCCESS) {
+		recordAuditEvent(ctx, AUDIT_LOGIN_SUCCEEDED, memberID, memberID, ``)
+	} else {
+		recordAuditEvent(ctx, AUDIT_LOGIN_FAILED, ``, memberID, reason)
+	}
+}
+
+/******************************************************************************
+**	List the audit events, the most recent first, optionally filtered by
+**	member and by type. The returned cursor is the BeforeID of the next
+**	page, or 0 on the last page.
+******************************************************************************/
+func	(s *server) ListAuditEvents(ctx context.Context, filter sAuditFilter) ([]*sAuditEvent, int64, error) {
+	if (filter.Type != `` && !auditEventTypes[filter.Type]) {
+		return nil, 0, errInvalidArgument(`unknown audit event type`, fieldViolation(`type`, `AUDIT_TYPE_UNKNOWN`))
+	}
+	if (filter.Limit < 0 || filter.BeforeID < 0) {
+		return nil, 0, errInvalidArgument(`invalid page`, fieldViolation(`limit`, `AUDIT_PAGE_INVALID`))
+	}
+	if (filter.Limit == 0) {
+		filter.Limit = DEFAULT_AUDIT_PAGE_SIZE
+	} else if (filter.Limit > MAX_AUDIT_PAGE_SIZE) {
+		filter.Limit = MAX_AUDIT_PAGE_SIZE
+	}
+
+	events, err := s.auditStore.ListAuditEvents(ctx, filter)
+	if (err != nil) {
+		return nil, 0, err
+	}
+	var	nextBeforeID int64
+	if (len(events) == filter.Limit) {
+		nextBeforeID = events[len(events) - 1].ID
+	}
+	return events, nextBeforeID, nil
+}
+
+/******************************************************************************
+**	The ListAuditEvents RPC of the MembersExtendedService
+******************************************************************************/
+func	(s *sExtendedServer) ListAuditEvents(ctx context.Context, req *extended.ListAuditEventsRequest) (*extended.ListAuditEventsResponse, error) {
+	events, nextBeforeID, err := s.server.ListAuditEvents(ctx, sAuditFilter{
+		MemberID:	req.MemberID,
+		Type:		req.Type,
+		BeforeID:	req.BeforeID,
+		Limit:		int(req.Limit),
+	})
+	if (err != nil) {
+		return nil, err
+	}
+	response := &extended.ListAuditEventsResponse{Events: []*extended.AuditEvent{}, NextBeforeID: nextBeforeID}
+	for _, event := range events {
+		response.Events = append(response.Events, &extended.AuditEvent{
+			ID:				event.ID,
+			Type:			event.Type,
+			ActorID:		event.ActorID,
+			TargetID:		event.TargetID,
+			Reason:			event.Reason,
+			IP:				event.IP,
+			UserAgent:		event.UserAgent,
+			CreatedAt:		event.CreatedAt,
+			PreviousHash:	event.PreviousHash,
+			Hash:			event.Hash,
+		})
+	}
+	return response, nil
+}
+
+/******************************************************************************
+**	Walk the whole chain, the oldest event first, and check every link.
+**	Returns the number of verified events.
+******************************************************************************/
+func	verifyAuditChain(ctx context.Context, store AuditStore) (int64, error) {
+	var	verified int64
+	var	afterID int64
+	var	previousHash string
+
+	for {
+		events, err := store.ScanAuditEvents(ctx, afterID, MAX_AUDIT_PAGE_SIZE)
+		if (err != nil) {
+			return verified, err
+		}
+		for _, event := range events {
+			if (event.PreviousHash != previousHash || hashAuditEvent(event) != event.Hash) {
+				return verified, fmt.Errorf("audit event %d: %v", event.ID, ErrAuditChainBroken)
+			}
+			previousHash = event.Hash
+			afterID = event.ID
+			verified++
+		}
+		if (len(events) < MAX_AUDIT_PAGE_SIZE) {
+			return verified, nil
+		}
+	}
+}
+
+/******************************************************************************
+**	Handle the `members audit verify` command
+******************************************************************************/
+func	runAuditCommand(args []string, store AuditStore) (error) {
+	if (len(args) != 1 || args[0] != `verify`) {
+		return errors.New("usage: members audit verify")
+	}
+	verified, err := verifyAuditChain(context.Background(), store)
+	if (err != nil) {
+		return err
+	}
+	fmt.Printf("%d audit events verified\n", verified)
+	return nil
+}
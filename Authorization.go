@@ -0,0 +1,188 @@
+/*******************************************************************************
+** @Author:					Thomas Bouder <Tbouder>
+** @Email:					Tbouder@protonmail.com
+** @Date:					Sunday 26 April 2020 - 14:21:37
+** @Filename:				Authorization.go
+**
+** @Last modified by:		Tbouder
+** @Last modified time:		Friday 08 May 2020 - 21:06:52
+*******************************************************************************/
+
+
+package			main
+
+import			"fmt"
+import			"context"
+import			"strings"
+import			"io/ioutil"
+import			"crypto/x509"
+import			"gopkg.in/yaml.v2"
+import			"google.golang.org/grpc"
+import			"google.golang.org/grpc/peer"
+import			"google.golang.org/grpc/credentials"
+import			"github.com/prometheus/client_golang/prometheus"
+import			"github.com/prometheus/client_golang/prometheus/promauto"
+
+/******************************************************************************
+**	Every RPC is restricted to the callers listed in the authorization
+**	policy. A caller is identified by the Common Name and the DNS SANs of its
+**	client certificate, and `*` allows any caller with a valid certificate.
+**	The RPCs missing from the policy are denied. The policy file
+**	(authorization.policy) has the following format :
+**
+**	methods:
+**	  /MembersService/LoginMember: [proxy]
+**	  /MembersService/GetMember: [proxy, pictures]
+**
+**	Without a policy file, the default policy below is used. The admin
+**	RPCs of the MembersExtendedService are restricted to the `admin` caller.
+******************************************************************************/
+type	sAuthorizationPolicy struct {
+	Methods		map[string][]string	`yaml:"methods"`
+}
+
+var		authorizationPolicy = defaultAuthorizationPolicy()
+var		authorizationDenied = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name:		`members_authorization_denied_total`,
+	Help:		`RPCs denied by the authorization policy, by method.`,
+}, []string{`method`})
+
+func	defaultAuthorizationPolicy() (*sAuthorizationPolicy) {
+	return &sAuthorizationPolicy{Methods: map[string][]string{
+		`/MembersService/CreateMember`:		{`proxy`},
+		`/MembersService/LoginMember`:		{`proxy`},
+		`/MembersService/CheckAccessToken`:	{`proxy`, `pictures`},
+		`/MembersService/GetMember`:		{`proxy`, `pictures`},
+
+		`/MembersExtendedService/ListAuditEvents`:			{`admin`},
+		`/MembersExtendedService/GetLoginHistory`:			{`proxy`},
+		`/MembersExtendedService/ReportUnrecognizedLogin`:	{`proxy`},
+		`/MembersExtendedService/ChangePassword`:			{`proxy`},
+		`/MembersExtendedService/ReserveStorage`:			{`pictures`},
+		`/MembersExtendedService/CommitStorage`:			{`pictures`},
+		`/MembersExtendedService/ReleaseStorage`:			{`pictures`},
+		`/MembersExtendedService/SetStorageQuota`:			{`admin`},
+		`/MembersExtendedService/CreatePlan`:				{`admin`},
+		`/MembersExtendedService/UpdatePlan`:				{`admin`},
+		`/MembersExtendedService/ListPlans`:				{`admin`},
+		`/MembersExtendedService/SetMemberPlan`:			{`admin`},
+		`/MembersExtendedService/GetMemberLimits`:			{`proxy`, `pictures`},
+		`/MembersExtendedService/CreateInvitation`:			{`admin`},
+		`/MembersExtendedService/ListInvitations`:			{`admin`},
+		`/MembersExtendedService/RevokeInvitation`:			{`admin`},
+
+		`/grpc.health.v1.Health/Check`:		{`*`},
+	}}
+}
+
+func	loadAuthorizationPolicy(path string) (*sAuthorizationPolicy, error) {
+	if (path == ``) {
+		return defaultAuthorizationPolicy(), nil
+	}
+	content, err := ioutil.ReadFile(path)
+	if (err != nil) {
+		return nil, err
+	}
+	policy := &sAuthorizationPolicy{}
+	if err := yaml.UnmarshalStrict(content, policy); err != nil {
+		return nil, err
+	}
+	for method := range policy.Methods {
+		if (!strings.HasPrefix(method, `/`) || strings.Count(method, `/`) != 2) {
+			return nil, fmt.Errorf("invalid method %q, expected /Service/Method", method)
+		}
+	}
+	return policy, nil
+}
+
+/******************************************************************************
+**	Check that every method of the policy is served, to catch the typos,
+**	which would deny an RPC without notice. Only the unary methods are
+**	authorized : the streaming ones (the health Watch) are open to any
+**	caller with a valid certificate.
+******************************************************************************/
+func	(p *sAuthorizationPolicy) check(services map[string]grpc.ServiceInfo) (error) {
+	served := map[string]bool{}
+	for serviceName, service := range services {
+		for _, method := range service.Methods {
+			if (method.IsClientStream || method.IsServerStream) {
+				continue
+			}
+			served[`/` + serviceName + `/` + method.Name] = true
+		}
+	}
+	for method := range p.Methods {
+		if (!served[method]) {
+			return fmt.Errorf("authorization policy: unknown method %s", method)
+		}
+	}
+	for method := range served {
+		if _, ok := p.Methods[method]; !ok {
+			logWarning(context.Background(), `No caller is allowed to call the method`, `method`, method)
+		}
+	}
+	return nil
+}
+
+func	(p *sAuthorizationPolicy) allows(method string, identities []string) (bool) {
+	for _, allowed := range p.Methods[method] {
+		if (allowed == `*`) {
+			return true
+		}
+		for _, identity := range identities {
+			if (identity == allowed) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+/******************************************************************************
+**	The identities of the caller : the Common Name and the DNS SANs of the
+**	verified client certificate
+******************************************************************************/
+func	callerIdentities(ctx context.Context) ([]string) {
+	caller, ok := peer.FromContext(ctx)
+	if (!ok) {
+		return nil
+	}
+	tlsInfo, ok := caller.AuthInfo.(credentials.TLSInfo)
+	if (!ok) {
+		return nil
+	}
+
+	var	certificate *x509.Certificate
+	if (len(tlsInfo.State.VerifiedChains) > 0 && len(tlsInfo.State.VerifiedChains[0]) > 0) {
+		certificate = tlsInfo.State.VerifiedChains[0][0]
+	}
+	if (certificate == nil) {
+		return nil
+	}
+
+	identities := []string{}
+	if (certificate.Subject.CommonName != ``) {
+		identities = append(identities, certificate.Subject.CommonName)
+	}
+	for _, name := range certificate.DNSNames {
+		if (name != certificate.Subject.CommonName) {
+			identities = append(identities, name)
+		}
+	}
+	return identities
+}
+
+/******************************************************************************
+**	Unary interceptor denying the RPCs the caller is not allowed to call.
+**	It is only installed when the server runs with mutual TLS : there is no
+**	identity to check in plaintext.
+******************************************************************************/
+func	authorizationInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	identities := callerIdentities(ctx)
+	if (!authorizationPolicy.allows(info.FullMethod, identities)) {
+		authorizationDenied.WithLabelValues(info.FullMethod).Inc()
+		logWarning(ctx, `Permission denied`, `identities`, strings.Join(identities, `, `))
+		return nil, errPermissionDenied(`caller not allowed to call ` + info.FullMethod)
+	}
+	return handler(ctx, req)
+}
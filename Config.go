@@ -0,0 +1,403 @@
+/*******************************************************************************
+** @Author:					Thomas Bouder <Tbouder>
+** @Email:					Tbouder@protonmail.com
+** @Date:					Wednesday 22 April 2020 - 10:27:14
+** @Filename:				Config.go
+**
+** @Last modified by:		Tbouder
+** @Last modified time:		Saturday 09 May 2020 - 17:31:02
+*******************************************************************************/
+
+package			main
+
+import			"os"
+import			"fmt"
+import			"flag"
+import			"errors"
+import			"strconv"
+import			"strings"
+import			"io/ioutil"
+import			"encoding/base64"
+import			"gopkg.in/yaml.v2"
+
+/******************************************************************************
+**	The configuration is loaded in layers, each one overriding the previous :
+**	the defaults, the YAML file (-config flag or MEMBERS_CONFIG), the
+**	environment variables and the command line flags. It is validated on
+**	boot, so a missing key stops the service right away instead of failing
+**	on the first request.
+******************************************************************************/
+type	sConfig struct {
+	Server struct {
+		Port				string	`yaml:"port"`
+		ShutdownTimeout		int64	`yaml:"shutdownTimeout"`
+	}	`yaml:"server"`
+	Log struct {
+		Level				string	`yaml:"level"`
+	}	`yaml:"log"`
+	Metrics struct {
+		Port				string	`yaml:"port"`
+	}	`yaml:"metrics"`
+	Tracing struct {
+		Exporter			string	`yaml:"exporter"`
+		Endpoint			string	`yaml:"endpoint"`
+		File				string	`yaml:"file"`
+	}	`yaml:"tracing"`
+	Database struct {
+		Driver				string	`yaml:"driver"`
+		Username			string	`yaml:"username"`
+		Password			string	`yaml:"password"`
+		Host				string	`yaml:"host"`
+		Name				string	`yaml:"name"`
+		SQLitePath			string	`yaml:"sqlitePath"`
+	}	`yaml:"database"`
+	TLS struct {
+		Mode				string	`yaml:"mode"`
+		ServerCert			string	`yaml:"serverCert"`
+		ServerKey			string	`yaml:"serverKey"`
+		ClientCert			string	`yaml:"clientCert"`
+		ClientKey			string	`yaml:"clientKey"`
+		CA					string	`yaml:"ca"`
+		ReloadInterval		int64	`yaml:"reloadInterval"`
+	}	`yaml:"tls"`
+	Authorization struct {
+		Policy				string	`yaml:"policy"`
+	}	`yaml:"authorization"`
+	Keys struct {
+		Master				string	`yaml:"master"`
+		JWTAccess			string	`yaml:"jwtAccess"`
+		JWTRefresh			string	`yaml:"jwtRefresh"`
+		Pepper				string	`yaml:"pepper"`
+		PepperVersion		string	`yaml:"pepperVersion"`
+	}	`yaml:"keys"`
+	Hashing struct {
+		MemoryBudget		int64	`yaml:"memoryBudget"`
+		MaxQueue			int64	`yaml:"maxQueue"`
+		QueueTimeout		int64	`yaml:"queueTimeout"`
+	}	`yaml:"hashing"`
+	Password struct {
+		MinLength			int64	`yaml:"minLength"`
+		MinScore			int64	`yaml:"minScore"`
+		BannedList			string	`yaml:"bannedList"`
+		BreachedDir			string	`yaml:"breachedDir"`
+		BreachedFailOpen	bool	`yaml:"breachedFailOpen"`
+	}	`yaml:"password"`
+	Storage struct {
+		DefaultQuota			int64	`yaml:"defaultQuota"`
+		ReservationTimeout		int64	`yaml:"reservationTimeout"`
+		UsageFile				string	`yaml:"usageFile"`
+		ReconcileInterval		int64	`yaml:"reconcileInterval"`
+	}	`yaml:"storage"`
+	Registration struct {
+		Mode					string	`yaml:"mode"`
+		InvitationExpiration	int64	`yaml:"invitationExpiration"`
+	}	`yaml:"registration"`
+	Mail struct {
+		Host					string	`yaml:"host"`
+		Port					string	`yaml:"port"`
+		Username				string	`yaml:"username"`
+		Password				string	`yaml:"password"`
+		From					string	`yaml:"from"`
+		UnrecognizedLoginURL	string	`yaml:"unrecognizedLoginURL"`
+	}	`yaml:"mail"`
+}
+
+var		config = defaultConfig()
+
+func	defaultConfig() (*sConfig) {
+	c := &sConfig{}
+	c.Server.Port = `8010`
+	c.Server.ShutdownTimeout = DEFAULT_SHUTDOWN_TIMEOUT
+	c.Log.Level = LOG_INFO
+	c.Metrics.Port = DEFAULT_METRICS_PORT
+	c.Tracing.Exporter = TRACING_NONE
+	c.Tracing.Endpoint = DEFAULT_TRACING_ENDPOINT
+	c.Database.Driver = DRIVER_POSTGRE
+	c.Database.SQLitePath = DEFAULT_SQLITE_PATH
+	c.TLS.Mode = TLS_MODE_REQUIRED
+	c.TLS.ServerCert = `/env/server.crt`
+	c.TLS.ServerKey = `/env/server.key`
+	c.TLS.ClientCert = `/env/client.crt`
+	c.TLS.ClientKey = `/env/client.key`
+	c.TLS.CA = `/env/ca.crt`
+	c.TLS.ReloadInterval = DEFAULT_TLS_RELOAD_INTERVAL
+	c.Hashing.MemoryBudget = DEFAULT_HASH_MEMORY_BUDGET
+	c.Hashing.MaxQueue = DEFAULT_HASH_MAX_QUEUE
+	c.Hashing.QueueTimeout = DEFAULT_HASH_QUEUE_TIMEOUT
+	c.Password.MinLength = DEFAULT_PASSWORD_MIN_LENGTH
+	c.Password.MinScore = DEFAULT_PASSWORD_MIN_SCORE
+	c.Storage.ReservationTimeout = DEFAULT_STORAGE_RESERVATION_TIMEOUT
+	c.Storage.ReconcileInterval = DEFAULT_RECONCILE_INTERVAL
+	c.Mail.Port = DEFAULT_MAIL_PORT
+	c.Registration.Mode = REGISTRATION_OPEN
+	c.Registration.InvitationExpiration = DEFAULT_INVITATION_EXPIRATION
+	return c
+}
+
+/******************************************************************************
+**	Every setting which can be overridden by an environment variable and by
+**	a flag. The value is either a *string, an *int64 or a *bool.
+******************************************************************************/
+type	sSetting struct {
+	env		string
+	flag	string
+	value	interface{}
+}
+
+func	(c *sConfig) settings() ([]sSetting) {
+	return []sSetting{
+		{`MEMBERS_PORT`, `port`, &c.Server.Port},
+		{`MEMBERS_SHUTDOWN_TIMEOUT`, `shutdown-timeout`, &c.Server.ShutdownTimeout},
+		{`LOG_LEVEL`, `log-level`, &c.Log.Level},
+		{`METRICS_PORT`, `metrics-port`, &c.Metrics.Port},
+		{`TRACING_EXPORTER`, `tracing-exporter`, &c.Tracing.Exporter},
+		{`TRACING_ENDPOINT`, `tracing-endpoint`, &c.Tracing.Endpoint},
+		{`TRACING_FILE`, `tracing-file`, &c.Tracing.File},
+		{`DATABASE_DRIVER`, `database-driver`, &c.Database.Driver},
+		{`POSTGRE_USERNAME`, `postgre-username`, &c.Database.Username},
+		{`POSTGRE_PWD`, `postgre-password`, &c.Database.Password},
+		{`POSTGRE_URI`, `postgre-host`, &c.Database.Host},
+		{`POSTGRE_DB`, `postgre-db`, &c.Database.Name},
+		{`SQLITE_PATH`, `sqlite-path`, &c.Database.SQLitePath},
+		{`TLS_MODE`, `tls-mode`, &c.TLS.Mode},
+		{`TLS_SERVER_CERT`, `tls-server-cert`, &c.TLS.ServerCert},
+		{`TLS_SERVER_KEY`, `tls-server-key`, &c.TLS.ServerKey},
+		{`TLS_CLIENT_CERT`, `tls-client-cert`, &c.TLS.ClientCert},
+		{`TLS_CLIENT_KEY`, `tls-client-key`, &c.TLS.ClientKey},
+		{`TLS_CA`, `tls-ca`, &c.TLS.CA},
+		{`TLS_RELOAD_INTERVAL`, `tls-reload-interval`, &c.TLS.ReloadInterval},
+		{`AUTHORIZATION_POLICY`, `authorization-policy`, &c.Authorization.Policy},
+		{`MASTER_KEY`, `master-key`, &c.Keys.Master},
+		{`JWT_ACCESS_TOKEN_KEY`, `jwt-access-key`, &c.Keys.JWTAccess},
+		{`JWT_REFRESH_TOKEN_KEY`, `jwt-refresh-key`, &c.Keys.JWTRefresh},
+		{`PEPPER_KEYS`, `pepper-keys`, &c.Keys.Pepper},
+		{`PEPPER_VERSION`, `pepper-version`, &c.Keys.PepperVersion},
+		{`HASH_MEMORY_BUDGET`, `hash-memory-budget`, &c.Hashing.MemoryBudget},
+		{`HASH_MAX_QUEUE`, `hash-max-queue`, &c.Hashing.MaxQueue},
+		{`HASH_QUEUE_TIMEOUT`, `hash-queue-timeout`, &c.Hashing.QueueTimeout},
+		{`PASSWORD_MIN_LENGTH`, `password-min-length`, &c.Password.MinLength},
+		{`PASSWORD_MIN_SCORE`, `password-min-score`, &c.Password.MinScore},
+		{`PASSWORD_BANNED_LIST`, `password-banned-list`, &c.Password.BannedList},
+		{`PASSWORD_BREACHED_DIR`, `password-breached-dir`, &c.Password.BreachedDir},
+		{`PASSWORD_BREACHED_FAIL_OPEN`, `password-breached-fail-open`, &c.Password.BreachedFailOpen},
+		{`STORAGE_DEFAULT_QUOTA`, `storage-default-quota`, &c.Storage.DefaultQuota},
+		{`STORAGE_RESERVATION_TIMEOUT`, `storage-reservation-timeout`, &c.Storage.ReservationTimeout},
+		{`STORAGE_USAGE_FILE`, `storage-usage-file`, &c.Storage.UsageFile},
+		{`STORAGE_RECONCILE_INTERVAL`, `storage-reconcile-interval`, &c.Storage.ReconcileInterval},
+		{`REGISTRATION_MODE`, `registration-mode`, &c.Registration.Mode},
+		{`REGISTRATION_INVITATION_EXPIRATION`, `registration-invitation-expiration`, &c.Registration.InvitationExpiration},
+		{`MAIL_HOST`, `mail-host`, &c.Mail.Host},
+		{`MAIL_PORT`, `mail-port`, &c.Mail.Port},
+		{`MAIL_USERNAME`, `mail-username`, &c.Mail.Username},
+		{`MAIL_PASSWORD`, `mail-password`, &c.Mail.Password},
+		{`MAIL_FROM`, `mail-from`, &c.Mail.From},
+		{`MAIL_UNRECOGNIZED_LOGIN_URL`, `mail-unrecognized-login-url`, &c.Mail.UnrecognizedLoginURL},
+	}
+}
+
+func	(s sSetting) set(value string) (error) {
+	switch pointer := s.value.(type) {
+	case *string:
+		*pointer = value
+	case *int64:
+		parsed, err := strconv.ParseInt(value, 10, 64)
+		if (err != nil) {
+			return fmt.Errorf("%s: %v", s.env, err)
+		}
+		*pointer = parsed
+	case *bool:
+		parsed, err := strconv.ParseBool(value)
+		if (err != nil) {
+			return fmt.Errorf("%s: %v", s.env, err)
+		}
+		*pointer = parsed
+	}
+	return nil
+}
+
+/******************************************************************************
+**	Load the configuration from the file, the environment and the flags, and
+**	return the remaining arguments (the command to run, if any)
+******************************************************************************/
+func	loadConfig(args []string) (*sConfig, []string, error) {
+	c := defaultConfig()
+	settings := c.settings()
+
+	flags := flag.NewFlagSet(`members`, flag.ContinueOnError)
+	configPath := flags.String(`config`, os.Getenv(`MEMBERS_CONFIG`), `path to the YAML configuration file`)
+	flagValues := map[string]*string{}
+	for _, setting := range settings {
+		flagValues[setting.flag] = flags.String(setting.flag, ``, `overrides ` + setting.env)
+	}
+	if err := flags.Parse(args); err != nil {
+		return nil, nil, err
+	}
+
+	if (*configPath != ``) {
+		content, err := ioutil.ReadFile(*configPath)
+		if (err != nil) {
+			return nil, nil, err
+		}
+		if err := yaml.UnmarshalStrict(content, c); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	for _, setting := range settings {
+		if value, ok := os.LookupEnv(setting.env); ok {
+			if err := setting.set(value); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+
+	var	flagErr error
+	flags.Visit(func(f *flag.Flag) {
+		for _, setting := range settings {
+			if (setting.flag == f.Name && flagErr == nil) {
+				flagErr = setting.set(*flagValues[f.Name])
+			}
+		}
+	})
+	if (flagErr != nil) {
+		return nil, nil, flagErr
+	}
+	return c, flags.Args(), nil
+}
+
+/******************************************************************************
+**	Check the configuration and return every problem found at once
+******************************************************************************/
+func	(c *sConfig) validate() ([]error) {
+	var	errs []error
+
+	if port, err := strconv.Atoi(c.Server.Port); err != nil || port <= 0 || port > 65535 {
+		errs = append(errs, errors.New("server.port must be a valid port number"))
+	}
+	if _, ok := logLevels[c.Log.Level]; !ok {
+		errs = append(errs, fmt.Errorf("log.level must be %s, %s, %s or %s", LOG_DEBUG, LOG_INFO, LOG_WARNING, LOG_ERROR))
+	}
+	if port, err := strconv.Atoi(c.Metrics.Port); c.Metrics.Port != `` && (err != nil || port <= 0 || port > 65535) {
+		errs = append(errs, errors.New("metrics.port must be a valid port number, or empty to disable the metrics"))
+	}
+	switch c.Tracing.Exporter {
+	case TRACING_NONE, TRACING_STDOUT:
+	case TRACING_OTLP:
+		if (c.Tracing.Endpoint == ``) {
+			errs = append(errs, errors.New("tracing.endpoint is required with the otlp exporter"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("tracing.exporter must be %s, %s or %s", TRACING_NONE, TRACING_OTLP, TRACING_STDOUT))
+	}
+	if (c.Server.ShutdownTimeout <= 0) {
+		errs = append(errs, errors.New("server.shutdownTimeout must be a positive number of seconds"))
+	}
+
+	switch c.Database.Driver {
+	case DRIVER_POSTGRE:
+		if (c.Database.Host == `` || c.Database.Name == `` || c.Database.Username == ``) {
+			errs = append(errs, errors.New("database.host, database.name and database.username are required with postgres"))
+		}
+	case DRIVER_SQLITE:
+		if (c.Database.SQLitePath == ``) {
+			errs = append(errs, errors.New("database.sqlitePath is required with sqlite"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("database.driver must be %s or %s", DRIVER_POSTGRE, DRIVER_SQLITE))
+	}
+
+	switch c.TLS.Mode {
+	case TLS_MODE_REQUIRED:
+		for _, path := range []string{c.TLS.ServerCert, c.TLS.ServerKey, c.TLS.CA} {
+			if _, err := os.Stat(path); err != nil {
+				errs = append(errs, fmt.Errorf("tls: %v (required by tls.mode=required)", err))
+			}
+		}
+	case TLS_MODE_OPTIONAL, TLS_MODE_DISABLED:
+	default:
+		errs = append(errs, fmt.Errorf("tls.mode must be %s, %s or %s", TLS_MODE_REQUIRED, TLS_MODE_OPTIONAL, TLS_MODE_DISABLED))
+	}
+	if (c.TLS.ReloadInterval <= 0) {
+		errs = append(errs, errors.New("tls.reloadInterval must be a positive number of seconds"))
+	}
+	if _, err := loadAuthorizationPolicy(c.Authorization.Policy); err != nil {
+		errs = append(errs, fmt.Errorf("authorization.policy: %v", err))
+	}
+
+	if masterKey, err := base64.RawStdEncoding.DecodeString(c.Keys.Master); err != nil || len(masterKey) != 32 {
+		errs = append(errs, errors.New("keys.master (MASTER_KEY) must be 32 bytes encoded in unpadded base64"))
+	}
+	if (strings.TrimSpace(c.Keys.JWTAccess) == ``) {
+		errs = append(errs, errors.New("keys.jwtAccess (JWT_ACCESS_TOKEN_KEY) is required"))
+	}
+	if (strings.TrimSpace(c.Keys.JWTRefresh) == ``) {
+		errs = append(errs, errors.New("keys.jwtRefresh (JWT_REFRESH_TOKEN_KEY) is required"))
+	}
+	if _, err := parsePepper(c.Keys.Pepper, c.Keys.PepperVersion); err != nil {
+		errs = append(errs, fmt.Errorf("keys.pepper: %v", err))
+	}
+
+	if (c.Hashing.MemoryBudget <= 0 || c.Hashing.MaxQueue <= 0 || c.Hashing.QueueTimeout <= 0) {
+		errs = append(errs, errors.New("hashing.memoryBudget, hashing.maxQueue and hashing.queueTimeout must be positive"))
+	}
+	if (c.Password.MinScore < 0 || c.Password.MinScore > 4) {
+		errs = append(errs, errors.New("password.minScore must be between 0 and 4"))
+	}
+	if (c.Password.BannedList != ``) {
+		if _, err := loadBannedPasswords(c.Password.BannedList); err != nil {
+			errs = append(errs, fmt.Errorf("password.bannedList: %v", err))
+		}
+	}
+	if (c.Password.BreachedDir != ``) {
+		if info, err := os.Stat(c.Password.BreachedDir); err != nil {
+			errs = append(errs, fmt.Errorf("password.breachedDir: %v", err))
+		} else if (!info.IsDir()) {
+			errs = append(errs, errors.New("password.breachedDir must be a directory of range files"))
+		}
+	}
+	if (c.Storage.DefaultQuota < 0) {
+		errs = append(errs, errors.New("storage.defaultQuota must be a number of bytes, or 0 for no quota"))
+	}
+	if (c.Storage.ReservationTimeout <= 0) {
+		errs = append(errs, errors.New("storage.reservationTimeout must be a positive number of seconds"))
+	}
+	if (c.Storage.ReconcileInterval <= 0) {
+		errs = append(errs, errors.New("storage.reconcileInterval must be a positive number of seconds"))
+	}
+	switch c.Registration.Mode {
+	case REGISTRATION_OPEN, REGISTRATION_INVITE, REGISTRATION_CLOSED:
+	default:
+		errs = append(errs, fmt.Errorf("registration.mode must be %s, %s or %s", REGISTRATION_OPEN, REGISTRATION_INVITE, REGISTRATION_CLOSED))
+	}
+	if (c.Registration.InvitationExpiration <= 0) {
+		errs = append(errs, errors.New("registration.invitationExpiration must be a positive number of seconds"))
+	}
+	if (c.Mail.Host != ``) {
+		if port, err := strconv.Atoi(c.Mail.Port); err != nil || port <= 0 || port > 65535 {
+			errs = append(errs, errors.New("mail.port must be a valid port number"))
+		}
+		if (c.Mail.From == `` || c.Mail.UnrecognizedLoginURL == ``) {
+			errs = append(errs, errors.New("mail.from and mail.unrecognizedLoginURL are required with mail.host"))
+		}
+	}
+	return errs
+}
+
+/******************************************************************************
+**	Handle the `members config check` command
+******************************************************************************/
+func	runConfigCommand(args []string) (error) {
+	if (len(args) != 1 || args[0] != `check`) {
+		return errors.New("usage: members config check")
+	}
+
+	errs := config.validate()
+	for _, err := range errs {
+		fmt.Println(err)
+	}
+	if (len(errs) > 0) {
+		return fmt.Errorf("%d configuration errors", len(errs))
+	}
+	fmt.Println(`configuration is valid`)
+	return nil
+}
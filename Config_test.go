@@ -0,0 +1,112 @@
+/*******************************************************************************
+** @Author:					Thomas Bouder <Tbouder>
+** @Email:					Tbouder@protonmail.com
+** @Date:					Saturday 09 May 2020 - 15:20:04
+** @Filename:				Config_test.go
+**
+** @Last modified by:		Tbouder
+** @Last modified time:		Saturday 09 May 2020 - 15:20:04
+*******************************************************************************/
+
+
+package			main
+
+import			"os"
+import			"strings"
+import			"testing"
+import			"io/ioutil"
+import			"path/filepath"
+
+/******************************************************************************
+**	Clear the environment variables for the duration of a test, and return
+**	the function restoring their previous values
+******************************************************************************/
+func	clearTestEnv(keys ...string) (func()) {
+	previous := map[string]string{}
+	for _, key := range keys {
+		if value, ok := os.LookupEnv(key); ok {
+			previous[key] = value
+		}
+		os.Unsetenv(key)
+	}
+	return func() {
+		for _, key := range keys {
+			if value, ok := previous[key]; ok {
+				os.Setenv(key, value)
+			} else {
+				os.Unsetenv(key)
+			}
+		}
+	}
+}
+
+func	newConfigTestFile(t *testing.T, content string) (string, func()) {
+	dir, err := ioutil.TempDir(``, `config`)
+	if (err != nil) {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, `members.yaml`)
+	if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path, func() {os.RemoveAll(dir)}
+}
+
+func	TestLoadConfigPrecedence(t *testing.T) {
+	path, remove := newConfigTestFile(t, "server:\n  port: \"9001\"\n  shutdownTimeout: 20\nlog:\n  level: debug\ntls:\n  mode: optional\n")
+	defer remove()
+	defer clearTestEnv(`MEMBERS_CONFIG`, `MEMBERS_PORT`, `MEMBERS_SHUTDOWN_TIMEOUT`, `LOG_LEVEL`, `TLS_MODE`)()
+
+	c, args, err := loadConfig([]string{`-config`, path, `config`, `check`})
+	if (err != nil) {
+		t.Fatal(err)
+	}
+	if (c.Server.Port != `9001` || c.Server.ShutdownTimeout != 20 || c.Log.Level != LOG_DEBUG || c.TLS.Mode != TLS_MODE_OPTIONAL) {
+		t.Errorf("the file must override the defaults, got %+v", c)
+	}
+	if (c.Metrics.Port != DEFAULT_METRICS_PORT) {
+		t.Errorf("a setting missing from the file must keep its default, got %q", c.Metrics.Port)
+	}
+	if (strings.Join(args, ` `) != `config check`) {
+		t.Errorf("expected the command in the remaining arguments, got %v", args)
+	}
+
+	os.Setenv(`MEMBERS_CONFIG`, path)
+	os.Setenv(`MEMBERS_PORT`, `9002`)
+	os.Setenv(`MEMBERS_SHUTDOWN_TIMEOUT`, `30`)
+	c, _, err = loadConfig(nil)
+	if (err != nil) {
+		t.Fatal(err)
+	}
+	if (c.Server.Port != `9002` || c.Server.ShutdownTimeout != 30) {
+		t.Errorf("the environment must override the file, got %q %d", c.Server.Port, c.Server.ShutdownTimeout)
+	}
+	if (c.Log.Level != LOG_DEBUG) {
+		t.Errorf("MEMBERS_CONFIG must load the file, got the log level %q", c.Log.Level)
+	}
+
+	c, _, err = loadConfig([]string{`-port`, `9003`, `-tls-mode`, `disabled`})
+	if (err != nil) {
+		t.Fatal(err)
+	}
+	if (c.Server.Port != `9003` || c.TLS.Mode != TLS_MODE_DISABLED) {
+		t.Errorf("the flags must override the environment, got %q %q", c.Server.Port, c.TLS.Mode)
+	}
+	if (c.Server.ShutdownTimeout != 30) {
+		t.Errorf("a setting without flag must keep the environment, got %d", c.Server.ShutdownTimeout)
+	}
+}
+
+func	TestLoadConfigErrors(t *testing.T) {
+	path, remove := newConfigTestFile(t, "server:\n  prot: \"9001\"\n")
+	defer remove()
+	defer clearTestEnv(`MEMBERS_CONFIG`, `MEMBERS_SHUTDOWN_TIMEOUT`)()
+	os.Setenv(`MEMBERS_SHUTDOWN_TIMEOUT`, `soon`)
+
+	if _, _, err := loadConfig([]string{`-config`, path}); err == nil {
+		t.Errorf("an unknown key in the file must be refused")
+	}
+	if _, _, err := loadConfig(nil); err == nil || !strings.Contains(err.Error(), `MEMBERS_SHUTDOWN_TIMEOUT`) {
+		t.Errorf("an invalid environment variable must be refused, got %v", err)
+	}
+}
@@ -0,0 +1,136 @@
+/*******************************************************************************
+** @Author:					Thomas Bouder <Tbouder>
+** @Email:					Tbouder@protonmail.com
+** @Date:					Monday 20 April 2020 - 14:03:37
+** @Filename:				Errors.go
+**
+** @Last modified by:		Tbouder
+** @Last modified time:		Friday 08 May 2020 - 10:05:44
+*******************************************************************************/
+
+package			main
+
+import			"time"
+import			"context"
+import			"google.golang.org/grpc"
+import			"google.golang.org/grpc/codes"
+import			"google.golang.org/grpc/status"
+import			"github.com/golang/protobuf/proto"
+import			"github.com/golang/protobuf/ptypes"
+import			"google.golang.org/genproto/googleapis/rpc/errdetails"
+
+/******************************************************************************
+**	Every error sent back by the RPCs is a gRPC status with a meaningful
+**	code, and, when it helps the caller, structured details : the violated
+**	fields for InvalidArgument, the resource for NotFound and AlreadyExists,
+**	and the retry delay for ResourceExhausted. The internal errors (database,
+**	crypto...) are logged and replaced by a generic Internal error, to never
+**	leak internals to the callers.
+******************************************************************************/
+var		ErrInvalidCredentials = status.Error(codes.Unauthenticated, `invalid email or password`)
+
+func	withDetails(st *status.Status, details ...proto.Message) (error) {
+	detailed, err := st.WithDetails(details...)
+	if (err != nil) {
+		return st.Err()
+	}
+	return detailed.Err()
+}
+
+func	fieldViolation(field, reason string) (*errdetails.BadRequest_FieldViolation) {
+	return &errdetails.BadRequest_FieldViolation{Field: field, Description: reason}
+}
+
+func	errInvalidArgument(message string, violations ...*errdetails.BadRequest_FieldViolation) (error) {
+	return withDetails(status.New(codes.InvalidArgument, message), &errdetails.BadRequest{FieldViolations: violations})
+}
+
+func	errNotFound(resourceType, resourceName string) (error) {
+	return withDetails(
+		status.New(codes.NotFound, resourceType + ` not found`),
+		&errdetails.ResourceInfo{ResourceType: resourceType, ResourceName: resourceName},
+	)
+}
+
+func	errAlreadyExists(resourceType, resourceName string) (error) {
+	return withDetails(
+		status.New(codes.AlreadyExists, resourceType + ` already exists`),
+		&errdetails.ResourceInfo{ResourceType: resourceType, ResourceName: resourceName},
+	)
+}
+
+func	errUnauthenticated(message string) (error) {
+	return status.Error(codes.Unauthenticated, message)
+}
+
+func	errPermissionDenied(message string) (error) {
+	return status.Error(codes.PermissionDenied, message)
+}
+
+func	errUnavailable(message string) (error) {
+	return status.Error(codes.Unavailable, message)
+}
+
+func	errFailedPrecondition(message, violationType string) (error) {
+	return withDetails(
+		status.New(codes.FailedPrecondition, message),
+		&errdetails.PreconditionFailure{Violations: []*errdetails.PreconditionFailure_Violation{{Type: violationType, Description: message}}},
+	)
+}
+
+func	errResourceExhausted(message string, retryDelay time.Duration) (error) {
+	return withDetails(
+		status.New(codes.ResourceExhausted, message),
+		&errdetails.RetryInfo{RetryDelay: ptypes.DurationProto(retryDelay)},
+	)
+}
+
+func	errQuotaExceeded(subject, description string) (error) {
+	return withDetails(
+		status.New(codes.ResourceExhausted, description),
+		&errdetails.QuotaFailure{Violations: []*errdetails.QuotaFailure_Violation{{Subject: subject, Description: description}}},
+	)
+}
+
+/******************************************************************************
+**	While checking the tokens, an unknown member is an authentication
+**	failure rather than a missing resource
+******************************************************************************/
+func	asUnauthenticated(err error) (error) {
+	if (err == ErrMemberNotFound) {
+		return errUnauthenticated(`unknown member`)
+	}
+	return err
+}
+
+/******************************************************************************
+**	Convert any error to a gRPC status error. The errors which are already
+**	a status are kept as is. The unexpected errors are logged, with the
+**	fields of the RPC, before being hidden behind an Internal status.
+******************************************************************************/
+func	toStatusError(ctx context.Context, err error) (error) {
+	if (err == nil) {
+		return nil
+	}
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+
+	switch err {
+	case ErrMemberNotFound:			return errNotFound(`member`, ``)
+	case ErrMemberAlreadyExists:	return errAlreadyExists(`member`, ``)
+	case context.DeadlineExceeded:	return status.Error(codes.DeadlineExceeded, err.Error())
+	case context.Canceled:			return status.Error(codes.Canceled, err.Error())
+	}
+
+	logError(ctx, `Internal error`, `error`, err)
+	return status.Error(codes.Internal, `internal error`)
+}
+
+/******************************************************************************
+**	Unary interceptor ensuring that every RPC returns a status error
+******************************************************************************/
+func	errorsInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	resp, err := handler(ctx, req)
+	return resp, toStatusError(ctx, err)
+}
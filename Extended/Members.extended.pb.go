@@ -0,0 +1,2428 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// source: Members.extended.proto
+
+package extended
+
+import (
+	context "context"
+	fmt "fmt"
+	proto "github.com/golang/protobuf/proto"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+	math "math"
+)
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the proto package it is being compiled against.
+// A compilation error at this line likely means your copy of the
+// proto package needs to be updated.
+const _ = proto.ProtoPackageIsVersion3 // please upgrade the proto package
+
+// *****************************************************************************
+// * Audit
+// ****************************************************************************
+type AuditEvent struct {
+	ID                   int64    `protobuf:"varint,1,opt,name=ID,proto3" json:"ID,omitempty"`
+	Type                 string   `protobuf:"bytes,2,opt,name=Type,proto3" json:"Type,omitempty"`
+	ActorID              string   `protobuf:"bytes,3,opt,name=ActorID,proto3" json:"ActorID,omitempty"`
+	TargetID             string   `protobuf:"bytes,4,opt,name=TargetID,proto3" json:"TargetID,omitempty"`
+	Reason               string   `protobuf:"bytes,5,opt,name=Reason,proto3" json:"Reason,omitempty"`
+	IP                   string   `protobuf:"bytes,6,opt,name=IP,proto3" json:"IP,omitempty"`
+	UserAgent            string   `protobuf:"bytes,7,opt,name=UserAgent,proto3" json:"UserAgent,omitempty"`
+	CreatedAt            int64    `protobuf:"varint,8,opt,name=CreatedAt,proto3" json:"CreatedAt,omitempty"`
+	PreviousHash         string   `protobuf:"bytes,9,opt,name=PreviousHash,proto3" json:"PreviousHash,omitempty"`
+	Hash                 string   `protobuf:"bytes,10,opt,name=Hash,proto3" json:"Hash,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *AuditEvent) Reset()         { *m = AuditEvent{} }
+func (m *AuditEvent) String() string { return proto.CompactTextString(m) }
+func (*AuditEvent) ProtoMessage()    {}
+func (*AuditEvent) Descriptor() ([]byte, []int) {
+	return fileDescriptor_8b6ce4408de419a3, []int{0}
+}
+
+func (m *AuditEvent) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_AuditEvent.Unmarshal(m, b)
+}
+func (m *AuditEvent) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_AuditEvent.Marshal(b, m, deterministic)
+}
+func (m *AuditEvent) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_AuditEvent.Merge(m, src)
+}
+func (m *AuditEvent) XXX_Size() int {
+	return xxx_messageInfo_AuditEvent.Size(m)
+}
+func (m *AuditEvent) XXX_DiscardUnknown() {
+	xxx_messageInfo_AuditEvent.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_AuditEvent proto.InternalMessageInfo
+
+func (m *AuditEvent) GetID() int64 {
+	if m != nil {
+		return m.ID
+	}
+	return 0
+}
+
+func (m *AuditEvent) GetType() string {
+	if m != nil {
+		return m.Type
+	}
+	return ""
+}
+
+func (m *AuditEvent) GetActorID() string {
+	if m != nil {
+		return m.ActorID
+	}
+	return ""
+}
+
+func (m *AuditEvent) GetTargetID() string {
+	if m != nil {
+		return m.TargetID
+	}
+	return ""
+}
+
+func (m *AuditEvent) GetReason() string {
+	if m != nil {
+		return m.Reason
+	}
+	return ""
+}
+
+func (m *AuditEvent) GetIP() string {
+	if m != nil {
+		return m.IP
+	}
+	return ""
+}
+
+func (m *AuditEvent) GetUserAgent() string {
+	if m != nil {
+		return m.UserAgent
+	}
+	return ""
+}
+
+func (m *AuditEvent) GetCreatedAt() int64 {
+	if m != nil {
+		return m.CreatedAt
+	}
+	return 0
+}
+
+func (m *AuditEvent) GetPreviousHash() string {
+	if m != nil {
+		return m.PreviousHash
+	}
+	return ""
+}
+
+func (m *AuditEvent) GetHash() string {
+	if m != nil {
+		return m.Hash
+	}
+	return ""
+}
+
+type ListAuditEventsRequest struct {
+	MemberID             string   `protobuf:"bytes,1,opt,name=MemberID,proto3" json:"MemberID,omitempty"`
+	Type                 string   `protobuf:"bytes,2,opt,name=Type,proto3" json:"Type,omitempty"`
+	BeforeID             int64    `protobuf:"varint,3,opt,name=BeforeID,proto3" json:"BeforeID,omitempty"`
+	Limit                int32    `protobuf:"varint,4,opt,name=Limit,proto3" json:"Limit,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ListAuditEventsRequest) Reset()         { *m = ListAuditEventsRequest{} }
+func (m *ListAuditEventsRequest) String() string { return proto.CompactTextString(m) }
+func (*ListAuditEventsRequest) ProtoMessage()    {}
+func (*ListAuditEventsRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_8b6ce4408de419a3, []int{1}
+}
+
+func (m *ListAuditEventsRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListAuditEventsRequest.Unmarshal(m, b)
+}
+func (m *ListAuditEventsRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListAuditEventsRequest.Marshal(b, m, deterministic)
+}
+func (m *ListAuditEventsRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListAuditEventsRequest.Merge(m, src)
+}
+func (m *ListAuditEventsRequest) XXX_Size() int {
+	return xxx_messageInfo_ListAuditEventsRequest.Size(m)
+}
+func (m *ListAuditEventsRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListAuditEventsRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListAuditEventsRequest proto.InternalMessageInfo
+
+func (m *ListAuditEventsRequest) GetMemberID() string {
+	if m != nil {
+		return m.MemberID
+	}
+	return ""
+}
+
+func (m *ListAuditEventsRequest) GetType() string {
+	if m != nil {
+		return m.Type
+	}
+	return ""
+}
+
+func (m *ListAuditEventsRequest) GetBeforeID() int64 {
+	if m != nil {
+		return m.BeforeID
+	}
+	return 0
+}
+
+func (m *ListAuditEventsRequest) GetLimit() int32 {
+	if m != nil {
+		return m.Limit
+	}
+	return 0
+}
+
+type ListAuditEventsResponse struct {
+	Events               []*AuditEvent `protobuf:"bytes,1,rep,name=Events,proto3" json:"Events,omitempty"`
+	NextBeforeID         int64         `protobuf:"varint,2,opt,name=NextBeforeID,proto3" json:"NextBeforeID,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}      `json:"-"`
+	XXX_unrecognized     []byte        `json:"-"`
+	XXX_sizecache        int32         `json:"-"`
+}
+
+func (m *ListAuditEventsResponse) Reset()         { *m = ListAuditEventsResponse{} }
+func (m *ListAuditEventsResponse) String() string { return proto.CompactTextString(m) }
+func (*ListAuditEventsResponse) ProtoMessage()    {}
+func (*ListAuditEventsResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_8b6ce4408de419a3, []int{2}
+}
+
+func (m *ListAuditEventsResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListAuditEventsResponse.Unmarshal(m, b)
+}
+func (m *ListAuditEventsResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListAuditEventsResponse.Marshal(b, m, deterministic)
+}
+func (m *ListAuditEventsResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListAuditEventsResponse.Merge(m, src)
+}
+func (m *ListAuditEventsResponse) XXX_Size() int {
+	return xxx_messageInfo_ListAuditEventsResponse.Size(m)
+}
+func (m *ListAuditEventsResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListAuditEventsResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListAuditEventsResponse proto.InternalMessageInfo
+
+func (m *ListAuditEventsResponse) GetEvents() []*AuditEvent {
+	if m != nil {
+		return m.Events
+	}
+	return nil
+}
+
+func (m *ListAuditEventsResponse) GetNextBeforeID() int64 {
+	if m != nil {
+		return m.NextBeforeID
+	}
+	return 0
+}
+
+// *****************************************************************************
+// * Login history and password
+// ****************************************************************************
+type Login struct {
+	ID                   int64    `protobuf:"varint,1,opt,name=ID,proto3" json:"ID,omitempty"`
+	IP                   string   `protobuf:"bytes,2,opt,name=IP,proto3" json:"IP,omitempty"`
+	UserAgent            string   `protobuf:"bytes,3,opt,name=UserAgent,proto3" json:"UserAgent,omitempty"`
+	NewDevice            bool     `protobuf:"varint,4,opt,name=NewDevice,proto3" json:"NewDevice,omitempty"`
+	CreatedAt            int64    `protobuf:"varint,5,opt,name=CreatedAt,proto3" json:"CreatedAt,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Login) Reset()         { *m = Login{} }
+func (m *Login) String() string { return proto.CompactTextString(m) }
+func (*Login) ProtoMessage()    {}
+func (*Login) Descriptor() ([]byte, []int) {
+	return fileDescriptor_8b6ce4408de419a3, []int{3}
+}
+
+func (m *Login) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Login.Unmarshal(m, b)
+}
+func (m *Login) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Login.Marshal(b, m, deterministic)
+}
+func (m *Login) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Login.Merge(m, src)
+}
+func (m *Login) XXX_Size() int {
+	return xxx_messageInfo_Login.Size(m)
+}
+func (m *Login) XXX_DiscardUnknown() {
+	xxx_messageInfo_Login.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Login proto.InternalMessageInfo
+
+func (m *Login) GetID() int64 {
+	if m != nil {
+		return m.ID
+	}
+	return 0
+}
+
+func (m *Login) GetIP() string {
+	if m != nil {
+		return m.IP
+	}
+	return ""
+}
+
+func (m *Login) GetUserAgent() string {
+	if m != nil {
+		return m.UserAgent
+	}
+	return ""
+}
+
+func (m *Login) GetNewDevice() bool {
+	if m != nil {
+		return m.NewDevice
+	}
+	return false
+}
+
+func (m *Login) GetCreatedAt() int64 {
+	if m != nil {
+		return m.CreatedAt
+	}
+	return 0
+}
+
+// The member is the owner of the access token
+type GetLoginHistoryRequest struct {
+	Limit                int32    `protobuf:"varint,2,opt,name=Limit,proto3" json:"Limit,omitempty"`
+	AccessToken          string   `protobuf:"bytes,3,opt,name=AccessToken,proto3" json:"AccessToken,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *GetLoginHistoryRequest) Reset()         { *m = GetLoginHistoryRequest{} }
+func (m *GetLoginHistoryRequest) String() string { return proto.CompactTextString(m) }
+func (*GetLoginHistoryRequest) ProtoMessage()    {}
+func (*GetLoginHistoryRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_8b6ce4408de419a3, []int{4}
+}
+
+func (m *GetLoginHistoryRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GetLoginHistoryRequest.Unmarshal(m, b)
+}
+func (m *GetLoginHistoryRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GetLoginHistoryRequest.Marshal(b, m, deterministic)
+}
+func (m *GetLoginHistoryRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GetLoginHistoryRequest.Merge(m, src)
+}
+func (m *GetLoginHistoryRequest) XXX_Size() int {
+	return xxx_messageInfo_GetLoginHistoryRequest.Size(m)
+}
+func (m *GetLoginHistoryRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_GetLoginHistoryRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GetLoginHistoryRequest proto.InternalMessageInfo
+
+func (m *GetLoginHistoryRequest) GetLimit() int32 {
+	if m != nil {
+		return m.Limit
+	}
+	return 0
+}
+
+func (m *GetLoginHistoryRequest) GetAccessToken() string {
+	if m != nil {
+		return m.AccessToken
+	}
+	return ""
+}
+
+type GetLoginHistoryResponse struct {
+	Logins               []*Login `protobuf:"bytes,1,rep,name=Logins,proto3" json:"Logins,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *GetLoginHistoryResponse) Reset()         { *m = GetLoginHistoryResponse{} }
+func (m *GetLoginHistoryResponse) String() string { return proto.CompactTextString(m) }
+func (*GetLoginHistoryResponse) ProtoMessage()    {}
+func (*GetLoginHistoryResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_8b6ce4408de419a3, []int{5}
+}
+
+func (m *GetLoginHistoryResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GetLoginHistoryResponse.Unmarshal(m, b)
+}
+func (m *GetLoginHistoryResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GetLoginHistoryResponse.Marshal(b, m, deterministic)
+}
+func (m *GetLoginHistoryResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GetLoginHistoryResponse.Merge(m, src)
+}
+func (m *GetLoginHistoryResponse) XXX_Size() int {
+	return xxx_messageInfo_GetLoginHistoryResponse.Size(m)
+}
+func (m *GetLoginHistoryResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_GetLoginHistoryResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GetLoginHistoryResponse proto.InternalMessageInfo
+
+func (m *GetLoginHistoryResponse) GetLogins() []*Login {
+	if m != nil {
+		return m.Logins
+	}
+	return nil
+}
+
+type ReportUnrecognizedLoginRequest struct {
+	Token                string   `protobuf:"bytes,1,opt,name=Token,proto3" json:"Token,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ReportUnrecognizedLoginRequest) Reset()         { *m = ReportUnrecognizedLoginRequest{} }
+func (m *ReportUnrecognizedLoginRequest) String() string { return proto.CompactTextString(m) }
+func (*ReportUnrecognizedLoginRequest) ProtoMessage()    {}
+func (*ReportUnrecognizedLoginRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_8b6ce4408de419a3, []int{6}
+}
+
+func (m *ReportUnrecognizedLoginRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ReportUnrecognizedLoginRequest.Unmarshal(m, b)
+}
+func (m *ReportUnrecognizedLoginRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ReportUnrecognizedLoginRequest.Marshal(b, m, deterministic)
+}
+func (m *ReportUnrecognizedLoginRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ReportUnrecognizedLoginRequest.Merge(m, src)
+}
+func (m *ReportUnrecognizedLoginRequest) XXX_Size() int {
+	return xxx_messageInfo_ReportUnrecognizedLoginRequest.Size(m)
+}
+func (m *ReportUnrecognizedLoginRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ReportUnrecognizedLoginRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ReportUnrecognizedLoginRequest proto.InternalMessageInfo
+
+func (m *ReportUnrecognizedLoginRequest) GetToken() string {
+	if m != nil {
+		return m.Token
+	}
+	return ""
+}
+
+type ReportUnrecognizedLoginResponse struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ReportUnrecognizedLoginResponse) Reset()         { *m = ReportUnrecognizedLoginResponse{} }
+func (m *ReportUnrecognizedLoginResponse) String() string { return proto.CompactTextString(m) }
+func (*ReportUnrecognizedLoginResponse) ProtoMessage()    {}
+func (*ReportUnrecognizedLoginResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_8b6ce4408de419a3, []int{7}
+}
+
+func (m *ReportUnrecognizedLoginResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ReportUnrecognizedLoginResponse.Unmarshal(m, b)
+}
+func (m *ReportUnrecognizedLoginResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ReportUnrecognizedLoginResponse.Marshal(b, m, deterministic)
+}
+func (m *ReportUnrecognizedLoginResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ReportUnrecognizedLoginResponse.Merge(m, src)
+}
+func (m *ReportUnrecognizedLoginResponse) XXX_Size() int {
+	return xxx_messageInfo_ReportUnrecognizedLoginResponse.Size(m)
+}
+func (m *ReportUnrecognizedLoginResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_ReportUnrecognizedLoginResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ReportUnrecognizedLoginResponse proto.InternalMessageInfo
+
+type ChangePasswordRequest struct {
+	Email          string `protobuf:"bytes,1,opt,name=Email,proto3" json:"Email,omitempty"`
+	Password       string `protobuf:"bytes,2,opt,name=Password,proto3" json:"Password,omitempty"`
+	NewPassword    string `protobuf:"bytes,3,opt,name=NewPassword,proto3" json:"NewPassword,omitempty"`
+	PrivateKey     string `protobuf:"bytes,4,opt,name=PrivateKey,proto3" json:"PrivateKey,omitempty"`
+	PrivateKeyIV   string `protobuf:"bytes,5,opt,name=PrivateKeyIV,proto3" json:"PrivateKeyIV,omitempty"`
+	PrivateKeySalt string `protobuf:"bytes,6,opt,name=PrivateKeySalt,proto3" json:"PrivateKeySalt,omitempty"`
+	// Required with PasswordChangeRequired : the token of the reported alert
+	Token                string   `protobuf:"bytes,7,opt,name=Token,proto3" json:"Token,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ChangePasswordRequest) Reset()         { *m = ChangePasswordRequest{} }
+func (m *ChangePasswordRequest) String() string { return proto.CompactTextString(m) }
+func (*ChangePasswordRequest) ProtoMessage()    {}
+func (*ChangePasswordRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_8b6ce4408de419a3, []int{8}
+}
+
+func (m *ChangePasswordRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ChangePasswordRequest.Unmarshal(m, b)
+}
+func (m *ChangePasswordRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ChangePasswordRequest.Marshal(b, m, deterministic)
+}
+func (m *ChangePasswordRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ChangePasswordRequest.Merge(m, src)
+}
+func (m *ChangePasswordRequest) XXX_Size() int {
+	return xxx_messageInfo_ChangePasswordRequest.Size(m)
+}
+func (m *ChangePasswordRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ChangePasswordRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ChangePasswordRequest proto.InternalMessageInfo
+
+func (m *ChangePasswordRequest) GetEmail() string {
+	if m != nil {
+		return m.Email
+	}
+	return ""
+}
+
+func (m *ChangePasswordRequest) GetPassword() string {
+	if m != nil {
+		return m.Password
+	}
+	return ""
+}
+
+func (m *ChangePasswordRequest) GetNewPassword() string {
+	if m != nil {
+		return m.NewPassword
+	}
+	return ""
+}
+
+func (m *ChangePasswordRequest) GetPrivateKey() string {
+	if m != nil {
+		return m.PrivateKey
+	}
+	return ""
+}
+
+func (m *ChangePasswordRequest) GetPrivateKeyIV() string {
+	if m != nil {
+		return m.PrivateKeyIV
+	}
+	return ""
+}
+
+func (m *ChangePasswordRequest) GetPrivateKeySalt() string {
+	if m != nil {
+		return m.PrivateKeySalt
+	}
+	return ""
+}
+
+func (m *ChangePasswordRequest) GetToken() string {
+	if m != nil {
+		return m.Token
+	}
+	return ""
+}
+
+type ChangePasswordResponse struct {
+	MemberID             string   `protobuf:"bytes,1,opt,name=MemberID,proto3" json:"MemberID,omitempty"`
+	AccessToken          string   `protobuf:"bytes,2,opt,name=AccessToken,proto3" json:"AccessToken,omitempty"`
+	AccessExpiration     int64    `protobuf:"varint,3,opt,name=AccessExpiration,proto3" json:"AccessExpiration,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ChangePasswordResponse) Reset()         { *m = ChangePasswordResponse{} }
+func (m *ChangePasswordResponse) String() string { return proto.CompactTextString(m) }
+func (*ChangePasswordResponse) ProtoMessage()    {}
+func (*ChangePasswordResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_8b6ce4408de419a3, []int{9}
+}
+
+func (m *ChangePasswordResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ChangePasswordResponse.Unmarshal(m, b)
+}
+func (m *ChangePasswordResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ChangePasswordResponse.Marshal(b, m, deterministic)
+}
+func (m *ChangePasswordResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ChangePasswordResponse.Merge(m, src)
+}
+func (m *ChangePasswordResponse) XXX_Size() int {
+	return xxx_messageInfo_ChangePasswordResponse.Size(m)
+}
+func (m *ChangePasswordResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_ChangePasswordResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ChangePasswordResponse proto.InternalMessageInfo
+
+func (m *ChangePasswordResponse) GetMemberID() string {
+	if m != nil {
+		return m.MemberID
+	}
+	return ""
+}
+
+func (m *ChangePasswordResponse) GetAccessToken() string {
+	if m != nil {
+		return m.AccessToken
+	}
+	return ""
+}
+
+func (m *ChangePasswordResponse) GetAccessExpiration() int64 {
+	if m != nil {
+		return m.AccessExpiration
+	}
+	return 0
+}
+
+// *****************************************************************************
+// * Storage
+// ****************************************************************************
+type ReserveStorageRequest struct {
+	MemberID             string   `protobuf:"bytes,1,opt,name=MemberID,proto3" json:"MemberID,omitempty"`
+	Bytes                int64    `protobuf:"varint,2,opt,name=Bytes,proto3" json:"Bytes,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ReserveStorageRequest) Reset()         { *m = ReserveStorageRequest{} }
+func (m *ReserveStorageRequest) String() string { return proto.CompactTextString(m) }
+func (*ReserveStorageRequest) ProtoMessage()    {}
+func (*ReserveStorageRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_8b6ce4408de419a3, []int{10}
+}
+
+func (m *ReserveStorageRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ReserveStorageRequest.Unmarshal(m, b)
+}
+func (m *ReserveStorageRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ReserveStorageRequest.Marshal(b, m, deterministic)
+}
+func (m *ReserveStorageRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ReserveStorageRequest.Merge(m, src)
+}
+func (m *ReserveStorageRequest) XXX_Size() int {
+	return xxx_messageInfo_ReserveStorageRequest.Size(m)
+}
+func (m *ReserveStorageRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ReserveStorageRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ReserveStorageRequest proto.InternalMessageInfo
+
+func (m *ReserveStorageRequest) GetMemberID() string {
+	if m != nil {
+		return m.MemberID
+	}
+	return ""
+}
+
+func (m *ReserveStorageRequest) GetBytes() int64 {
+	if m != nil {
+		return m.Bytes
+	}
+	return 0
+}
+
+type ReserveStorageResponse struct {
+	ReservationID        string   `protobuf:"bytes,1,opt,name=ReservationID,proto3" json:"ReservationID,omitempty"`
+	ExpiresAt            int64    `protobuf:"varint,2,opt,name=ExpiresAt,proto3" json:"ExpiresAt,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ReserveStorageResponse) Reset()         { *m = ReserveStorageResponse{} }
+func (m *ReserveStorageResponse) String() string { return proto.CompactTextString(m) }
+func (*ReserveStorageResponse) ProtoMessage()    {}
+func (*ReserveStorageResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_8b6ce4408de419a3, []int{11}
+}
+
+func (m *ReserveStorageResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ReserveStorageResponse.Unmarshal(m, b)
+}
+func (m *ReserveStorageResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ReserveStorageResponse.Marshal(b, m, deterministic)
+}
+func (m *ReserveStorageResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ReserveStorageResponse.Merge(m, src)
+}
+func (m *ReserveStorageResponse) XXX_Size() int {
+	return xxx_messageInfo_ReserveStorageResponse.Size(m)
+}
+func (m *ReserveStorageResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_ReserveStorageResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ReserveStorageResponse proto.InternalMessageInfo
+
+func (m *ReserveStorageResponse) GetReservationID() string {
+	if m != nil {
+		return m.ReservationID
+	}
+	return ""
+}
+
+func (m *ReserveStorageResponse) GetExpiresAt() int64 {
+	if m != nil {
+		return m.ExpiresAt
+	}
+	return 0
+}
+
+type CommitStorageRequest struct {
+	MemberID             string   `protobuf:"bytes,1,opt,name=MemberID,proto3" json:"MemberID,omitempty"`
+	ReservationID        string   `protobuf:"bytes,2,opt,name=ReservationID,proto3" json:"ReservationID,omitempty"`
+	FullBytes            int64    `protobuf:"varint,3,opt,name=FullBytes,proto3" json:"FullBytes,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *CommitStorageRequest) Reset()         { *m = CommitStorageRequest{} }
+func (m *CommitStorageRequest) String() string { return proto.CompactTextString(m) }
+func (*CommitStorageRequest) ProtoMessage()    {}
+func (*CommitStorageRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_8b6ce4408de419a3, []int{12}
+}
+
+func (m *CommitStorageRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CommitStorageRequest.Unmarshal(m, b)
+}
+func (m *CommitStorageRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CommitStorageRequest.Marshal(b, m, deterministic)
+}
+func (m *CommitStorageRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CommitStorageRequest.Merge(m, src)
+}
+func (m *CommitStorageRequest) XXX_Size() int {
+	return xxx_messageInfo_CommitStorageRequest.Size(m)
+}
+func (m *CommitStorageRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_CommitStorageRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_CommitStorageRequest proto.InternalMessageInfo
+
+func (m *CommitStorageRequest) GetMemberID() string {
+	if m != nil {
+		return m.MemberID
+	}
+	return ""
+}
+
+func (m *CommitStorageRequest) GetReservationID() string {
+	if m != nil {
+		return m.ReservationID
+	}
+	return ""
+}
+
+func (m *CommitStorageRequest) GetFullBytes() int64 {
+	if m != nil {
+		return m.FullBytes
+	}
+	return 0
+}
+
+type ReleaseStorageRequest struct {
+	MemberID             string   `protobuf:"bytes,1,opt,name=MemberID,proto3" json:"MemberID,omitempty"`
+	ReservationID        string   `protobuf:"bytes,2,opt,name=ReservationID,proto3" json:"ReservationID,omitempty"`
+	Bytes                int64    `protobuf:"varint,3,opt,name=Bytes,proto3" json:"Bytes,omitempty"`
+	FullBytes            int64    `protobuf:"varint,4,opt,name=FullBytes,proto3" json:"FullBytes,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ReleaseStorageRequest) Reset()         { *m = ReleaseStorageRequest{} }
+func (m *ReleaseStorageRequest) String() string { return proto.CompactTextString(m) }
+func (*ReleaseStorageRequest) ProtoMessage()    {}
+func (*ReleaseStorageRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_8b6ce4408de419a3, []int{13}
+}
+
+func (m *ReleaseStorageRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ReleaseStorageRequest.Unmarshal(m, b)
+}
+func (m *ReleaseStorageRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ReleaseStorageRequest.Marshal(b, m, deterministic)
+}
+func (m *ReleaseStorageRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ReleaseStorageRequest.Merge(m, src)
+}
+func (m *ReleaseStorageRequest) XXX_Size() int {
+	return xxx_messageInfo_ReleaseStorageRequest.Size(m)
+}
+func (m *ReleaseStorageRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ReleaseStorageRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ReleaseStorageRequest proto.InternalMessageInfo
+
+func (m *ReleaseStorageRequest) GetMemberID() string {
+	if m != nil {
+		return m.MemberID
+	}
+	return ""
+}
+
+func (m *ReleaseStorageRequest) GetReservationID() string {
+	if m != nil {
+		return m.ReservationID
+	}
+	return ""
+}
+
+func (m *ReleaseStorageRequest) GetBytes() int64 {
+	if m != nil {
+		return m.Bytes
+	}
+	return 0
+}
+
+func (m *ReleaseStorageRequest) GetFullBytes() int64 {
+	if m != nil {
+		return m.FullBytes
+	}
+	return 0
+}
+
+type SetStorageQuotaRequest struct {
+	MemberID             string   `protobuf:"bytes,1,opt,name=MemberID,proto3" json:"MemberID,omitempty"`
+	Quota                int64    `protobuf:"varint,2,opt,name=Quota,proto3" json:"Quota,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *SetStorageQuotaRequest) Reset()         { *m = SetStorageQuotaRequest{} }
+func (m *SetStorageQuotaRequest) String() string { return proto.CompactTextString(m) }
+func (*SetStorageQuotaRequest) ProtoMessage()    {}
+func (*SetStorageQuotaRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_8b6ce4408de419a3, []int{14}
+}
+
+func (m *SetStorageQuotaRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SetStorageQuotaRequest.Unmarshal(m, b)
+}
+func (m *SetStorageQuotaRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SetStorageQuotaRequest.Marshal(b, m, deterministic)
+}
+func (m *SetStorageQuotaRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SetStorageQuotaRequest.Merge(m, src)
+}
+func (m *SetStorageQuotaRequest) XXX_Size() int {
+	return xxx_messageInfo_SetStorageQuotaRequest.Size(m)
+}
+func (m *SetStorageQuotaRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_SetStorageQuotaRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SetStorageQuotaRequest proto.InternalMessageInfo
+
+func (m *SetStorageQuotaRequest) GetMemberID() string {
+	if m != nil {
+		return m.MemberID
+	}
+	return ""
+}
+
+func (m *SetStorageQuotaRequest) GetQuota() int64 {
+	if m != nil {
+		return m.Quota
+	}
+	return 0
+}
+
+type StorageResponse struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *StorageResponse) Reset()         { *m = StorageResponse{} }
+func (m *StorageResponse) String() string { return proto.CompactTextString(m) }
+func (*StorageResponse) ProtoMessage()    {}
+func (*StorageResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_8b6ce4408de419a3, []int{15}
+}
+
+func (m *StorageResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_StorageResponse.Unmarshal(m, b)
+}
+func (m *StorageResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_StorageResponse.Marshal(b, m, deterministic)
+}
+func (m *StorageResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_StorageResponse.Merge(m, src)
+}
+func (m *StorageResponse) XXX_Size() int {
+	return xxx_messageInfo_StorageResponse.Size(m)
+}
+func (m *StorageResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_StorageResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_StorageResponse proto.InternalMessageInfo
+
+// *****************************************************************************
+// * Plans
+// ****************************************************************************
+type Plan struct {
+	ID                   string   `protobuf:"bytes,1,opt,name=ID,proto3" json:"ID,omitempty"`
+	Name                 string   `protobuf:"bytes,2,opt,name=Name,proto3" json:"Name,omitempty"`
+	StorageQuota         int64    `protobuf:"varint,3,opt,name=StorageQuota,proto3" json:"StorageQuota,omitempty"`
+	MaxPictures          int64    `protobuf:"varint,4,opt,name=MaxPictures,proto3" json:"MaxPictures,omitempty"`
+	MaxAlbums            int64    `protobuf:"varint,5,opt,name=MaxAlbums,proto3" json:"MaxAlbums,omitempty"`
+	MaxSessions          int64    `protobuf:"varint,6,opt,name=MaxSessions,proto3" json:"MaxSessions,omitempty"`
+	Features             []string `protobuf:"bytes,7,rep,name=Features,proto3" json:"Features,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Plan) Reset()         { *m = Plan{} }
+func (m *Plan) String() string { return proto.CompactTextString(m) }
+func (*Plan) ProtoMessage()    {}
+func (*Plan) Descriptor() ([]byte, []int) {
+	return fileDescriptor_8b6ce4408de419a3, []int{16}
+}
+
+func (m *Plan) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Plan.Unmarshal(m, b)
+}
+func (m *Plan) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Plan.Marshal(b, m, deterministic)
+}
+func (m *Plan) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Plan.Merge(m, src)
+}
+func (m *Plan) XXX_Size() int {
+	return xxx_messageInfo_Plan.Size(m)
+}
+func (m *Plan) XXX_DiscardUnknown() {
+	xxx_messageInfo_Plan.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Plan proto.InternalMessageInfo
+
+func (m *Plan) GetID() string {
+	if m != nil {
+		return m.ID
+	}
+	return ""
+}
+
+func (m *Plan) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *Plan) GetStorageQuota() int64 {
+	if m != nil {
+		return m.StorageQuota
+	}
+	return 0
+}
+
+func (m *Plan) GetMaxPictures() int64 {
+	if m != nil {
+		return m.MaxPictures
+	}
+	return 0
+}
+
+func (m *Plan) GetMaxAlbums() int64 {
+	if m != nil {
+		return m.MaxAlbums
+	}
+	return 0
+}
+
+func (m *Plan) GetMaxSessions() int64 {
+	if m != nil {
+		return m.MaxSessions
+	}
+	return 0
+}
+
+func (m *Plan) GetFeatures() []string {
+	if m != nil {
+		return m.Features
+	}
+	return nil
+}
+
+type ListPlansRequest struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ListPlansRequest) Reset()         { *m = ListPlansRequest{} }
+func (m *ListPlansRequest) String() string { return proto.CompactTextString(m) }
+func (*ListPlansRequest) ProtoMessage()    {}
+func (*ListPlansRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_8b6ce4408de419a3, []int{17}
+}
+
+func (m *ListPlansRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListPlansRequest.Unmarshal(m, b)
+}
+func (m *ListPlansRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListPlansRequest.Marshal(b, m, deterministic)
+}
+func (m *ListPlansRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListPlansRequest.Merge(m, src)
+}
+func (m *ListPlansRequest) XXX_Size() int {
+	return xxx_messageInfo_ListPlansRequest.Size(m)
+}
+func (m *ListPlansRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListPlansRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListPlansRequest proto.InternalMessageInfo
+
+type ListPlansResponse struct {
+	Plans                []*Plan  `protobuf:"bytes,1,rep,name=Plans,proto3" json:"Plans,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ListPlansResponse) Reset()         { *m = ListPlansResponse{} }
+func (m *ListPlansResponse) String() string { return proto.CompactTextString(m) }
+func (*ListPlansResponse) ProtoMessage()    {}
+func (*ListPlansResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_8b6ce4408de419a3, []int{18}
+}
+
+func (m *ListPlansResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListPlansResponse.Unmarshal(m, b)
+}
+func (m *ListPlansResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListPlansResponse.Marshal(b, m, deterministic)
+}
+func (m *ListPlansResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListPlansResponse.Merge(m, src)
+}
+func (m *ListPlansResponse) XXX_Size() int {
+	return xxx_messageInfo_ListPlansResponse.Size(m)
+}
+func (m *ListPlansResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListPlansResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListPlansResponse proto.InternalMessageInfo
+
+func (m *ListPlansResponse) GetPlans() []*Plan {
+	if m != nil {
+		return m.Plans
+	}
+	return nil
+}
+
+type SetMemberPlanRequest struct {
+	MemberID             string   `protobuf:"bytes,1,opt,name=MemberID,proto3" json:"MemberID,omitempty"`
+	PlanID               string   `protobuf:"bytes,2,opt,name=PlanID,proto3" json:"PlanID,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *SetMemberPlanRequest) Reset()         { *m = SetMemberPlanRequest{} }
+func (m *SetMemberPlanRequest) String() string { return proto.CompactTextString(m) }
+func (*SetMemberPlanRequest) ProtoMessage()    {}
+func (*SetMemberPlanRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_8b6ce4408de419a3, []int{19}
+}
+
+func (m *SetMemberPlanRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SetMemberPlanRequest.Unmarshal(m, b)
+}
+func (m *SetMemberPlanRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SetMemberPlanRequest.Marshal(b, m, deterministic)
+}
+func (m *SetMemberPlanRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SetMemberPlanRequest.Merge(m, src)
+}
+func (m *SetMemberPlanRequest) XXX_Size() int {
+	return xxx_messageInfo_SetMemberPlanRequest.Size(m)
+}
+func (m *SetMemberPlanRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_SetMemberPlanRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SetMemberPlanRequest proto.InternalMessageInfo
+
+func (m *SetMemberPlanRequest) GetMemberID() string {
+	if m != nil {
+		return m.MemberID
+	}
+	return ""
+}
+
+func (m *SetMemberPlanRequest) GetPlanID() string {
+	if m != nil {
+		return m.PlanID
+	}
+	return ""
+}
+
+type SetMemberPlanResponse struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *SetMemberPlanResponse) Reset()         { *m = SetMemberPlanResponse{} }
+func (m *SetMemberPlanResponse) String() string { return proto.CompactTextString(m) }
+func (*SetMemberPlanResponse) ProtoMessage()    {}
+func (*SetMemberPlanResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_8b6ce4408de419a3, []int{20}
+}
+
+func (m *SetMemberPlanResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SetMemberPlanResponse.Unmarshal(m, b)
+}
+func (m *SetMemberPlanResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SetMemberPlanResponse.Marshal(b, m, deterministic)
+}
+func (m *SetMemberPlanResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SetMemberPlanResponse.Merge(m, src)
+}
+func (m *SetMemberPlanResponse) XXX_Size() int {
+	return xxx_messageInfo_SetMemberPlanResponse.Size(m)
+}
+func (m *SetMemberPlanResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_SetMemberPlanResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SetMemberPlanResponse proto.InternalMessageInfo
+
+type GetMemberLimitsRequest struct {
+	MemberID             string   `protobuf:"bytes,1,opt,name=MemberID,proto3" json:"MemberID,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *GetMemberLimitsRequest) Reset()         { *m = GetMemberLimitsRequest{} }
+func (m *GetMemberLimitsRequest) String() string { return proto.CompactTextString(m) }
+func (*GetMemberLimitsRequest) ProtoMessage()    {}
+func (*GetMemberLimitsRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_8b6ce4408de419a3, []int{21}
+}
+
+func (m *GetMemberLimitsRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GetMemberLimitsRequest.Unmarshal(m, b)
+}
+func (m *GetMemberLimitsRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GetMemberLimitsRequest.Marshal(b, m, deterministic)
+}
+func (m *GetMemberLimitsRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GetMemberLimitsRequest.Merge(m, src)
+}
+func (m *GetMemberLimitsRequest) XXX_Size() int {
+	return xxx_messageInfo_GetMemberLimitsRequest.Size(m)
+}
+func (m *GetMemberLimitsRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_GetMemberLimitsRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GetMemberLimitsRequest proto.InternalMessageInfo
+
+func (m *GetMemberLimitsRequest) GetMemberID() string {
+	if m != nil {
+		return m.MemberID
+	}
+	return ""
+}
+
+type MemberLimits struct {
+	MemberID             string   `protobuf:"bytes,1,opt,name=MemberID,proto3" json:"MemberID,omitempty"`
+	PlanID               string   `protobuf:"bytes,2,opt,name=PlanID,proto3" json:"PlanID,omitempty"`
+	PlanName             string   `protobuf:"bytes,3,opt,name=PlanName,proto3" json:"PlanName,omitempty"`
+	StorageQuota         int64    `protobuf:"varint,4,opt,name=StorageQuota,proto3" json:"StorageQuota,omitempty"`
+	UsedStorage          float64  `protobuf:"fixed64,5,opt,name=UsedStorage,proto3" json:"UsedStorage,omitempty"`
+	ReservedStorage      int64    `protobuf:"varint,6,opt,name=ReservedStorage,proto3" json:"ReservedStorage,omitempty"`
+	MaxPictures          int64    `protobuf:"varint,7,opt,name=MaxPictures,proto3" json:"MaxPictures,omitempty"`
+	MaxAlbums            int64    `protobuf:"varint,8,opt,name=MaxAlbums,proto3" json:"MaxAlbums,omitempty"`
+	MaxSessions          int64    `protobuf:"varint,9,opt,name=MaxSessions,proto3" json:"MaxSessions,omitempty"`
+	Features             []string `protobuf:"bytes,10,rep,name=Features,proto3" json:"Features,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *MemberLimits) Reset()         { *m = MemberLimits{} }
+func (m *MemberLimits) String() string { return proto.CompactTextString(m) }
+func (*MemberLimits) ProtoMessage()    {}
+func (*MemberLimits) Descriptor() ([]byte, []int) {
+	return fileDescriptor_8b6ce4408de419a3, []int{22}
+}
+
+func (m *MemberLimits) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_MemberLimits.Unmarshal(m, b)
+}
+func (m *MemberLimits) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_MemberLimits.Marshal(b, m, deterministic)
+}
+func (m *MemberLimits) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_MemberLimits.Merge(m, src)
+}
+func (m *MemberLimits) XXX_Size() int {
+	return xxx_messageInfo_MemberLimits.Size(m)
+}
+func (m *MemberLimits) XXX_DiscardUnknown() {
+	xxx_messageInfo_MemberLimits.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_MemberLimits proto.InternalMessageInfo
+
+func (m *MemberLimits) GetMemberID() string {
+	if m != nil {
+		return m.MemberID
+	}
+	return ""
+}
+
+func (m *MemberLimits) GetPlanID() string {
+	if m != nil {
+		return m.PlanID
+	}
+	return ""
+}
+
+func (m *MemberLimits) GetPlanName() string {
+	if m != nil {
+		return m.PlanName
+	}
+	return ""
+}
+
+func (m *MemberLimits) GetStorageQuota() int64 {
+	if m != nil {
+		return m.StorageQuota
+	}
+	return 0
+}
+
+func (m *MemberLimits) GetUsedStorage() float64 {
+	if m != nil {
+		return m.UsedStorage
+	}
+	return 0
+}
+
+func (m *MemberLimits) GetReservedStorage() int64 {
+	if m != nil {
+		return m.ReservedStorage
+	}
+	return 0
+}
+
+func (m *MemberLimits) GetMaxPictures() int64 {
+	if m != nil {
+		return m.MaxPictures
+	}
+	return 0
+}
+
+func (m *MemberLimits) GetMaxAlbums() int64 {
+	if m != nil {
+		return m.MaxAlbums
+	}
+	return 0
+}
+
+func (m *MemberLimits) GetMaxSessions() int64 {
+	if m != nil {
+		return m.MaxSessions
+	}
+	return 0
+}
+
+func (m *MemberLimits) GetFeatures() []string {
+	if m != nil {
+		return m.Features
+	}
+	return nil
+}
+
+// *****************************************************************************
+// * Invitations
+// ****************************************************************************
+type Invitation struct {
+	ID                   string   `protobuf:"bytes,1,opt,name=ID,proto3" json:"ID,omitempty"`
+	Email                string   `protobuf:"bytes,2,opt,name=Email,proto3" json:"Email,omitempty"`
+	PlanID               string   `protobuf:"bytes,3,opt,name=PlanID,proto3" json:"PlanID,omitempty"`
+	MaxUses              int64    `protobuf:"varint,4,opt,name=MaxUses,proto3" json:"MaxUses,omitempty"`
+	Uses                 int64    `protobuf:"varint,5,opt,name=Uses,proto3" json:"Uses,omitempty"`
+	ExpiresAt            int64    `protobuf:"varint,6,opt,name=ExpiresAt,proto3" json:"ExpiresAt,omitempty"`
+	CreatedBy            string   `protobuf:"bytes,7,opt,name=CreatedBy,proto3" json:"CreatedBy,omitempty"`
+	CreatedAt            int64    `protobuf:"varint,8,opt,name=CreatedAt,proto3" json:"CreatedAt,omitempty"`
+	RevokedAt            int64    `protobuf:"varint,9,opt,name=RevokedAt,proto3" json:"RevokedAt,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Invitation) Reset()         { *m = Invitation{} }
+func (m *Invitation) String() string { return proto.CompactTextString(m) }
+func (*Invitation) ProtoMessage()    {}
+func (*Invitation) Descriptor() ([]byte, []int) {
+	return fileDescriptor_8b6ce4408de419a3, []int{23}
+}
+
+func (m *Invitation) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Invitation.Unmarshal(m, b)
+}
+func (m *Invitation) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Invitation.Marshal(b, m, deterministic)
+}
+func (m *Invitation) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Invitation.Merge(m, src)
+}
+func (m *Invitation) XXX_Size() int {
+	return xxx_messageInfo_Invitation.Size(m)
+}
+func (m *Invitation) XXX_DiscardUnknown() {
+	xxx_messageInfo_Invitation.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Invitation proto.InternalMessageInfo
+
+func (m *Invitation) GetID() string {
+	if m != nil {
+		return m.ID
+	}
+	return ""
+}
+
+func (m *Invitation) GetEmail() string {
+	if m != nil {
+		return m.Email
+	}
+	return ""
+}
+
+func (m *Invitation) GetPlanID() string {
+	if m != nil {
+		return m.PlanID
+	}
+	return ""
+}
+
+func (m *Invitation) GetMaxUses() int64 {
+	if m != nil {
+		return m.MaxUses
+	}
+	return 0
+}
+
+func (m *Invitation) GetUses() int64 {
+	if m != nil {
+		return m.Uses
+	}
+	return 0
+}
+
+func (m *Invitation) GetExpiresAt() int64 {
+	if m != nil {
+		return m.ExpiresAt
+	}
+	return 0
+}
+
+func (m *Invitation) GetCreatedBy() string {
+	if m != nil {
+		return m.CreatedBy
+	}
+	return ""
+}
+
+func (m *Invitation) GetCreatedAt() int64 {
+	if m != nil {
+		return m.CreatedAt
+	}
+	return 0
+}
+
+func (m *Invitation) GetRevokedAt() int64 {
+	if m != nil {
+		return m.RevokedAt
+	}
+	return 0
+}
+
+type CreateInvitationRequest struct {
+	Email                string   `protobuf:"bytes,1,opt,name=Email,proto3" json:"Email,omitempty"`
+	PlanID               string   `protobuf:"bytes,2,opt,name=PlanID,proto3" json:"PlanID,omitempty"`
+	MaxUses              int64    `protobuf:"varint,3,opt,name=MaxUses,proto3" json:"MaxUses,omitempty"`
+	Expiration           int64    `protobuf:"varint,4,opt,name=Expiration,proto3" json:"Expiration,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *CreateInvitationRequest) Reset()         { *m = CreateInvitationRequest{} }
+func (m *CreateInvitationRequest) String() string { return proto.CompactTextString(m) }
+func (*CreateInvitationRequest) ProtoMessage()    {}
+func (*CreateInvitationRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_8b6ce4408de419a3, []int{24}
+}
+
+func (m *CreateInvitationRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CreateInvitationRequest.Unmarshal(m, b)
+}
+func (m *CreateInvitationRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CreateInvitationRequest.Marshal(b, m, deterministic)
+}
+func (m *CreateInvitationRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CreateInvitationRequest.Merge(m, src)
+}
+func (m *CreateInvitationRequest) XXX_Size() int {
+	return xxx_messageInfo_CreateInvitationRequest.Size(m)
+}
+func (m *CreateInvitationRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_CreateInvitationRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_CreateInvitationRequest proto.InternalMessageInfo
+
+func (m *CreateInvitationRequest) GetEmail() string {
+	if m != nil {
+		return m.Email
+	}
+	return ""
+}
+
+func (m *CreateInvitationRequest) GetPlanID() string {
+	if m != nil {
+		return m.PlanID
+	}
+	return ""
+}
+
+func (m *CreateInvitationRequest) GetMaxUses() int64 {
+	if m != nil {
+		return m.MaxUses
+	}
+	return 0
+}
+
+func (m *CreateInvitationRequest) GetExpiration() int64 {
+	if m != nil {
+		return m.Expiration
+	}
+	return 0
+}
+
+type CreateInvitationResponse struct {
+	Code                 string      `protobuf:"bytes,1,opt,name=Code,proto3" json:"Code,omitempty"`
+	Invitation           *Invitation `protobuf:"bytes,2,opt,name=Invitation,proto3" json:"Invitation,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}    `json:"-"`
+	XXX_unrecognized     []byte      `json:"-"`
+	XXX_sizecache        int32       `json:"-"`
+}
+
+func (m *CreateInvitationResponse) Reset()         { *m = CreateInvitationResponse{} }
+func (m *CreateInvitationResponse) String() string { return proto.CompactTextString(m) }
+func (*CreateInvitationResponse) ProtoMessage()    {}
+func (*CreateInvitationResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_8b6ce4408de419a3, []int{25}
+}
+
+func (m *CreateInvitationResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CreateInvitationResponse.Unmarshal(m, b)
+}
+func (m *CreateInvitationResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CreateInvitationResponse.Marshal(b, m, deterministic)
+}
+func (m *CreateInvitationResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CreateInvitationResponse.Merge(m, src)
+}
+func (m *CreateInvitationResponse) XXX_Size() int {
+	return xxx_messageInfo_CreateInvitationResponse.Size(m)
+}
+func (m *CreateInvitationResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_CreateInvitationResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_CreateInvitationResponse proto.InternalMessageInfo
+
+func (m *CreateInvitationResponse) GetCode() string {
+	if m != nil {
+		return m.Code
+	}
+	return ""
+}
+
+func (m *CreateInvitationResponse) GetInvitation() *Invitation {
+	if m != nil {
+		return m.Invitation
+	}
+	return nil
+}
+
+type ListInvitationsRequest struct {
+	Limit                int32    `protobuf:"varint,1,opt,name=Limit,proto3" json:"Limit,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ListInvitationsRequest) Reset()         { *m = ListInvitationsRequest{} }
+func (m *ListInvitationsRequest) String() string { return proto.CompactTextString(m) }
+func (*ListInvitationsRequest) ProtoMessage()    {}
+func (*ListInvitationsRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_8b6ce4408de419a3, []int{26}
+}
+
+func (m *ListInvitationsRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListInvitationsRequest.Unmarshal(m, b)
+}
+func (m *ListInvitationsRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListInvitationsRequest.Marshal(b, m, deterministic)
+}
+func (m *ListInvitationsRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListInvitationsRequest.Merge(m, src)
+}
+func (m *ListInvitationsRequest) XXX_Size() int {
+	return xxx_messageInfo_ListInvitationsRequest.Size(m)
+}
+func (m *ListInvitationsRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListInvitationsRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListInvitationsRequest proto.InternalMessageInfo
+
+func (m *ListInvitationsRequest) GetLimit() int32 {
+	if m != nil {
+		return m.Limit
+	}
+	return 0
+}
+
+type ListInvitationsResponse struct {
+	Invitations          []*Invitation `protobuf:"bytes,1,rep,name=Invitations,proto3" json:"Invitations,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}      `json:"-"`
+	XXX_unrecognized     []byte        `json:"-"`
+	XXX_sizecache        int32         `json:"-"`
+}
+
+func (m *ListInvitationsResponse) Reset()         { *m = ListInvitationsResponse{} }
+func (m *ListInvitationsResponse) String() string { return proto.CompactTextString(m) }
+func (*ListInvitationsResponse) ProtoMessage()    {}
+func (*ListInvitationsResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_8b6ce4408de419a3, []int{27}
+}
+
+func (m *ListInvitationsResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListInvitationsResponse.Unmarshal(m, b)
+}
+func (m *ListInvitationsResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListInvitationsResponse.Marshal(b, m, deterministic)
+}
+func (m *ListInvitationsResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListInvitationsResponse.Merge(m, src)
+}
+func (m *ListInvitationsResponse) XXX_Size() int {
+	return xxx_messageInfo_ListInvitationsResponse.Size(m)
+}
+func (m *ListInvitationsResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListInvitationsResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListInvitationsResponse proto.InternalMessageInfo
+
+func (m *ListInvitationsResponse) GetInvitations() []*Invitation {
+	if m != nil {
+		return m.Invitations
+	}
+	return nil
+}
+
+type RevokeInvitationRequest struct {
+	InvitationID         string   `protobuf:"bytes,1,opt,name=InvitationID,proto3" json:"InvitationID,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *RevokeInvitationRequest) Reset()         { *m = RevokeInvitationRequest{} }
+func (m *RevokeInvitationRequest) String() string { return proto.CompactTextString(m) }
+func (*RevokeInvitationRequest) ProtoMessage()    {}
+func (*RevokeInvitationRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_8b6ce4408de419a3, []int{28}
+}
+
+func (m *RevokeInvitationRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_RevokeInvitationRequest.Unmarshal(m, b)
+}
+func (m *RevokeInvitationRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_RevokeInvitationRequest.Marshal(b, m, deterministic)
+}
+func (m *RevokeInvitationRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RevokeInvitationRequest.Merge(m, src)
+}
+func (m *RevokeInvitationRequest) XXX_Size() int {
+	return xxx_messageInfo_RevokeInvitationRequest.Size(m)
+}
+func (m *RevokeInvitationRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_RevokeInvitationRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_RevokeInvitationRequest proto.InternalMessageInfo
+
+func (m *RevokeInvitationRequest) GetInvitationID() string {
+	if m != nil {
+		return m.InvitationID
+	}
+	return ""
+}
+
+type RevokeInvitationResponse struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *RevokeInvitationResponse) Reset()         { *m = RevokeInvitationResponse{} }
+func (m *RevokeInvitationResponse) String() string { return proto.CompactTextString(m) }
+func (*RevokeInvitationResponse) ProtoMessage()    {}
+func (*RevokeInvitationResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_8b6ce4408de419a3, []int{29}
+}
+
+func (m *RevokeInvitationResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_RevokeInvitationResponse.Unmarshal(m, b)
+}
+func (m *RevokeInvitationResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_RevokeInvitationResponse.Marshal(b, m, deterministic)
+}
+func (m *RevokeInvitationResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RevokeInvitationResponse.Merge(m, src)
+}
+func (m *RevokeInvitationResponse) XXX_Size() int {
+	return xxx_messageInfo_RevokeInvitationResponse.Size(m)
+}
+func (m *RevokeInvitationResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_RevokeInvitationResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_RevokeInvitationResponse proto.InternalMessageInfo
+
+func init() {
+	proto.RegisterType((*AuditEvent)(nil), "AuditEvent")
+	proto.RegisterType((*ListAuditEventsRequest)(nil), "ListAuditEventsRequest")
+	proto.RegisterType((*ListAuditEventsResponse)(nil), "ListAuditEventsResponse")
+	proto.RegisterType((*Login)(nil), "Login")
+	proto.RegisterType((*GetLoginHistoryRequest)(nil), "GetLoginHistoryRequest")
+	proto.RegisterType((*GetLoginHistoryResponse)(nil), "GetLoginHistoryResponse")
+	proto.RegisterType((*ReportUnrecognizedLoginRequest)(nil), "ReportUnrecognizedLoginRequest")
+	proto.RegisterType((*ReportUnrecognizedLoginResponse)(nil), "ReportUnrecognizedLoginResponse")
+	proto.RegisterType((*ChangePasswordRequest)(nil), "ChangePasswordRequest")
+	proto.RegisterType((*ChangePasswordResponse)(nil), "ChangePasswordResponse")
+	proto.RegisterType((*ReserveStorageRequest)(nil), "ReserveStorageRequest")
+	proto.RegisterType((*ReserveStorageResponse)(nil), "ReserveStorageResponse")
+	proto.RegisterType((*CommitStorageRequest)(nil), "CommitStorageRequest")
+	proto.RegisterType((*ReleaseStorageRequest)(nil), "ReleaseStorageRequest")
+	proto.RegisterType((*SetStorageQuotaRequest)(nil), "SetStorageQuotaRequest")
+	proto.RegisterType((*StorageResponse)(nil), "StorageResponse")
+	proto.RegisterType((*Plan)(nil), "Plan")
+	proto.RegisterType((*ListPlansRequest)(nil), "ListPlansRequest")
+	proto.RegisterType((*ListPlansResponse)(nil), "ListPlansResponse")
+	proto.RegisterType((*SetMemberPlanRequest)(nil), "SetMemberPlanRequest")
+	proto.RegisterType((*SetMemberPlanResponse)(nil), "SetMemberPlanResponse")
+	proto.RegisterType((*GetMemberLimitsRequest)(nil), "GetMemberLimitsRequest")
+	proto.RegisterType((*MemberLimits)(nil), "MemberLimits")
+	proto.RegisterType((*Invitation)(nil), "Invitation")
+	proto.RegisterType((*CreateInvitationRequest)(nil), "CreateInvitationRequest")
+	proto.RegisterType((*CreateInvitationResponse)(nil), "CreateInvitationResponse")
+	proto.RegisterType((*ListInvitationsRequest)(nil), "ListInvitationsRequest")
+	proto.RegisterType((*ListInvitationsResponse)(nil), "ListInvitationsResponse")
+	proto.RegisterType((*RevokeInvitationRequest)(nil), "RevokeInvitationRequest")
+	proto.RegisterType((*RevokeInvitationResponse)(nil), "RevokeInvitationResponse")
+}
+
+func init() {
+	proto.RegisterFile("Members.extended.proto", fileDescriptor_8b6ce4408de419a3)
+}
+
+var fileDescriptor_8b6ce4408de419a3 = []byte{
+	// 1328 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xac, 0x58, 0xcb, 0x6f, 0xdb, 0xc6,
+	0x13, 0x06, 0xa9, 0x97, 0x35, 0x8e, 0x1f, 0x59, 0xd8, 0x22, 0xc3, 0x5f, 0xe0, 0xf8, 0xb7, 0x2d,
+	0x0a, 0xa3, 0x45, 0x17, 0x45, 0x5a, 0x14, 0x4d, 0xd1, 0x16, 0x50, 0x2c, 0x27, 0x51, 0x1a, 0x1b,
+	0x2a, 0x1d, 0xe7, 0xd2, 0x5c, 0x68, 0x6b, 0xea, 0x10, 0x91, 0x48, 0x97, 0xbb, 0x92, 0xe5, 0xdc,
+	0x9a, 0x7b, 0xff, 0xb3, 0xfc, 0x2b, 0x05, 0x7a, 0xef, 0xa5, 0xe0, 0xee, 0xf2, 0x4d, 0xd9, 0x6e,
+	0xd1, 0x93, 0x39, 0xdf, 0x3e, 0xe7, 0x9b, 0x9d, 0x99, 0x4f, 0x86, 0xde, 0x21, 0x4e, 0x4f, 0x31,
+	0xe2, 0x0c, 0x17, 0x02, 0x83, 0x31, 0x8e, 0xd9, 0x45, 0x14, 0x8a, 0x90, 0xbe, 0x37, 0x01, 0xfa,
+	0xb3, 0xb1, 0x2f, 0x0e, 0xe6, 0x18, 0x08, 0xb2, 0x0e, 0xe6, 0x70, 0x60, 0x1b, 0xbb, 0xc6, 0x5e,
+	0xc3, 0x35, 0x87, 0x03, 0x42, 0xa0, 0xf9, 0xf2, 0xea, 0x02, 0x6d, 0x73, 0xd7, 0xd8, 0xeb, 0xba,
+	0xf2, 0x9b, 0xd8, 0xd0, 0xe9, 0x9f, 0x89, 0x30, 0x1a, 0x0e, 0xec, 0x86, 0x84, 0x13, 0x93, 0x38,
+	0xb0, 0xf2, 0xd2, 0x8b, 0xce, 0x51, 0x0c, 0x07, 0x76, 0x53, 0x0e, 0xa5, 0x36, 0xe9, 0x41, 0xdb,
+	0x45, 0x8f, 0x87, 0x81, 0xdd, 0x92, 0x23, 0xda, 0x92, 0x27, 0x8e, 0xec, 0xb6, 0xc4, 0xcc, 0xe1,
+	0x88, 0xdc, 0x87, 0xee, 0x09, 0xc7, 0xa8, 0x7f, 0x8e, 0x81, 0xb0, 0x3b, 0x12, 0xce, 0x80, 0x78,
+	0x74, 0x3f, 0x42, 0x4f, 0xe0, 0xb8, 0x2f, 0xec, 0x15, 0x79, 0xcd, 0x0c, 0x20, 0x14, 0xee, 0x8c,
+	0x22, 0x9c, 0xfb, 0xe1, 0x8c, 0x3f, 0xf3, 0xf8, 0x1b, 0xbb, 0x2b, 0x97, 0x17, 0xb0, 0xd8, 0x23,
+	0x39, 0x06, 0xca, 0xa3, 0xf8, 0x9b, 0xbe, 0x83, 0xde, 0x0b, 0x9f, 0x8b, 0x8c, 0x07, 0xee, 0xe2,
+	0xaf, 0x33, 0xe4, 0x22, 0xf6, 0x48, 0x11, 0xa7, 0x59, 0xe9, 0xba, 0xa9, 0x5d, 0xcb, 0x8d, 0x03,
+	0x2b, 0x8f, 0xf1, 0x97, 0x30, 0x42, 0x4d, 0x4e, 0xc3, 0x4d, 0x6d, 0xb2, 0x05, 0xad, 0x17, 0xfe,
+	0xd4, 0x17, 0x92, 0x9a, 0x96, 0xab, 0x0c, 0x7a, 0x0a, 0x56, 0xe5, 0x6c, 0x7e, 0x11, 0x06, 0x1c,
+	0xc9, 0x47, 0xd0, 0x56, 0x88, 0x6d, 0xec, 0x36, 0xf6, 0x56, 0x1f, 0xae, 0xb2, 0x6c, 0x96, 0xab,
+	0x87, 0x62, 0x9f, 0x8f, 0x70, 0x21, 0xd2, 0x53, 0x4d, 0x79, 0x6a, 0x01, 0xa3, 0xbf, 0x19, 0xd0,
+	0x7a, 0x11, 0x9e, 0xfb, 0x41, 0x25, 0xbe, 0x8a, 0x7d, 0xb3, 0x9e, 0xfd, 0x46, 0x0d, 0xfb, 0x47,
+	0x78, 0x39, 0xc0, 0xb9, 0x7f, 0x86, 0xd2, 0x8b, 0x15, 0x37, 0x03, 0x8a, 0xb1, 0x69, 0x95, 0x62,
+	0x43, 0x5f, 0x41, 0xef, 0x29, 0x0a, 0x79, 0x8b, 0x67, 0x3e, 0x17, 0x61, 0x74, 0x95, 0x70, 0x9c,
+	0xf2, 0x62, 0xe6, 0x78, 0x21, 0xbb, 0xb0, 0xda, 0x3f, 0x3b, 0x43, 0xce, 0x5f, 0x86, 0x6f, 0x31,
+	0xd0, 0x77, 0xc9, 0x43, 0xcf, 0x9b, 0x2b, 0xc6, 0xa6, 0x49, 0x1f, 0x81, 0x55, 0xd9, 0x57, 0xf3,
+	0xb7, 0x03, 0x6d, 0x89, 0x27, 0xfc, 0xb5, 0x99, 0x34, 0x5d, 0x8d, 0xd2, 0xaf, 0x61, 0xc7, 0xc5,
+	0x8b, 0x30, 0x12, 0x27, 0x41, 0x84, 0x67, 0xe1, 0x79, 0xe0, 0xbf, 0xc3, 0xb1, 0x9a, 0x92, 0x5d,
+	0x4d, 0x1d, 0xaf, 0x62, 0xaf, 0x0c, 0xfa, 0x7f, 0x78, 0xb0, 0x74, 0x9d, 0x3a, 0x9a, 0xfe, 0x61,
+	0xc0, 0xf6, 0xfe, 0x1b, 0x2f, 0x38, 0xc7, 0x91, 0xc7, 0xf9, 0x65, 0x18, 0x8d, 0x73, 0x5b, 0x1e,
+	0x4c, 0x3d, 0x7f, 0x92, 0x6c, 0x29, 0x8d, 0xf8, 0xdd, 0x24, 0x13, 0x75, 0x34, 0x52, 0x3b, 0x66,
+	0xe2, 0x08, 0x2f, 0xd3, 0x61, 0xcd, 0x44, 0x0e, 0x22, 0x3b, 0x00, 0xa3, 0xc8, 0x9f, 0x7b, 0x02,
+	0x7f, 0xc4, 0x2b, 0x9d, 0x79, 0x39, 0x44, 0xe5, 0x45, 0x62, 0x0d, 0x5f, 0xe9, 0x0c, 0x2c, 0x60,
+	0xe4, 0x13, 0x58, 0xcf, 0xec, 0x63, 0x6f, 0x22, 0x74, 0x4e, 0x96, 0xd0, 0x8c, 0x92, 0x4e, 0x9e,
+	0x92, 0xf7, 0x06, 0xf4, 0xca, 0xfe, 0xea, 0x28, 0x5c, 0x97, 0x42, 0xa5, 0x20, 0x9b, 0x95, 0x20,
+	0x93, 0x4f, 0x61, 0x53, 0x99, 0x07, 0x8b, 0x0b, 0x3f, 0xf2, 0x84, 0x1f, 0x06, 0x3a, 0xb1, 0x2a,
+	0x38, 0x1d, 0xc2, 0xb6, 0x8b, 0x1c, 0xa3, 0x39, 0x1e, 0x8b, 0x30, 0xf2, 0xce, 0xf1, 0x36, 0x59,
+	0xbc, 0x05, 0xad, 0xc7, 0x57, 0x02, 0xb9, 0x4e, 0x1c, 0x65, 0xd0, 0xd7, 0xd0, 0x2b, 0x6f, 0xa5,
+	0xdd, 0xf9, 0x18, 0xd6, 0xd4, 0x88, 0x3c, 0x33, 0xdd, 0xb0, 0x08, 0xc6, 0xb9, 0x20, 0x2f, 0x86,
+	0xbc, 0x2f, 0xf4, 0xce, 0x19, 0x40, 0xe7, 0xb0, 0xb5, 0x1f, 0x4e, 0xa7, 0xbe, 0xf8, 0x07, 0xf7,
+	0xac, 0x9c, 0x6b, 0x2e, 0x39, 0xf7, 0xc9, 0x6c, 0x32, 0x51, 0x1e, 0x29, 0x9e, 0x32, 0x80, 0xfe,
+	0x6e, 0xc4, 0x0c, 0x4d, 0xd0, 0xe3, 0xf8, 0x9f, 0x9f, 0x9c, 0xf2, 0xd8, 0xc8, 0xf1, 0x58, 0xbc,
+	0x4f, 0xb3, 0x7c, 0x9f, 0xe7, 0xd0, 0x3b, 0xc6, 0x84, 0x84, 0x9f, 0x66, 0xa1, 0xf0, 0x6e, 0x19,
+	0x31, 0x39, 0x37, 0x89, 0x98, 0x34, 0xe8, 0x5d, 0xd8, 0x28, 0x85, 0x8a, 0x7e, 0x30, 0xa0, 0x39,
+	0x9a, 0x78, 0xf9, 0xaa, 0xd7, 0x4d, 0xba, 0xda, 0x91, 0x37, 0x4d, 0x2b, 0x77, 0xfc, 0x1d, 0xe7,
+	0x48, 0xfe, 0x22, 0xda, 0x8d, 0x02, 0x16, 0x3f, 0xd7, 0x43, 0x6f, 0x31, 0xf2, 0xcf, 0xc4, 0x2c,
+	0x4a, 0xfd, 0xc9, 0x43, 0xb1, 0xbf, 0x87, 0xde, 0xa2, 0x3f, 0x39, 0x9d, 0x4d, 0x79, 0x52, 0x03,
+	0x53, 0x40, 0xaf, 0x3f, 0x46, 0xce, 0xfd, 0x30, 0xe0, 0x76, 0x3b, 0x5d, 0x9f, 0x40, 0xb1, 0xdf,
+	0x4f, 0xd0, 0x53, 0xdb, 0x77, 0x76, 0x1b, 0xb1, 0xdf, 0x89, 0x4d, 0x09, 0x6c, 0xc6, 0x9d, 0x22,
+	0xf6, 0x28, 0xe9, 0x4f, 0xf4, 0x0b, 0xb8, 0x9b, 0xc3, 0xf4, 0x13, 0xfd, 0x1f, 0xb4, 0x24, 0xa0,
+	0xcb, 0x5e, 0x8b, 0xc5, 0x96, 0xab, 0x30, 0xfa, 0x1c, 0xb6, 0x8e, 0x51, 0x28, 0x32, 0x25, 0x7e,
+	0x0b, 0xc6, 0x7b, 0xd0, 0x8e, 0xa7, 0xa6, 0xa1, 0xd7, 0x16, 0xb5, 0x60, 0xbb, 0xb4, 0x97, 0x66,
+	0xfe, 0x2b, 0x59, 0xec, 0xd5, 0x80, 0x2c, 0xe7, 0xb7, 0x69, 0xa8, 0xf4, 0x83, 0x09, 0x77, 0xf2,
+	0x6b, 0xfe, 0xcd, 0x9d, 0x64, 0x25, 0x9d, 0x78, 0x81, 0x8c, 0x6f, 0x43, 0x57, 0x52, 0x6d, 0x57,
+	0x62, 0xdc, 0xac, 0x8f, 0xf1, 0x09, 0xc7, 0xb1, 0xc6, 0x64, 0x0c, 0x0d, 0x37, 0x0f, 0x91, 0x3d,
+	0xd8, 0xd0, 0xb5, 0x21, 0x9d, 0xa5, 0x22, 0x59, 0x86, 0xcb, 0xef, 0xa5, 0x73, 0xc3, 0x7b, 0x59,
+	0xb9, 0xe1, 0xbd, 0x74, 0xaf, 0x7f, 0x2f, 0x50, 0x7a, 0x2f, 0x7f, 0x1a, 0x00, 0xc3, 0x60, 0xee,
+	0x0b, 0x99, 0xa2, 0x95, 0x24, 0x48, 0x1b, 0x91, 0x99, 0x6f, 0x44, 0x19, 0xad, 0x8d, 0x02, 0xad,
+	0x36, 0x74, 0x0e, 0xbd, 0xc5, 0x09, 0x4f, 0x9f, 0x7d, 0x62, 0xc6, 0xc9, 0x24, 0x61, 0xf5, 0xda,
+	0xe5, 0x77, 0xb1, 0xfc, 0xb5, 0x4b, 0xe5, 0x2f, 0x27, 0x14, 0x1e, 0x5f, 0x25, 0x12, 0x2f, 0x05,
+	0x6e, 0x90, 0x78, 0xf7, 0xa1, 0xeb, 0xe2, 0x3c, 0x7c, 0x2b, 0x47, 0x15, 0x21, 0x19, 0x10, 0x0b,
+	0x1d, 0x4b, 0xcd, 0xcd, 0x1c, 0xbf, 0xbe, 0xf1, 0x2e, 0x7b, 0x46, 0x39, 0x7f, 0x1b, 0x45, 0x7f,
+	0x77, 0x00, 0x72, 0xbd, 0x48, 0x91, 0x91, 0x43, 0xe8, 0xcf, 0x60, 0x57, 0xaf, 0xa0, 0x33, 0x93,
+	0x40, 0x73, 0x3f, 0x1c, 0xa3, 0xbe, 0x82, 0xfc, 0x26, 0x9f, 0xe5, 0xa3, 0x24, 0x6f, 0x11, 0x2b,
+	0xbd, 0xdc, 0xe2, 0xdc, 0x30, 0x65, 0x4a, 0xa9, 0x66, 0x08, 0xaf, 0xa8, 0x28, 0x23, 0xaf, 0x2e,
+	0x9f, 0x81, 0x55, 0x99, 0xaf, 0xef, 0xf2, 0x39, 0xac, 0xe6, 0xe0, 0x54, 0x62, 0xe6, 0x0e, 0xce,
+	0x8f, 0xd3, 0xef, 0xc1, 0x52, 0x3c, 0x57, 0x99, 0xa5, 0x70, 0x27, 0x03, 0xd3, 0x37, 0x56, 0xc0,
+	0xa8, 0x03, 0x76, 0x75, 0xb9, 0xba, 0xc9, 0xc3, 0xbf, 0x3a, 0xe9, 0xcf, 0x93, 0x03, 0xfd, 0xeb,
+	0xe4, 0x18, 0x23, 0xa9, 0x29, 0x07, 0xb0, 0x51, 0x52, 0xc7, 0xc4, 0x62, 0xf5, 0x5a, 0xdd, 0xb1,
+	0xd9, 0x32, 0x21, 0x3d, 0x80, 0x8d, 0x92, 0x46, 0x24, 0x16, 0xab, 0x57, 0xa3, 0x8e, 0xcd, 0x96,
+	0xc9, 0xc9, 0xd7, 0x60, 0x2d, 0x91, 0x7d, 0xe4, 0x01, 0xbb, 0x5e, 0x48, 0x3a, 0xbb, 0xec, 0x06,
+	0xc5, 0x48, 0xfa, 0xb0, 0x5e, 0x14, 0x50, 0xa4, 0xc7, 0x6a, 0x15, 0xa4, 0x63, 0xb1, 0x25, 0x4a,
+	0xab, 0x0f, 0xeb, 0x45, 0xd1, 0x42, 0x7a, 0xac, 0x56, 0x10, 0x39, 0x16, 0x5b, 0xa2, 0x6e, 0xbe,
+	0x81, 0xb5, 0x82, 0x32, 0x21, 0xdb, 0xac, 0x4e, 0xa9, 0x38, 0x9b, 0xac, 0xbc, 0xf2, 0x5b, 0x58,
+	0x2f, 0x4a, 0x0b, 0x79, 0x78, 0x8d, 0xd6, 0xa8, 0x59, 0xfb, 0x1d, 0x6c, 0x94, 0x74, 0x00, 0xb1,
+	0x58, 0xbd, 0x32, 0xa8, 0x59, 0xed, 0x00, 0xa8, 0x84, 0x93, 0xbd, 0x5e, 0x75, 0x3b, 0x47, 0xfd,
+	0x89, 0xc7, 0x4e, 0x2e, 0xc6, 0xf5, 0x63, 0x0f, 0xa1, 0x9b, 0xf6, 0x4e, 0x72, 0x97, 0x95, 0x7b,
+	0xab, 0x43, 0x58, 0xb5, 0xb5, 0xfe, 0x00, 0x6b, 0x85, 0x8e, 0x47, 0xb6, 0x59, 0x5d, 0x37, 0x75,
+	0x7a, 0xac, 0xb6, 0x31, 0x92, 0x47, 0xf2, 0x25, 0x16, 0x9a, 0x9c, 0xc5, 0x4a, 0x48, 0xb2, 0xc7,
+	0x1a, 0x2b, 0xcc, 0x7b, 0x0a, 0x9b, 0xe5, 0xba, 0x42, 0x6c, 0xb6, 0xa4, 0xda, 0x39, 0xf7, 0xd8,
+	0xd2, 0x22, 0xa4, 0x73, 0x2a, 0x1b, 0x49, 0x72, 0xaa, 0x5a, 0x55, 0x1c, 0xbb, 0x3a, 0xa0, 0x77,
+	0x79, 0x0a, 0x9b, 0xe5, 0x84, 0x26, 0x36, 0x5b, 0x52, 0x22, 0x9c, 0x7b, 0x6c, 0x59, 0xf6, 0x9f,
+	0xb6, 0xe5, 0x3f, 0x22, 0xbe, 0xfc, 0x7b, 0x00, 0x43, 0x69, 0xf2, 0xfa, 0xa2, 0x10, 0x00, 0x00,
+}
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ context.Context
+var _ grpc.ClientConnInterface
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+const _ = grpc.SupportPackageIsVersion6
+
+// MembersExtendedServiceClient is the client API for MembersExtendedService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
+type MembersExtendedServiceClient interface {
+	// Restricted to the admin caller
+	ListAuditEvents(ctx context.Context, in *ListAuditEventsRequest, opts ...grpc.CallOption) (*ListAuditEventsResponse, error)
+	GetLoginHistory(ctx context.Context, in *GetLoginHistoryRequest, opts ...grpc.CallOption) (*GetLoginHistoryResponse, error)
+	ReportUnrecognizedLogin(ctx context.Context, in *ReportUnrecognizedLoginRequest, opts ...grpc.CallOption) (*ReportUnrecognizedLoginResponse, error)
+	// The only way out of PasswordChangeRequired, with the token of the
+	// reported alert. The private key is encrypted with the new password.
+	ChangePassword(ctx context.Context, in *ChangePasswordRequest, opts ...grpc.CallOption) (*ChangePasswordResponse, error)
+	// Restricted to the pictures caller
+	ReserveStorage(ctx context.Context, in *ReserveStorageRequest, opts ...grpc.CallOption) (*ReserveStorageResponse, error)
+	CommitStorage(ctx context.Context, in *CommitStorageRequest, opts ...grpc.CallOption) (*StorageResponse, error)
+	ReleaseStorage(ctx context.Context, in *ReleaseStorageRequest, opts ...grpc.CallOption) (*StorageResponse, error)
+	// Restricted to the admin caller
+	SetStorageQuota(ctx context.Context, in *SetStorageQuotaRequest, opts ...grpc.CallOption) (*StorageResponse, error)
+	// Restricted to the admin caller
+	CreatePlan(ctx context.Context, in *Plan, opts ...grpc.CallOption) (*Plan, error)
+	UpdatePlan(ctx context.Context, in *Plan, opts ...grpc.CallOption) (*Plan, error)
+	ListPlans(ctx context.Context, in *ListPlansRequest, opts ...grpc.CallOption) (*ListPlansResponse, error)
+	SetMemberPlan(ctx context.Context, in *SetMemberPlanRequest, opts ...grpc.CallOption) (*SetMemberPlanResponse, error)
+	// Restricted to the proxy and the pictures callers
+	GetMemberLimits(ctx context.Context, in *GetMemberLimitsRequest, opts ...grpc.CallOption) (*MemberLimits, error)
+	// Restricted to the admin caller
+	CreateInvitation(ctx context.Context, in *CreateInvitationRequest, opts ...grpc.CallOption) (*CreateInvitationResponse, error)
+	ListInvitations(ctx context.Context, in *ListInvitationsRequest, opts ...grpc.CallOption) (*ListInvitationsResponse, error)
+	RevokeInvitation(ctx context.Context, in *RevokeInvitationRequest, opts ...grpc.CallOption) (*RevokeInvitationResponse, error)
+}
+
+type membersExtendedServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewMembersExtendedServiceClient(cc grpc.ClientConnInterface) MembersExtendedServiceClient {
+	return &membersExtendedServiceClient{cc}
+}
+
+func (c *membersExtendedServiceClient) ListAuditEvents(ctx context.Context, in *ListAuditEventsRequest, opts ...grpc.CallOption) (*ListAuditEventsResponse, error) {
+	out := new(ListAuditEventsResponse)
+	err := c.cc.Invoke(ctx, "/MembersExtendedService/ListAuditEvents", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *membersExtendedServiceClient) GetLoginHistory(ctx context.Context, in *GetLoginHistoryRequest, opts ...grpc.CallOption) (*GetLoginHistoryResponse, error) {
+	out := new(GetLoginHistoryResponse)
+	err := c.cc.Invoke(ctx, "/MembersExtendedService/GetLoginHistory", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *membersExtendedServiceClient) ReportUnrecognizedLogin(ctx context.Context, in *ReportUnrecognizedLoginRequest, opts ...grpc.CallOption) (*ReportUnrecognizedLoginResponse, error) {
+	out := new(ReportUnrecognizedLoginResponse)
+	err := c.cc.Invoke(ctx, "/MembersExtendedService/ReportUnrecognizedLogin", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *membersExtendedServiceClient) ChangePassword(ctx context.Context, in *ChangePasswordRequest, opts ...grpc.CallOption) (*ChangePasswordResponse, error) {
+	out := new(ChangePasswordResponse)
+	err := c.cc.Invoke(ctx, "/MembersExtendedService/ChangePassword", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *membersExtendedServiceClient) ReserveStorage(ctx context.Context, in *ReserveStorageRequest, opts ...grpc.CallOption) (*ReserveStorageResponse, error) {
+	out := new(ReserveStorageResponse)
+	err := c.cc.Invoke(ctx, "/MembersExtendedService/ReserveStorage", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *membersExtendedServiceClient) CommitStorage(ctx context.Context, in *CommitStorageRequest, opts ...grpc.CallOption) (*StorageResponse, error) {
+	out := new(StorageResponse)
+	err := c.cc.Invoke(ctx, "/MembersExtendedService/CommitStorage", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *membersExtendedServiceClient) ReleaseStorage(ctx context.Context, in *ReleaseStorageRequest, opts ...grpc.CallOption) (*StorageResponse, error) {
+	out := new(StorageResponse)
+	err := c.cc.Invoke(ctx, "/MembersExtendedService/ReleaseStorage", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *membersExtendedServiceClient) SetStorageQuota(ctx context.Context, in *SetStorageQuotaRequest, opts ...grpc.CallOption) (*StorageResponse, error) {
+	out := new(StorageResponse)
+	err := c.cc.Invoke(ctx, "/MembersExtendedService/SetStorageQuota", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *membersExtendedServiceClient) CreatePlan(ctx context.Context, in *Plan, opts ...grpc.CallOption) (*Plan, error) {
+	out := new(Plan)
+	err := c.cc.Invoke(ctx, "/MembersExtendedService/CreatePlan", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *membersExtendedServiceClient) UpdatePlan(ctx context.Context, in *Plan, opts ...grpc.CallOption) (*Plan, error) {
+	out := new(Plan)
+	err := c.cc.Invoke(ctx, "/MembersExtendedService/UpdatePlan", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *membersExtendedServiceClient) ListPlans(ctx context.Context, in *ListPlansRequest, opts ...grpc.CallOption) (*ListPlansResponse, error) {
+	out := new(ListPlansResponse)
+	err := c.cc.Invoke(ctx, "/MembersExtendedService/ListPlans", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *membersExtendedServiceClient) SetMemberPlan(ctx context.Context, in *SetMemberPlanRequest, opts ...grpc.CallOption) (*SetMemberPlanResponse, error) {
+	out := new(SetMemberPlanResponse)
+	err := c.cc.Invoke(ctx, "/MembersExtendedService/SetMemberPlan", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *membersExtendedServiceClient) GetMemberLimits(ctx context.Context, in *GetMemberLimitsRequest, opts ...grpc.CallOption) (*MemberLimits, error) {
+	out := new(MemberLimits)
+	err := c.cc.Invoke(ctx, "/MembersExtendedService/GetMemberLimits", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *membersExtendedServiceClient) CreateInvitation(ctx context.Context, in *CreateInvitationRequest, opts ...grpc.CallOption) (*CreateInvitationResponse, error) {
+	out := new(CreateInvitationResponse)
+	err := c.cc.Invoke(ctx, "/MembersExtendedService/CreateInvitation", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *membersExtendedServiceClient) ListInvitations(ctx context.Context, in *ListInvitationsRequest, opts ...grpc.CallOption) (*ListInvitationsResponse, error) {
+	out := new(ListInvitationsResponse)
+	err := c.cc.Invoke(ctx, "/MembersExtendedService/ListInvitations", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *membersExtendedServiceClient) RevokeInvitation(ctx context.Context, in *RevokeInvitationRequest, opts ...grpc.CallOption) (*RevokeInvitationResponse, error) {
+	out := new(RevokeInvitationResponse)
+	err := c.cc.Invoke(ctx, "/MembersExtendedService/RevokeInvitation", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// MembersExtendedServiceServer is the server API for MembersExtendedService service.
+type MembersExtendedServiceServer interface {
+	// Restricted to the admin caller
+	ListAuditEvents(context.Context, *ListAuditEventsRequest) (*ListAuditEventsResponse, error)
+	GetLoginHistory(context.Context, *GetLoginHistoryRequest) (*GetLoginHistoryResponse, error)
+	ReportUnrecognizedLogin(context.Context, *ReportUnrecognizedLoginRequest) (*ReportUnrecognizedLoginResponse, error)
+	// The only way out of PasswordChangeRequired, with the token of the
+	// reported alert. The private key is encrypted with the new password.
+	ChangePassword(context.Context, *ChangePasswordRequest) (*ChangePasswordResponse, error)
+	// Restricted to the pictures caller
+	ReserveStorage(context.Context, *ReserveStorageRequest) (*ReserveStorageResponse, error)
+	CommitStorage(context.Context, *CommitStorageRequest) (*StorageResponse, error)
+	ReleaseStorage(context.Context, *ReleaseStorageRequest) (*StorageResponse, error)
+	// Restricted to the admin caller
+	SetStorageQuota(context.Context, *SetStorageQuotaRequest) (*StorageResponse, error)
+	// Restricted to the admin caller
+	CreatePlan(context.Context, *Plan) (*Plan, error)
+	UpdatePlan(context.Context, *Plan) (*Plan, error)
+	ListPlans(context.Context, *ListPlansRequest) (*ListPlansResponse, error)
+	SetMemberPlan(context.Context, *SetMemberPlanRequest) (*SetMemberPlanResponse, error)
+	// Restricted to the proxy and the pictures callers
+	GetMemberLimits(context.Context, *GetMemberLimitsRequest) (*MemberLimits, error)
+	// Restricted to the admin caller
+	CreateInvitation(context.Context, *CreateInvitationRequest) (*CreateInvitationResponse, error)
+	ListInvitations(context.Context, *ListInvitationsRequest) (*ListInvitationsResponse, error)
+	RevokeInvitation(context.Context, *RevokeInvitationRequest) (*RevokeInvitationResponse, error)
+}
+
+// UnimplementedMembersExtendedServiceServer can be embedded to have forward compatible implementations.
+type UnimplementedMembersExtendedServiceServer struct {
+}
+
+func (*UnimplementedMembersExtendedServiceServer) ListAuditEvents(ctx context.Context, req *ListAuditEventsRequest) (*ListAuditEventsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListAuditEvents not implemented")
+}
+func (*UnimplementedMembersExtendedServiceServer) GetLoginHistory(ctx context.Context, req *GetLoginHistoryRequest) (*GetLoginHistoryResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetLoginHistory not implemented")
+}
+func (*UnimplementedMembersExtendedServiceServer) ReportUnrecognizedLogin(ctx context.Context, req *ReportUnrecognizedLoginRequest) (*ReportUnrecognizedLoginResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReportUnrecognizedLogin not implemented")
+}
+func (*UnimplementedMembersExtendedServiceServer) ChangePassword(ctx context.Context, req *ChangePasswordRequest) (*ChangePasswordResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ChangePassword not implemented")
+}
+func (*UnimplementedMembersExtendedServiceServer) ReserveStorage(ctx context.Context, req *ReserveStorageRequest) (*ReserveStorageResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReserveStorage not implemented")
+}
+func (*UnimplementedMembersExtendedServiceServer) CommitStorage(ctx context.Context, req *CommitStorageRequest) (*StorageResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CommitStorage not implemented")
+}
+func (*UnimplementedMembersExtendedServiceServer) ReleaseStorage(ctx context.Context, req *ReleaseStorageRequest) (*StorageResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReleaseStorage not implemented")
+}
+func (*UnimplementedMembersExtendedServiceServer) SetStorageQuota(ctx context.Context, req *SetStorageQuotaRequest) (*StorageResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetStorageQuota not implemented")
+}
+func (*UnimplementedMembersExtendedServiceServer) CreatePlan(ctx context.Context, req *Plan) (*Plan, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreatePlan not implemented")
+}
+func (*UnimplementedMembersExtendedServiceServer) UpdatePlan(ctx context.Context, req *Plan) (*Plan, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdatePlan not implemented")
+}
+func (*UnimplementedMembersExtendedServiceServer) ListPlans(ctx context.Context, req *ListPlansRequest) (*ListPlansResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListPlans not implemented")
+}
+func (*UnimplementedMembersExtendedServiceServer) SetMemberPlan(ctx context.Context, req *SetMemberPlanRequest) (*SetMemberPlanResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetMemberPlan not implemented")
+}
+func (*UnimplementedMembersExtendedServiceServer) GetMemberLimits(ctx context.Context, req *GetMemberLimitsRequest) (*MemberLimits, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetMemberLimits not implemented")
+}
+func (*UnimplementedMembersExtendedServiceServer) CreateInvitation(ctx context.Context, req *CreateInvitationRequest) (*CreateInvitationResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateInvitation not implemented")
+}
+func (*UnimplementedMembersExtendedServiceServer) ListInvitations(ctx context.Context, req *ListInvitationsRequest) (*ListInvitationsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListInvitations not implemented")
+}
+func (*UnimplementedMembersExtendedServiceServer) RevokeInvitation(ctx context.Context, req *RevokeInvitationRequest) (*RevokeInvitationResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RevokeInvitation not implemented")
+}
+
+func RegisterMembersExtendedServiceServer(s *grpc.Server, srv MembersExtendedServiceServer) {
+	s.RegisterService(&_MembersExtendedService_serviceDesc, srv)
+}
+
+func _MembersExtendedService_ListAuditEvents_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListAuditEventsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MembersExtendedServiceServer).ListAuditEvents(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/MembersExtendedService/ListAuditEvents",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MembersExtendedServiceServer).ListAuditEvents(ctx, req.(*ListAuditEventsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MembersExtendedService_GetLoginHistory_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetLoginHistoryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MembersExtendedServiceServer).GetLoginHistory(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/MembersExtendedService/GetLoginHistory",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MembersExtendedServiceServer).GetLoginHistory(ctx, req.(*GetLoginHistoryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MembersExtendedService_ReportUnrecognizedLogin_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReportUnrecognizedLoginRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MembersExtendedServiceServer).ReportUnrecognizedLogin(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/MembersExtendedService/ReportUnrecognizedLogin",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MembersExtendedServiceServer).ReportUnrecognizedLogin(ctx, req.(*ReportUnrecognizedLoginRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MembersExtendedService_ChangePassword_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ChangePasswordRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MembersExtendedServiceServer).ChangePassword(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/MembersExtendedService/ChangePassword",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MembersExtendedServiceServer).ChangePassword(ctx, req.(*ChangePasswordRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MembersExtendedService_ReserveStorage_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReserveStorageRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MembersExtendedServiceServer).ReserveStorage(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/MembersExtendedService/ReserveStorage",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MembersExtendedServiceServer).ReserveStorage(ctx, req.(*ReserveStorageRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MembersExtendedService_CommitStorage_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CommitStorageRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MembersExtendedServiceServer).CommitStorage(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/MembersExtendedService/CommitStorage",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MembersExtendedServiceServer).CommitStorage(ctx, req.(*CommitStorageRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MembersExtendedService_ReleaseStorage_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReleaseStorageRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MembersExtendedServiceServer).ReleaseStorage(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/MembersExtendedService/ReleaseStorage",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MembersExtendedServiceServer).ReleaseStorage(ctx, req.(*ReleaseStorageRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MembersExtendedService_SetStorageQuota_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetStorageQuotaRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MembersExtendedServiceServer).SetStorageQuota(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/MembersExtendedService/SetStorageQuota",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MembersExtendedServiceServer).SetStorageQuota(ctx, req.(*SetStorageQuotaRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MembersExtendedService_CreatePlan_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Plan)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MembersExtendedServiceServer).CreatePlan(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/MembersExtendedService/CreatePlan",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MembersExtendedServiceServer).CreatePlan(ctx, req.(*Plan))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MembersExtendedService_UpdatePlan_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Plan)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MembersExtendedServiceServer).UpdatePlan(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/MembersExtendedService/UpdatePlan",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MembersExtendedServiceServer).UpdatePlan(ctx, req.(*Plan))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MembersExtendedService_ListPlans_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListPlansRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MembersExtendedServiceServer).ListPlans(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/MembersExtendedService/ListPlans",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MembersExtendedServiceServer).ListPlans(ctx, req.(*ListPlansRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MembersExtendedService_SetMemberPlan_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetMemberPlanRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MembersExtendedServiceServer).SetMemberPlan(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/MembersExtendedService/SetMemberPlan",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MembersExtendedServiceServer).SetMemberPlan(ctx, req.(*SetMemberPlanRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MembersExtendedService_GetMemberLimits_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetMemberLimitsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MembersExtendedServiceServer).GetMemberLimits(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/MembersExtendedService/GetMemberLimits",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MembersExtendedServiceServer).GetMemberLimits(ctx, req.(*GetMemberLimitsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MembersExtendedService_CreateInvitation_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateInvitationRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MembersExtendedServiceServer).CreateInvitation(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/MembersExtendedService/CreateInvitation",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MembersExtendedServiceServer).CreateInvitation(ctx, req.(*CreateInvitationRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MembersExtendedService_ListInvitations_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListInvitationsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MembersExtendedServiceServer).ListInvitations(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/MembersExtendedService/ListInvitations",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MembersExtendedServiceServer).ListInvitations(ctx, req.(*ListInvitationsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MembersExtendedService_RevokeInvitation_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RevokeInvitationRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MembersExtendedServiceServer).RevokeInvitation(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/MembersExtendedService/RevokeInvitation",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MembersExtendedServiceServer).RevokeInvitation(ctx, req.(*RevokeInvitationRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+var _MembersExtendedService_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "MembersExtendedService",
+	HandlerType: (*MembersExtendedServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ListAuditEvents",
+			Handler:    _MembersExtendedService_ListAuditEvents_Handler,
+		},
+		{
+			MethodName: "GetLoginHistory",
+			Handler:    _MembersExtendedService_GetLoginHistory_Handler,
+		},
+		{
+			MethodName: "ReportUnrecognizedLogin",
+			Handler:    _MembersExtendedService_ReportUnrecognizedLogin_Handler,
+		},
+		{
+			MethodName: "ChangePassword",
+			Handler:    _MembersExtendedService_ChangePassword_Handler,
+		},
+		{
+			MethodName: "ReserveStorage",
+			Handler:    _MembersExtendedService_ReserveStorage_Handler,
+		},
+		{
+			MethodName: "CommitStorage",
+			Handler:    _MembersExtendedService_CommitStorage_Handler,
+		},
+		{
+			MethodName: "ReleaseStorage",
+			Handler:    _MembersExtendedService_ReleaseStorage_Handler,
+		},
+		{
+			MethodName: "SetStorageQuota",
+			Handler:    _MembersExtendedService_SetStorageQuota_Handler,
+		},
+		{
+			MethodName: "CreatePlan",
+			Handler:    _MembersExtendedService_CreatePlan_Handler,
+		},
+		{
+			MethodName: "UpdatePlan",
+			Handler:    _MembersExtendedService_UpdatePlan_Handler,
+		},
+		{
+			MethodName: "ListPlans",
+			Handler:    _MembersExtendedService_ListPlans_Handler,
+		},
+		{
+			MethodName: "SetMemberPlan",
+			Handler:    _MembersExtendedService_SetMemberPlan_Handler,
+		},
+		{
+			MethodName: "GetMemberLimits",
+			Handler:    _MembersExtendedService_GetMemberLimits_Handler,
+		},
+		{
+			MethodName: "CreateInvitation",
+			Handler:    _MembersExtendedService_CreateInvitation_Handler,
+		},
+		{
+			MethodName: "ListInvitations",
+			Handler:    _MembersExtendedService_ListInvitations_Handler,
+		},
+		{
+			MethodName: "RevokeInvitation",
+			Handler:    _MembersExtendedService_RevokeInvitation_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "Members.extended.proto",
+}
@@ -5,19 +5,20 @@
 ** @Filename:				Hash.go
 **
 ** @Last modified by:		Tbouder
-** @Last modified time:		Wednesday 19 February 2020 - 15:28:08
+** @Last modified time:		Friday 08 May 2020 - 10:41:17
 *******************************************************************************/
 
 package			main
 
-import			"os"
+import			"context"
+import			"google.golang.org/grpc/codes"
 import			"crypto/aes"
 import			"crypto/cipher"
 import			"encoding/base64"
 import			"golang.org/x/crypto/argon2"
-import			"github.com/microgolang/logs"
 import			"errors"
 import			"bytes"
+import			"sync"
 
 var (
 	ErrInvalidBlockSize		= errors.New("invalid blocksize")
@@ -69,13 +70,15 @@ func	pkcs7Unpad(b []byte, blocksize int) ([]byte, error) {
 **	which will later be used to log-in the member by a comparison between
 **	it's password and the hashes
 ******************************************************************************/
-func	GeneratePasswordHash(password string) ([]byte, []byte, cipher.Block, error) {
+func	GeneratePasswordHash(ctx context.Context, password string) ([]byte, []byte, cipher.Block, error) {
+	ctx, span := startSpan(ctx, `GeneratePasswordHash`)
+	defer span.End()
+
 	/**************************************************************************
-	**	Get the master key from the .env file
+	**	Get the master key from the configuration
 	**************************************************************************/
-	MasterKey, err := base64.RawStdEncoding.DecodeString(os.Getenv("MASTER_KEY"))
+	MasterKey, err := base64.RawStdEncoding.DecodeString(config.Keys.Master)
 	if (err != nil) {
-		logs.Error(err)
 		return nil, nil, nil, err
 	}
 
@@ -84,16 +87,25 @@ func	GeneratePasswordHash(password string) ([]byte, []byte, cipher.Block, error)
 	**************************************************************************/
 	block, err := aes.NewCipher(MasterKey)
 	if (err != nil) {
-		logs.Error(err)
 		return nil, nil, nil, err
 	}
 
 	/**************************************************************************
-	**	Generate the argon2 and scrypt hash from the password
+	**	Generate the argon2 and scrypt hash from the password, once the hash
+	**	pool has enough memory available
 	**************************************************************************/
+	release, err := hashPool.acquire(ctx)
+	if (err != nil) {
+		if (isHashPoolExhausted(err)) {
+			logWarning(ctx, `Hash pool exhausted`, `error`, err)
+			span.SetStatus(codes.ResourceExhausted, err.Error())
+		}
+		return nil, nil, nil, err
+	}
+	span.AddEvent(ctx, `hash pool slot acquired`)
 	argon2Hash, scryptHash, err := hashMemberPassword(password)
+	release()
 	if (err != nil) {
-		logs.Error(err)
 		return nil, nil, nil, err
 	}
 
@@ -102,12 +114,10 @@ func	GeneratePasswordHash(password string) ([]byte, []byte, cipher.Block, error)
 	**************************************************************************/
 	argon2Hash, err = pkcs7Pad(argon2Hash, block.BlockSize())
 	if (err != nil) {
-		logs.Error(err)
 		return nil, nil, nil, err
 	}
 	scryptHash, err = pkcs7Pad(scryptHash, block.BlockSize())
 	if (err != nil) {
-		logs.Error(err)
 		return nil, nil, nil, err
 	}
 	return argon2Hash, scryptHash, block, nil
@@ -123,12 +133,10 @@ func	EncryptPasswordHash(plainArgon2Hash, plainScryptHash []byte, block cipher.B
 	**************************************************************************/
 	argon2IV, err := generateNonce(aes.BlockSize)
 	if (err != nil) {
-		logs.Error(err)
 		return nil, nil, nil, nil, err
 	}
 	scryptIV, err := generateNonce(aes.BlockSize)
 	if (err != nil) {
-		logs.Error(err)
 		return nil, nil, nil, nil, err
 	}
 
@@ -145,17 +153,36 @@ func	EncryptPasswordHash(plainArgon2Hash, plainScryptHash []byte, block cipher.B
 	return argon2Hash, argon2IV, scryptHash, scryptIV, nil
 }
 
+/******************************************************************************
+**	Hash the password and set the encrypted hashes of the member, encoded as
+**	they are stored
+******************************************************************************/
+func	setPasswordHashes(ctx context.Context, member *sMember, password string) (error) {
+	plainArgon2Hash, plainScryptHash, block, err := GeneratePasswordHash(ctx, password)
+	if (err != nil) {
+		return err
+	}
+	argon2Hash, argon2IV, scryptHash, scryptIV, err := EncryptPasswordHash(plainArgon2Hash, plainScryptHash, block)
+	if (err != nil) {
+		return err
+	}
+	member.PasswordArgon2Hash = base64.RawStdEncoding.EncodeToString(argon2Hash)
+	member.PasswordArgon2IV = base64.RawStdEncoding.EncodeToString(argon2IV)
+	member.PasswordScryptHash = base64.RawStdEncoding.EncodeToString(scryptHash)
+	member.PasswordScryptIV = base64.RawStdEncoding.EncodeToString(scryptIV)
+	return nil
+}
+
 /******************************************************************************
 **	Symetric encryption. Decrypt the hashes, from the database, with the
 **	MasterKey to get the plain hashes
 ******************************************************************************/
 func	DecryptPasswordHash(argon2Hash, argon2IV, scryptHash, scryptIV []byte) ([]byte, []byte, error) {
 	/**************************************************************************
-	**	Get the master key from the .env file
+	**	Get the master key from the configuration
 	**************************************************************************/
-	MasterKey, err := base64.RawStdEncoding.DecodeString(os.Getenv("MASTER_KEY"))
+	MasterKey, err := base64.RawStdEncoding.DecodeString(config.Keys.Master)
 	if (err != nil) {
-		logs.Error(err)
 		return nil, nil, err
 	}
 
@@ -164,29 +191,50 @@ func	DecryptPasswordHash(argon2Hash, argon2IV, scryptHash, scryptIV []byte) ([]b
 	**************************************************************************/
 	block, err := aes.NewCipher(MasterKey)
 	if (err != nil) {
-		logs.Error(err)
 		return nil, nil, err
 	}
 
 	/**************************************************************************
-	**	Decrypt the ciphertext
-	**************************************************************************/
-	argon2UnHash := make([]byte, len(argon2Hash))
-	argon2Dec := cipher.NewCBCDecrypter(block, argon2IV)
-	argon2Dec.CryptBlocks(argon2UnHash, argon2Hash)
-
-	scryptUnHash := make([]byte, len(scryptHash))
-	scryptDec := cipher.NewCBCDecrypter(block, scryptIV)
-	scryptDec.CryptBlocks(scryptUnHash, scryptHash)
-
-	/**************************************************************************
-	**	Unpad the result
+	**	Decrypt the ciphertext and unpad the result. The scrypt hash is empty
+	**	for the members imported with a legacy hash.
 	**************************************************************************/
-	argon2UnHash, _ = pkcs7Unpad(argon2UnHash, aes.BlockSize)
-	scryptUnHash, _ = pkcs7Unpad(scryptUnHash, aes.BlockSize)
+	argon2UnHash := decryptHashBlocks(block, argon2Hash, argon2IV)
+	scryptUnHash := decryptHashBlocks(block, scryptHash, scryptIV)
 
 	return argon2UnHash, scryptUnHash, nil
 }
+func	decryptHashBlocks(block cipher.Block, hash, IV []byte) ([]byte) {
+	if (len(hash) == 0 || len(hash) % aes.BlockSize != 0 || len(IV) != aes.BlockSize) {
+		return nil
+	}
+	unHash := make([]byte, len(hash))
+	dec := cipher.NewCBCDecrypter(block, IV)
+	dec.CryptBlocks(unHash, hash)
+	unHash, _ = pkcs7Unpad(unHash, aes.BlockSize)
+	return unHash
+}
+
+/******************************************************************************
+**	Get the dummy hashes used to verify the password of an unknown email, so
+**	that an unknown email costs the same argon2 and scrypt time as a known
+**	one. They are generated once, at startup, from a random password nobody
+**	knows. Without them, an unknown email would fail fast : the error is
+**	returned instead.
+******************************************************************************/
+var		dummyPasswordHashOnce sync.Once
+var		dummyArgon2Hash []byte
+var		dummyScryptHash []byte
+var		dummyPasswordHashErr error
+func	getDummyPasswordHash() ([]byte, []byte, error) {
+	dummyPasswordHashOnce.Do(func() {
+		dummyPassword, err := generateNonce(32)
+		if (err == nil) {
+			dummyArgon2Hash, dummyScryptHash, err = hashMemberPassword(base64.RawStdEncoding.EncodeToString(dummyPassword))
+		}
+		dummyPasswordHashErr = err
+	})
+	return dummyArgon2Hash, dummyScryptHash, dummyPasswordHashErr
+}
 
 /******************************************************************************
 **	Take a key (the user password) and a salt to get the encryption hash used
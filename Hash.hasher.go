@@ -0,0 +1,161 @@
+/*******************************************************************************
+** @Author:					Thomas Bouder <Tbouder>
+** @Email:					Tbouder@protonmail.com
+** @Date:					Monday 06 April 2020 - 11:47:30
+** @Filename:				Hash.hasher.go
+**
+** @Last modified by:		Tbouder
+** @Last modified time:		Wednesday 29 April 2020 - 18:31:12
+*******************************************************************************/
+
+package			main
+
+import			"errors"
+import			"strings"
+import			"strconv"
+import			"crypto/sha256"
+import			"crypto/subtle"
+import			"encoding/base64"
+import			"golang.org/x/crypto/bcrypt"
+import			"golang.org/x/crypto/pbkdf2"
+
+/******************************************************************************
+**	A PasswordHasher knows how to verify an encoded hash of a specific
+**	algorithm, identified by the `$algo$` prefix of the encoded hash. The
+**	native scheme is the argon2id + scrypt pair, the other hashers are only
+**	used to verify the hashes imported from legacy galleries (bcrypt and
+**	PBKDF2-SHA256), which are upgraded to the native scheme on first login.
+**
+**	Imported members store their legacy hash in the PasswordArgon2Hash
+**	column, encrypted with the MasterKey like the native hashes, and leave
+**	the PasswordScryptHash column empty.
+******************************************************************************/
+type	PasswordHasher interface {
+	Hash(password string) (string, error)
+	Verify(password, encodedHash string) (bool, error)
+	IsLegacy() (bool)
+}
+
+var (
+	ErrUnknownHashAlgorithm	= errors.New("unknown password hash algorithm")
+	ErrLegacyHashAlgorithm	= errors.New("legacy hash algorithms cannot be used for new hashes")
+)
+
+var		passwordHashers = map[string]PasswordHasher{
+	`argon2id`:			argon2Hasher{},
+	`scrypt`:			scryptHasher{},
+	`2a`:				bcryptHasher{},
+	`2b`:				bcryptHasher{},
+	`2y`:				bcryptHasher{},
+	`pbkdf2-sha256`:	pbkdf2Hasher{},
+}
+
+/******************************************************************************
+**	Get the hasher matching the algorithm of an encoded hash
+******************************************************************************/
+func	getPasswordHasher(encodedHash string) (PasswordHasher, error) {
+	vals := strings.SplitN(encodedHash, "$", 3)
+	if (len(vals) != 3 || vals[0] != ``) {
+		return nil, ErrInvalidHash
+	}
+	hasher, ok := passwordHashers[vals[1]]
+	if (!ok) {
+		return nil, ErrUnknownHashAlgorithm
+	}
+	return hasher, nil
+}
+
+/******************************************************************************
+**	The algorithm of an encoded hash, for the metrics
+******************************************************************************/
+func	hashAlgorithm(encodedHash string) (string) {
+	vals := strings.SplitN(encodedHash, "$", 3)
+	if (len(vals) != 3 || vals[0] != ``) {
+		return `unknown`
+	}
+	return vals[1]
+}
+
+/******************************************************************************
+**	Native hashers, wrapping the argon2 and scrypt helpers
+******************************************************************************/
+type	argon2Hasher struct {}
+func	(argon2Hasher) Hash(password string) (string, error) {
+	encodedHash, _, _, err := generateArgon2HashFromPassword(password)
+	return encodedHash, err
+}
+func	(argon2Hasher) Verify(password, encodedHash string) (bool, error) {
+	return compareArgon2PasswordAndHash(password, encodedHash)
+}
+func	(argon2Hasher) IsLegacy() (bool) {
+	return false
+}
+
+type	scryptHasher struct {}
+func	(scryptHasher) Hash(password string) (string, error) {
+	return generateScryptHashFromPassword(password)
+}
+func	(scryptHasher) Verify(password, encodedHash string) (bool, error) {
+	return compareScryptPasswordAndHash(password, encodedHash)
+}
+func	(scryptHasher) IsLegacy() (bool) {
+	return false
+}
+
+/******************************************************************************
+**	Legacy bcrypt hasher : `$2a$cost$<salt+hash>`
+******************************************************************************/
+type	bcryptHasher struct {}
+func	(bcryptHasher) Hash(password string) (string, error) {
+	return ``, ErrLegacyHashAlgorithm
+}
+func	(bcryptHasher) Verify(password, encodedHash string) (bool, error) {
+	err := bcrypt.CompareHashAndPassword([]byte(encodedHash), []byte(password))
+	if (err == bcrypt.ErrMismatchedHashAndPassword) {
+		return false, nil
+	} else if (err != nil) {
+		return false, err
+	}
+	return true, nil
+}
+func	(bcryptHasher) IsLegacy() (bool) {
+	return true
+}
+
+/******************************************************************************
+**	Legacy PBKDF2-SHA256 hasher, in the passlib format :
+**	`$pbkdf2-sha256$rounds$salt$checksum` where salt and checksum use the
+**	adapted base64 alphabet (`.` instead of `+`, without padding)
+******************************************************************************/
+type	pbkdf2Hasher struct {}
+func	(pbkdf2Hasher) Hash(password string) (string, error) {
+	return ``, ErrLegacyHashAlgorithm
+}
+func	(pbkdf2Hasher) Verify(password, encodedHash string) (bool, error) {
+	vals := strings.Split(encodedHash, "$")
+	if len(vals) != 5 {
+		return false, ErrInvalidHash
+	}
+
+	rounds, err := strconv.Atoi(vals[2])
+	if (err != nil || rounds <= 0) {
+		return false, ErrInvalidHash
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(strings.Replace(vals[3], `.`, `+`, -1))
+	if (err != nil) {
+		return false, err
+	}
+	hash, err := base64.RawStdEncoding.DecodeString(strings.Replace(vals[4], `.`, `+`, -1))
+	if (err != nil) {
+		return false, err
+	}
+
+	otherHash := pbkdf2.Key([]byte(password), salt, rounds, len(hash), sha256.New)
+	if (subtle.ConstantTimeCompare(hash, otherHash) == 1) {
+		return true, nil
+	}
+	return false, nil
+}
+func	(pbkdf2Hasher) IsLegacy() (bool) {
+	return true
+}
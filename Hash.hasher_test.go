@@ -0,0 +1,123 @@
+/*******************************************************************************
+** @Author:					Thomas Bouder <Tbouder>
+** @Email:					Tbouder@protonmail.com
+** @Date:					Saturday 09 May 2020 - 10:03:27
+** @Filename:				Hash.hasher_test.go
+**
+** @Last modified by:		Tbouder
+** @Last modified time:		Saturday 09 May 2020 - 10:03:27
+*******************************************************************************/
+
+
+package			main
+
+import			"strings"
+import			"context"
+import			"testing"
+import			"crypto/aes"
+import			"encoding/base64"
+import			"google.golang.org/grpc/codes"
+import			"github.com/panghostlin/SDK/Members"
+
+/******************************************************************************
+**	Hashes of TEST_PASSWORD, as a legacy gallery would export them
+******************************************************************************/
+const	TEST_BCRYPT_HASH = `$2a$04$bjni8KOLFD2oPXXAPVkNWOmd6rfTjiq.SdHs2g8H3KreFCOKvz5YG`
+const	TEST_PBKDF2_HASH = `$pbkdf2-sha256$29000$bGVnYWN5LWdhbGxlcnktc2FsdA$uRJxrynh6mq7..OLADNu7z6tIuZoZwtxPAQ/Twppr30`
+
+/******************************************************************************
+**	Import the legacy hash of a member the way the import does : encrypted
+**	with the MasterKey in the argon2 column, without scrypt hash
+******************************************************************************/
+func	importLegacyHash(t *testing.T, store *sMemoryStore, memberID, encodedHash string) {
+	masterKey, _ := base64.RawStdEncoding.DecodeString(config.Keys.Master)
+	block, err := aes.NewCipher(masterKey)
+	if (err != nil) {
+		t.Fatal(err)
+	}
+	plainHash, err := pkcs7Pad([]byte(encodedHash), block.BlockSize())
+	if (err != nil) {
+		t.Fatal(err)
+	}
+	argon2Hash, argon2IV, _, _, err := EncryptPasswordHash(plainHash, []byte{}, block)
+	if (err != nil) {
+		t.Fatal(err)
+	}
+
+	member, _ := store.GetMemberByID(context.Background(), memberID)
+	member.PasswordArgon2Hash = base64.RawStdEncoding.EncodeToString(argon2Hash)
+	member.PasswordArgon2IV = base64.RawStdEncoding.EncodeToString(argon2IV)
+	member.PasswordScryptHash = ``
+	member.PasswordScryptIV = ``
+	if err := store.UpdateMember(context.Background(), member); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func	storedPasswordHashes(t *testing.T, store *sMemoryStore, memberID string) (string, string) {
+	member, _ := store.GetMemberByID(context.Background(), memberID)
+	argon2Hash, _ := base64.RawStdEncoding.DecodeString(member.PasswordArgon2Hash)
+	argon2IV, _ := base64.RawStdEncoding.DecodeString(member.PasswordArgon2IV)
+	scryptHash, _ := base64.RawStdEncoding.DecodeString(member.PasswordScryptHash)
+	scryptIV, _ := base64.RawStdEncoding.DecodeString(member.PasswordScryptIV)
+	plainArgon2Hash, plainScryptHash, err := DecryptPasswordHash(argon2Hash, argon2IV, scryptHash, scryptIV)
+	if (err != nil) {
+		t.Fatal(err)
+	}
+	return string(plainArgon2Hash), string(plainScryptHash)
+}
+
+func	TestLegacyHashVerify(t *testing.T) {
+	for _, encodedHash := range []string{TEST_BCRYPT_HASH, TEST_PBKDF2_HASH} {
+		hasher, err := getPasswordHasher(encodedHash)
+		if (err != nil || !hasher.IsLegacy()) {
+			t.Fatalf("%s: expected a legacy hasher, got %v", hashAlgorithm(encodedHash), err)
+		}
+		if ok, err := hasher.Verify(TEST_PASSWORD, encodedHash); !ok || err != nil {
+			t.Errorf("%s: the password must match, got %v", hashAlgorithm(encodedHash), err)
+		}
+		if ok, _ := hasher.Verify(`wrong-password`, encodedHash); ok {
+			t.Errorf("%s: a wrong password must not match", hashAlgorithm(encodedHash))
+		}
+		if _, err := hasher.Hash(TEST_PASSWORD); err != ErrLegacyHashAlgorithm {
+			t.Errorf("%s: expected ErrLegacyHashAlgorithm, got %v", hashAlgorithm(encodedHash), err)
+		}
+	}
+}
+
+/******************************************************************************
+**	A login with the right password replaces the legacy hash with the
+**	native argon2 and scrypt hashes. A wrong password leaves it untouched.
+******************************************************************************/
+func	TestLegacyHashUpgradeOnLogin(t *testing.T) {
+	for _, encodedHash := range []string{TEST_BCRYPT_HASH, TEST_PBKDF2_HASH} {
+		t.Run(hashAlgorithm(encodedHash), func(t *testing.T) {
+			s, store := newTestServer()
+			created := createTestMember(t, s, `legacy@example.com`)
+			importLegacyHash(t, store, created.MemberID, encodedHash)
+
+			_, err := s.LoginMember(context.Background(), &members.LoginMemberRequest{Email: `legacy@example.com`, Password: `wrong-password`})
+			if code := statusCode(err); code != codes.Unauthenticated {
+				t.Fatalf("a wrong password: expected Unauthenticated, got %v", code)
+			}
+			if argon2Hash, _ := storedPasswordHashes(t, store, created.MemberID); argon2Hash != encodedHash {
+				t.Fatalf("a wrong password must not replace the legacy hash")
+			}
+
+			if _, err := s.LoginMember(context.Background(), &members.LoginMemberRequest{Email: `legacy@example.com`, Password: TEST_PASSWORD}); err != nil {
+				t.Fatalf("LoginMember: %v", err)
+			}
+			argon2Hash, scryptHash := storedPasswordHashes(t, store, created.MemberID)
+			if (!strings.HasPrefix(argon2Hash, `$argon2id$`) || !strings.HasPrefix(scryptHash, `$scrypt$`)) {
+				t.Errorf("expected the native hashes, got %q and %q", argon2Hash, scryptHash)
+			}
+			if (strings.Contains(argon2Hash + scryptHash, encodedHash)) {
+				t.Errorf("the legacy hash must be gone")
+			}
+
+			if _, err := s.LoginMember(context.Background(), &members.LoginMemberRequest{Email: `legacy@example.com`, Password: TEST_PASSWORD}); err != nil {
+				t.Errorf("LoginMember with the upgraded hashes: %v", err)
+			}
+		})
+	}
+}
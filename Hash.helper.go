@@ -5,7 +5,7 @@
 ** @Filename:				Hash.helper.go
 **
 ** @Last modified by:		Tbouder
-** @Last modified time:		Wednesday 19 February 2020 - 15:27:36
+** @Last modified time:		Thursday 30 April 2020 - 14:55:08
 *******************************************************************************/
 
 package			main
@@ -18,6 +18,10 @@ import			"golang.org/x/crypto/scrypt"
 import			"crypto/subtle"
 import			"strings"
 import			"runtime"
+import			"time"
+import			"context"
+import			"go.opentelemetry.io/otel/api/key"
+import			"go.opentelemetry.io/otel/api/trace"
 
 type argon2Params struct {
 	memory          uint32
@@ -25,6 +29,7 @@ type argon2Params struct {
 	parallelism     uint8
 	saltLength      uint32
 	keyLength       uint32
+	pepperVersion   int
 }
 
 func    initArgon2Parameters() (*argon2Params) {
@@ -39,6 +44,10 @@ func    initArgon2Parameters() (*argon2Params) {
 const	MemoryAmount = 32 //Should be 64
 var		argon2Parameters = initArgon2Parameters()
 
+const	scryptN = 64 * 1024
+const	scryptR = 8
+const	scryptP = 3
+
 func	generateNonce(n uint32) ([]byte, error) {
 	b := make([]byte, n)
 	_, err := rand.Read(b)
@@ -58,11 +67,15 @@ func	generateArgon2HashFromPassword(password string) (encodedHash string, salt,
 	if (err != nil) {
 		return ``, nil, nil, err
 	}
+	pepperedPassword, err := pepper.apply(password, pepper.current)
+	if (err != nil) {
+		return ``, nil, nil, err
+	}
 
-	hash = argon2.IDKey([]byte(password), salt, argon2Parameters.iterations, argon2Parameters.memory, argon2Parameters.parallelism, argon2Parameters.keyLength)
+	hash = argon2.IDKey([]byte(pepperedPassword), salt, argon2Parameters.iterations, argon2Parameters.memory, argon2Parameters.parallelism, argon2Parameters.keyLength)
 	b64Salt := base64.RawStdEncoding.EncodeToString(salt)
 	b64Hash := base64.RawStdEncoding.EncodeToString(hash)
-	encodedHash = fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version, argon2Parameters.memory, argon2Parameters.iterations, argon2Parameters.parallelism, b64Salt, b64Hash)
+	encodedHash = fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d%s$%s$%s", argon2.Version, argon2Parameters.memory, argon2Parameters.iterations, argon2Parameters.parallelism, formatPepperVersion(pepper.current), b64Salt, b64Hash)
 	return encodedHash, salt, hash, nil
 }
 func	compareArgon2PasswordAndHash(password, encodedHash string) (match bool, err error) {
@@ -71,7 +84,12 @@ func	compareArgon2PasswordAndHash(password, encodedHash string) (match bool, err
 		return false, err
 	}
 
-	otherHash := argon2.IDKey([]byte(password), salt, p.iterations, p.memory, p.parallelism, p.keyLength)
+	pepperedPassword, err := pepper.apply(password, p.pepperVersion)
+	if (err != nil) {
+		return false, err
+	}
+
+	otherHash := argon2.IDKey([]byte(pepperedPassword), salt, p.iterations, p.memory, p.parallelism, p.keyLength)
 
 	if (subtle.ConstantTimeCompare(hash, otherHash) == 1) {
 		return true, nil
@@ -94,7 +112,12 @@ func	decodeArgon2Hash(encodedHash string) (p *argon2Params, salt, hash []byte, e
 	}
 
 	p = &argon2Params{}
-	_, err = fmt.Sscanf(vals[3], "m=%d,t=%d,p=%d", &p.memory, &p.iterations, &p.parallelism)
+	params, pepperVersion, err := splitPepperVersion(vals[3])
+	if (err != nil) {
+		return nil, nil, nil, err
+	}
+	p.pepperVersion = pepperVersion
+	_, err = fmt.Sscanf(params, "m=%d,t=%d,p=%d", &p.memory, &p.iterations, &p.parallelism)
 	if (err != nil) {
 		return nil, nil, nil, err
 	}
@@ -113,68 +136,68 @@ func	decodeArgon2Hash(encodedHash string) (p *argon2Params, salt, hash []byte, e
 
 	return p, salt, hash, nil
 }
-func	argon2Match(password, hashedPassword string) (bool) {
-	match, err := compareArgon2PasswordAndHash(password, hashedPassword)
-	if (err != nil) {
-		return (false)
-	}
-	return (match)
-}
-
 
 func	generateScryptHashFromPassword(password string) (encodedHash string, err error) {
-	const	memory = 64 * 1024
-	const	r = 8
-	const	p = 3
-	
 	salt, err := generateNonce(32)
 	if (err != nil) {
 		return ``, err
 	}
+	pepperedPassword, err := pepper.apply(password, pepper.current)
+	if (err != nil) {
+		return ``, err
+	}
 
-	hash, err := scrypt.Key([]byte(password), salt, memory, r, p, 32)
+	hash, err := scrypt.Key([]byte(pepperedPassword), salt, scryptN, scryptR, scryptP, 32)
 	if (err != nil) {
 		return ``, err
 	}
 	b64Salt := base64.RawStdEncoding.EncodeToString(salt)
 	b64Hash := base64.RawStdEncoding.EncodeToString(hash)
 	
-	encodedHash = fmt.Sprintf("$scrypt$n=%d,r=%d,p=%d$%s$%s", memory, r, p, b64Salt, b64Hash)
+	encodedHash = fmt.Sprintf("$scrypt$n=%d,r=%d,p=%d%s$%s$%s", scryptN, scryptR, scryptP, formatPepperVersion(pepper.current), b64Salt, b64Hash)
 
 	return encodedHash, nil
 }
-func	decodeScryptHash(encodedHash string) (salt, hash []byte, memory, r, p, keyLen int, err error) {
+func	decodeScryptHash(encodedHash string) (salt, hash []byte, memory, r, p, keyLen, pepperVersion int, err error) {
 	vals := strings.Split(encodedHash, "$")
 	if len(vals) != 5 {
-		return nil, nil, 0, 0, 0, 0, ErrInvalidHash
+		return nil, nil, 0, 0, 0, 0, 0, ErrInvalidHash
 	}
 
-	_, err = fmt.Sscanf(vals[2], "n=%d,r=%d,p=%d", &memory, &r, &p)
+	params, pepperVersion, err := splitPepperVersion(vals[2])
+	if (err != nil) {
+		return nil, nil, 0, 0, 0, 0, 0, err
+	}
+	_, err = fmt.Sscanf(params, "n=%d,r=%d,p=%d", &memory, &r, &p)
 	if (err != nil) {
-		return nil, nil, 0, 0, 0, 0, err
+		return nil, nil, 0, 0, 0, 0, 0, err
 	}
 
 	salt, err = base64.RawStdEncoding.DecodeString(vals[3])
 	if (err != nil) {
-		return nil, nil, 0, 0, 0, 0, err
+		return nil, nil, 0, 0, 0, 0, 0, err
 	}
 
 	hash, err = base64.RawStdEncoding.DecodeString(vals[4])
 	if (err != nil) {
-		return nil, nil, 0, 0, 0, 0, err
+		return nil, nil, 0, 0, 0, 0, 0, err
 	}
 	keyLen = len(hash)
 	
 	return
 }
 func	compareScryptPasswordAndHash(password, encodedHash string) (match bool, err error) {
-   salt, hash, memory, r, p, keyLen, err := decodeScryptHash(encodedHash)
+   salt, hash, memory, r, p, keyLen, pepperVersion, err := decodeScryptHash(encodedHash)
 
 	if (err != nil) {
 		return false, err
 	}
+	pepperedPassword, err := pepper.apply(password, pepperVersion)
+	if (err != nil) {
+		return false, err
+	}
 
-	otherHash, err := scrypt.Key([]byte(password), salt, memory, r, p, keyLen)
+	otherHash, err := scrypt.Key([]byte(pepperedPassword), salt, memory, r, p, keyLen)
 	if (err != nil) {
 		return false, err
 	}
@@ -183,8 +206,9 @@ func	compareScryptPasswordAndHash(password, encodedHash string) (match bool, err
 	}
 	return false, nil
 }
-func	scryptMatch(password, hashedPassword string) (bool) {
-	match, err := compareScryptPasswordAndHash(password, hashedPassword)
+func	passwordMatch(hasher PasswordHasher, password, hashedPassword string) (bool) {
+	defer observePasswordHash(hashAlgorithm(hashedPassword), `verify`, time.Now())
+	match, err := hasher.Verify(password, hashedPassword)
 	if (err != nil) {
 		return (false)
 	}
@@ -192,16 +216,37 @@ func	scryptMatch(password, hashedPassword string) (bool) {
 }
 
 func	hashMemberPassword(password string) ([]byte, []byte, error) {
-	encodedArgon2Hash, _, _, err := generateArgon2HashFromPassword(password)
+	start := time.Now()
+	encodedArgon2Hash, err := passwordHashers[`argon2id`].Hash(password)
 	if (err != nil) {
 		return nil, nil, err
 	}
-	encodedScryptHash, err := generateScryptHashFromPassword(password)
+	observePasswordHash(`argon2id`, `hash`, start)
+
+	start = time.Now()
+	encodedScryptHash, err := passwordHashers[`scrypt`].Hash(password)
 	if (err != nil) {
 		return nil, nil, err
 	}
+	observePasswordHash(`scrypt`, `hash`, start)
 	return []byte(encodedArgon2Hash), []byte(encodedScryptHash), nil
 }
-func    verifyMemberPasswordHash(password, argon2Hash, scryptHash string) (bool) {
-	return argon2Match(password, argon2Hash) && scryptMatch(password, scryptHash)
+
+/******************************************************************************
+**	Verify the password against the member hashes. A legacy hash, imported
+**	in the argon2 column, is verified alone and the second returned value
+**	tells that the member hashes should be upgraded to the native scheme.
+******************************************************************************/
+func    verifyMemberPasswordHash(ctx context.Context, password, argon2Hash, scryptHash string) (bool, bool) {
+	_, span := startSpan(ctx, `verifyMemberPasswordHash`, trace.WithAttributes(key.String(`hash.algorithm`, hashAlgorithm(argon2Hash))))
+	defer span.End()
+
+	hasher, err := getPasswordHasher(argon2Hash)
+	if (err != nil) {
+		return false, false
+	}
+	if (hasher.IsLegacy()) {
+		return passwordMatch(hasher, password, argon2Hash), true
+	}
+	return passwordMatch(passwordHashers[`argon2id`], password, argon2Hash) && passwordMatch(passwordHashers[`scrypt`], password, scryptHash), false
 }
@@ -0,0 +1,132 @@
+/*******************************************************************************
+** @Author:					Thomas Bouder <Tbouder>
+** @Email:					Tbouder@protonmail.com
+** @Date:					Saturday 04 April 2020 - 16:21:08
+** @Filename:				Hash.pepper.go
+**
+** @Last modified by:		Tbouder
+** @Last modified time:		Saturday 09 May 2020 - 09:12:40
+*******************************************************************************/
+
+package			main
+
+import			"errors"
+import			"strings"
+import			"strconv"
+import			"crypto/hmac"
+import			"crypto/sha256"
+import			"encoding/base64"
+
+/******************************************************************************
+**	The pepper is a server-side secret, stored apart from the MASTER_KEY,
+**	applied to the password with HMAC-SHA256 before it is hashed. Peppers are
+**	versioned to allow rotation : keys.pepper holds every known version
+**	(`1:base64key,2:base64key`) and keys.pepperVersion the one used for new
+**	hashes. The version is stored in the encoded hash as `pv=N`, and version 0
+**	means that no pepper was applied.
+******************************************************************************/
+var (
+	ErrInvalidPepperKeys		= errors.New("invalid PEPPER_KEYS format")
+	ErrUnknownPepperVersion		= errors.New("unknown pepper version")
+)
+
+type	sPepper struct {
+	current	int
+	keys	map[int][]byte
+}
+var		pepper = &sPepper{keys: map[int][]byte{}}
+
+func	initPepper() (*sPepper) {
+	p, err := parsePepper(config.Keys.Pepper, config.Keys.PepperVersion)
+	if (err != nil) {
+		logFatal(`Invalid pepper`, `error`, err)
+	}
+	return p
+}
+
+func	parsePepper(pepperKeys, pepperVersion string) (*sPepper, error) {
+	p := &sPepper{keys: map[int][]byte{}}
+
+	/**************************************************************************
+	**	A PEPPER_VERSION without PEPPER_KEYS is a forgotten key, not a
+	**	server without pepper
+	**************************************************************************/
+	if (pepperKeys == `` && (pepperVersion == `` || pepperVersion == `0`)) {
+		return p, nil
+	} else if (pepperKeys == ``) {
+		return nil, ErrUnknownPepperVersion
+	}
+
+	for _, each := range strings.Split(pepperKeys, `,`) {
+		parts := strings.SplitN(strings.TrimSpace(each), `:`, 2)
+		if (len(parts) != 2) {
+			return nil, ErrInvalidPepperKeys
+		}
+		version, err := strconv.Atoi(parts[0])
+		if (err != nil || version <= 0) {
+			return nil, ErrInvalidPepperKeys
+		}
+		key, err := base64.RawStdEncoding.DecodeString(parts[1])
+		if (err != nil || len(key) == 0) {
+			return nil, ErrInvalidPepperKeys
+		}
+		p.keys[version] = key
+		if (version > p.current) {
+			p.current = version
+		}
+	}
+
+	/**************************************************************************
+	**	Without an explicit PEPPER_VERSION, the most recent pepper is used
+	**************************************************************************/
+	if (pepperVersion != ``) {
+		version, err := strconv.Atoi(pepperVersion)
+		if (err != nil) {
+			return nil, ErrInvalidPepperKeys
+		}
+		if _, ok := p.keys[version]; (!ok && version != 0) {
+			return nil, ErrUnknownPepperVersion
+		}
+		p.current = version
+	}
+	return p, nil
+}
+
+/******************************************************************************
+**	Apply the pepper matching the version to the password. Version 0 returns
+**	the password untouched, to keep the hashes created without pepper valid.
+******************************************************************************/
+func	(p *sPepper) apply(password string, version int) (string, error) {
+	if (version == 0) {
+		return password, nil
+	}
+	key, ok := p.keys[version]
+	if (!ok) {
+		return ``, ErrUnknownPepperVersion
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(password))
+	return base64.RawStdEncoding.EncodeToString(mac.Sum(nil)), nil
+}
+
+/******************************************************************************
+**	Format the pepper version to append to the hash parameters, and split it
+**	back from the parameters of an encoded hash
+******************************************************************************/
+func	formatPepperVersion(version int) (string) {
+	if (version == 0) {
+		return ``
+	}
+	return `,pv=` + strconv.Itoa(version)
+}
+func	splitPepperVersion(params string) (string, int, error) {
+	index := strings.Index(params, `,pv=`)
+	if (index < 0) {
+		return params, 0, nil
+	}
+	version, err := strconv.Atoi(params[index + len(`,pv=`):])
+	if (err != nil) {
+		return ``, 0, ErrInvalidHash
+	}
+	return params[:index], version, nil
+}
@@ -0,0 +1,42 @@
+/*******************************************************************************
+** @Author:					Thomas Bouder <Tbouder>
+** @Email:					Tbouder@protonmail.com
+** @Date:					Saturday 09 May 2020 - 09:12:40
+** @Filename:				Hash.pepper_test.go
+**
+** @Last modified by:		Tbouder
+** @Last modified time:		Saturday 09 May 2020 - 09:12:40
+*******************************************************************************/
+
+
+package			main
+
+import			"testing"
+
+func	TestParsePepper(t *testing.T) {
+	cases := []struct {
+		name			string
+		keys			string
+		version			string
+		current			int
+		err				error
+	}{
+		{`no pepper`, ``, ``, 0, nil},
+		{`no pepper, explicitly`, ``, `0`, 0, nil},
+		{`a version without keys`, ``, `2`, 0, ErrUnknownPepperVersion},
+		{`an invalid version without keys`, ``, `two`, 0, ErrUnknownPepperVersion},
+		{`the most recent key by default`, `1:a2V5LW9uZQ,2:a2V5LXR3bw`, ``, 2, nil},
+		{`an explicit version`, `1:a2V5LW9uZQ,2:a2V5LXR3bw`, `1`, 1, nil},
+		{`an unknown version`, `1:a2V5LW9uZQ`, `3`, 0, ErrUnknownPepperVersion},
+		{`a key without version`, `a2V5LW9uZQ`, ``, 0, ErrInvalidPepperKeys},
+		{`an empty key`, `1:`, ``, 0, ErrInvalidPepperKeys},
+	}
+	for _, each := range cases {
+		p, err := parsePepper(each.keys, each.version)
+		if (err != each.err) {
+			t.Errorf("%s: expected %v, got %v", each.name, each.err, err)
+		} else if (err == nil && p.current != each.current) {
+			t.Errorf("%s: expected the version %d, got %d", each.name, each.current, p.current)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+/*******************************************************************************
+** @Author:					Thomas Bouder <Tbouder>
+** @Email:					Tbouder@protonmail.com
+** @Date:					Friday 03 April 2020 - 10:12:41
+** @Filename:				Hash.pool.go
+**
+** @Last modified by:		Tbouder
+** @Last modified time:		Friday 08 May 2020 - 09:12:30
+*******************************************************************************/
+
+package			main
+
+import			"time"
+import			"context"
+import			"sync/atomic"
+import			"golang.org/x/sync/semaphore"
+import			"google.golang.org/grpc/codes"
+import			"google.golang.org/grpc/status"
+import			"github.com/prometheus/client_golang/prometheus"
+import			"github.com/prometheus/client_golang/prometheus/promauto"
+
+/******************************************************************************
+**	Every password hash or verification runs argon2 with MemoryAmount MiB and
+**	scrypt with 128 * N * r bytes. The pool bounds the number of concurrent
+**	operations according to a memory budget (hashing.memoryBudget, in MiB)
+**	so that a burst of logins cannot exhaust the container memory.
+******************************************************************************/
+const	DEFAULT_HASH_MEMORY_BUDGET = 512
+const	DEFAULT_HASH_MAX_QUEUE = 64
+const	DEFAULT_HASH_QUEUE_TIMEOUT = 10
+
+type	sHashPool struct {
+	sem			*semaphore.Weighted
+	weight		int64
+	maxQueue	int64
+	timeout		time.Duration
+	queued		int64
+}
+
+var		hashPoolQueueDepth = promauto.NewGauge(prometheus.GaugeOpts{
+	Name:		`members_hash_pool_queue_depth`,
+	Help:		`Password operations waiting for a slot in the hash pool.`,
+})
+var		hashPoolInFlight = promauto.NewGauge(prometheus.GaugeOpts{
+	Name:		`members_hash_pool_in_flight`,
+	Help:		`Password operations running in the hash pool.`,
+})
+var		hashPoolRejected = promauto.NewCounter(prometheus.CounterOpts{
+	Name:		`members_hash_pool_rejected_total`,
+	Help:		`Password operations rejected because the hash pool was full or timed out.`,
+})
+var		hashPool *sHashPool
+
+func	initHashPool() (*sHashPool) {
+	/**************************************************************************
+	**	The weight of one operation is the memory, in MiB, used by argon2 and
+	**	scrypt. The budget can never be lower than a single operation.
+	**************************************************************************/
+	weight := int64(argon2Parameters.memory / 1024) + int64(128 * scryptN * scryptR) / (1024 * 1024)
+	budget := config.Hashing.MemoryBudget
+	if (budget < weight) {
+		budget = weight
+	}
+
+	return &sHashPool{
+		sem:		semaphore.NewWeighted(budget),
+		weight:		weight,
+		maxQueue:	config.Hashing.MaxQueue,
+		timeout:	time.Duration(config.Hashing.QueueTimeout) * time.Second,
+	}
+}
+
+/******************************************************************************
+**	Wait for enough memory to be available to perform a hashing operation.
+**	The wait is bounded by the context deadline and by the pool timeout. A
+**	ResourceExhausted error is returned if the queue is full or if the wait
+**	timed out, and the error of the context if the caller gave up. The
+**	returned function must be called to release the slot.
+******************************************************************************/
+func	(p *sHashPool) acquire(ctx context.Context) (func(), error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if (atomic.AddInt64(&p.queued, 1) > p.maxQueue) {
+		atomic.AddInt64(&p.queued, -1)
+		hashPoolRejected.Inc()
+		return nil, errResourceExhausted(`too many password operations in progress`, p.timeout)
+	}
+	hashPoolQueueDepth.Inc()
+
+	waitCtx, cancel := context.WithTimeout(ctx, p.timeout)
+	err := p.sem.Acquire(waitCtx, p.weight)
+	cancel()
+
+	atomic.AddInt64(&p.queued, -1)
+	hashPoolQueueDepth.Dec()
+	if (err != nil && ctx.Err() != nil) {
+		return nil, ctx.Err()
+	} else if (err != nil) {
+		hashPoolRejected.Inc()
+		return nil, errResourceExhausted(`timed out waiting for a password operation slot`, p.timeout)
+	}
+
+	hashPoolInFlight.Inc()
+	return func() {
+		hashPoolInFlight.Dec()
+		p.sem.Release(p.weight)
+	}, nil
+}
+
+func	isHashPoolExhausted(err error) (bool) {
+	return status.Code(err) == codes.ResourceExhausted
+}
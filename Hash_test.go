@@ -0,0 +1,138 @@
+/*******************************************************************************
+** @Author:					Thomas Bouder <Tbouder>
+** @Email:					Tbouder@protonmail.com
+** @Date:					Friday 08 May 2020 - 10:41:17
+** @Filename:				Hash_test.go
+**
+** @Last modified by:		Tbouder
+** @Last modified time:		Saturday 09 May 2020 - 11:20:05
+*******************************************************************************/
+
+
+package			main
+
+import			"os"
+import			"sort"
+import			"time"
+import			"context"
+import			"testing"
+import			"google.golang.org/grpc/codes"
+import			"google.golang.org/grpc/status"
+import			"github.com/panghostlin/SDK/Members"
+
+/******************************************************************************
+**	A hasher counting the hashes it verifies, to check that a login does the
+**	same work whatever the email
+******************************************************************************/
+type	sCountingHasher struct {
+	PasswordHasher
+	verified	[]string
+}
+func	(h *sCountingHasher) Verify(password, encodedHash string) (bool, error) {
+	h.verified = append(h.verified, encodedHash)
+	return h.PasswordHasher.Verify(password, encodedHash)
+}
+
+func	countPasswordVerifications(algorithm string) (*sCountingHasher, func()) {
+	hasher := passwordHashers[algorithm]
+	counting := &sCountingHasher{PasswordHasher: hasher}
+	passwordHashers[algorithm] = counting
+	return counting, func() {passwordHashers[algorithm] = hasher}
+}
+
+/******************************************************************************
+**	A login with an unknown email must not be distinguishable from a login
+**	with a wrong password : same error, and the same hashes verified, the
+**	unknown email against the dummy hashes.
+******************************************************************************/
+func	TestLoginUnknownEmailVerifiesDummyHash(t *testing.T) {
+	ctx := context.Background()
+	s, _ := newTestServer()
+	createTestMember(t, s, `known@example.com`)
+	dummyArgon2Hash, dummyScryptHash, _ := getDummyPasswordHash()
+
+	argon2, restoreArgon2 := countPasswordVerifications(`argon2id`)
+	defer restoreArgon2()
+	scrypt, restoreScrypt := countPasswordVerifications(`scrypt`)
+	defer restoreScrypt()
+
+	_, knownErr := s.LoginMember(ctx, &members.LoginMemberRequest{Email: `known@example.com`, Password: `wrong-` + TEST_PASSWORD})
+	knownArgon2, knownScrypt := len(argon2.verified), len(scrypt.verified)
+	_, unknownErr := s.LoginMember(ctx, &members.LoginMemberRequest{Email: `unknown@example.com`, Password: `wrong-` + TEST_PASSWORD})
+	if (status.Code(knownErr) != codes.Unauthenticated || status.Code(unknownErr) != codes.Unauthenticated) {
+		t.Fatalf("expected Unauthenticated, got %v and %v", knownErr, unknownErr)
+	}
+	if (knownErr.Error() != unknownErr.Error()) {
+		t.Fatalf("the errors differ : %q and %q", knownErr, unknownErr)
+	}
+
+	unknownArgon2, unknownScrypt := argon2.verified[knownArgon2:], scrypt.verified[knownScrypt:]
+	if (knownArgon2 == 0 || len(unknownArgon2) != knownArgon2 || len(unknownScrypt) != knownScrypt) {
+		t.Fatalf("expected the same verifications, got %d argon2 and %d scrypt against %d and %d", len(unknownArgon2), len(unknownScrypt), knownArgon2, knownScrypt)
+	}
+	for _, encodedHash := range unknownArgon2 {
+		if (encodedHash != string(dummyArgon2Hash)) {
+			t.Errorf("the unknown email must be verified against the dummy argon2 hash")
+		}
+	}
+	for _, encodedHash := range unknownScrypt {
+		if (encodedHash != string(dummyScryptHash)) {
+			t.Errorf("the unknown email must be verified against the dummy scrypt hash")
+		}
+	}
+}
+
+/******************************************************************************
+**	The same, measured : the logins are interleaved to spread the noise of
+**	the machine over both samples, and the medians are compared. The wall
+**	clock is too noisy for a shared runner : the test only runs with
+**	MEMBERS_TIMING_TESTS set.
+******************************************************************************/
+const	TIMING_SAMPLES = 15
+const	TIMING_TOLERANCE = 0.2
+
+func	medianDuration(samples []time.Duration) (time.Duration) {
+	sort.Slice(samples, func(i, j int) bool {return samples[i] < samples[j]})
+	return samples[len(samples) / 2]
+}
+
+func	TestLoginTimingUnknownEmail(t *testing.T) {
+	if (os.Getenv(`MEMBERS_TIMING_TESTS`) == ``) {
+		t.Skip(`the timing tests only run with MEMBERS_TIMING_TESTS set`)
+	}
+	ctx := context.Background()
+	s, _ := newTestServer()
+	_, err := s.CreateMember(ctx, &members.CreateMemberRequest{Email: `known@example.com`, Password: TEST_PASSWORD})
+	if (err != nil) {
+		t.Fatalf("CreateMember: %v", err)
+	}
+
+	login := func(email string) (time.Duration, error) {
+		start := time.Now()
+		_, err := s.LoginMember(ctx, &members.LoginMemberRequest{Email: email, Password: `wrong-` + TEST_PASSWORD})
+		return time.Since(start), err
+	}
+	login(`known@example.com`)
+	login(`unknown@example.com`)
+
+	var	known, unknown []time.Duration
+	for index := 0; index < TIMING_SAMPLES; index++ {
+		knownDuration, knownErr := login(`known@example.com`)
+		unknownDuration, unknownErr := login(`unknown@example.com`)
+		if (status.Code(knownErr) != codes.Unauthenticated || status.Code(unknownErr) != codes.Unauthenticated) {
+			t.Fatalf("expected Unauthenticated, got %v and %v", knownErr, unknownErr)
+		}
+		if (knownErr.Error() != unknownErr.Error()) {
+			t.Fatalf("the errors differ : %q and %q", knownErr, unknownErr)
+		}
+		known = append(known, knownDuration)
+		unknown = append(unknown, unknownDuration)
+	}
+
+	knownMedian, unknownMedian := medianDuration(known), medianDuration(unknown)
+	ratio := float64(unknownMedian) / float64(knownMedian)
+	t.Logf("median known %v, unknown %v, ratio %.3f", knownMedian, unknownMedian, ratio)
+	if (ratio < 1 - TIMING_TOLERANCE || ratio > 1 + TIMING_TOLERANCE) {
+		t.Errorf("the unknown emails are distinguishable : median %v against %v", unknownMedian, knownMedian)
+	}
+}
@@ -0,0 +1,151 @@
+/*******************************************************************************
+** @Author:					Thomas Bouder <Tbouder>
+** @Email:					Tbouder@protonmail.com
+** @Date:					Tuesday 28 April 2020 - 10:27:51
+** @Filename:				Health.go
+**
+** @Last modified by:		Tbouder
+** @Last modified time:		Saturday 02 May 2020 - 09:36:14
+*******************************************************************************/
+
+
+package			main
+
+import			"time"
+import			"errors"
+import			"context"
+import			"crypto/aes"
+import			"sync/atomic"
+import			"encoding/base64"
+import			"google.golang.org/grpc"
+import			"google.golang.org/grpc/health"
+import			"google.golang.org/grpc/connectivity"
+import			"google.golang.org/grpc/health/grpc_health_v1"
+
+/******************************************************************************
+**	The standard grpc.health.v1 service reports the readiness of the
+**	service : the overall status (empty service name, and MembersService)
+**	is SERVING once the migrations have run and while the required
+**	dependencies are reachable. Each dependency also has its own status,
+**	under its name : database, master-key and pictures (only when the
+**	bridge to Pictures exists, which does not change the overall status).
+**	The dependencies are checked every HEALTH_CHECK_INTERVAL.
+******************************************************************************/
+const	HEALTH_CHECK_INTERVAL = 5 * time.Second
+const	HEALTH_CHECK_TIMEOUT = 2 * time.Second
+const	HEALTH_SERVICE = `MembersService`
+
+var		healthServer = health.NewServer()
+var		healthStop = make(chan struct{})
+var		migrationsApplied int32
+
+type	sDependency struct {
+	name		string
+	required	bool
+	check		func(ctx context.Context) error
+}
+
+func	dependencies() ([]sDependency) {
+	list := []sDependency{
+		{name: `database`, required: true, check: func(ctx context.Context) error {return DB.PingContext(ctx)}},
+		{name: `master-key`, required: true, check: checkMasterKey},
+	}
+	if conn, ok := bridges[`pictures`]; ok {
+		list = append(list, sDependency{name: `pictures`, required: false, check: func(ctx context.Context) error {return checkBridge(conn)}})
+	}
+	return list
+}
+
+/******************************************************************************
+**	The master key must still decode to a valid AES key, as it is needed to
+**	encrypt and decrypt the password hashes
+******************************************************************************/
+func	checkMasterKey(ctx context.Context) (error) {
+	masterKey, err := base64.RawStdEncoding.DecodeString(config.Keys.Master)
+	if (err != nil) {
+		return err
+	}
+	_, err = aes.NewCipher(masterKey)
+	return err
+}
+
+func	checkBridge(conn *grpc.ClientConn) (error) {
+	switch state := conn.GetState(); state {
+	case connectivity.TransientFailure, connectivity.Shutdown:
+		return errors.New(`bridge is ` + state.String())
+	}
+	return nil
+}
+
+func	markMigrationsApplied() {
+	atomic.StoreInt32(&migrationsApplied, 1)
+}
+
+func	servingStatus(serving bool) (grpc_health_v1.HealthCheckResponse_ServingStatus) {
+	if (serving) {
+		return grpc_health_v1.HealthCheckResponse_SERVING
+	}
+	return grpc_health_v1.HealthCheckResponse_NOT_SERVING
+}
+
+/******************************************************************************
+**	Check every dependency and update the statuses. The changes are logged.
+******************************************************************************/
+func	updateHealth(previous map[string]bool) {
+	serving := atomic.LoadInt32(&migrationsApplied) == 1
+
+	for _, dependency := range dependencies() {
+		ctx, cancel := context.WithTimeout(context.Background(), HEALTH_CHECK_TIMEOUT)
+		err := dependency.check(ctx)
+		cancel()
+
+		healthy, known := previous[dependency.name]
+		if (err != nil && (healthy || !known)) {
+			logError(context.Background(), `Dependency is unhealthy`, `dependency`, dependency.name, `error`, err)
+		} else if (err == nil && !healthy && known) {
+			logInfo(context.Background(), `Dependency is healthy again`, `dependency`, dependency.name)
+		}
+		previous[dependency.name] = err == nil
+
+		healthServer.SetServingStatus(dependency.name, servingStatus(err == nil))
+		if (err != nil && dependency.required) {
+			serving = false
+		}
+	}
+	healthServer.SetServingStatus(``, servingStatus(serving))
+	healthServer.SetServingStatus(HEALTH_SERVICE, servingStatus(serving))
+}
+
+func	watchHealth() {
+	previous := map[string]bool{}
+	ticker := time.NewTicker(HEALTH_CHECK_INTERVAL)
+	defer ticker.Stop()
+
+	for {
+		updateHealth(previous)
+		select {
+		case <-ticker.C:
+		case <-healthStop:
+			return
+		}
+	}
+}
+
+/******************************************************************************
+**	Register the health service, NOT_SERVING until the first check
+******************************************************************************/
+func	serveHealth(srv *grpc.Server) {
+	healthServer.SetServingStatus(``, grpc_health_v1.HealthCheckResponse_NOT_SERVING)
+	healthServer.SetServingStatus(HEALTH_SERVICE, grpc_health_v1.HealthCheckResponse_NOT_SERVING)
+	grpc_health_v1.RegisterHealthServer(srv, healthServer)
+	go watchHealth()
+}
+
+/******************************************************************************
+**	On shutdown, every status is NOT_SERVING for good, so that no new
+**	request is routed to the service while it drains
+******************************************************************************/
+func	stopHealth() {
+	close(healthStop)
+	healthServer.Shutdown()
+}
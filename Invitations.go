@@ -0,0 +1,191 @@
+/*******************************************************************************
+** @Author:					Thomas Bouder <Tbouder>
+** @Email:					Tbouder@protonmail.com
+** @Date:					Thursday 07 May 2020 - 16:02:48
+** @Filename:				Invitations.go
+**
+** @Last modified by:		Tbouder
+** @Last modified time:		Saturday 09 May 2020 - 18:12:40
+*******************************************************************************/
+
+
+package			main
+
+import			"time"
+import			"context"
+import			"strings"
+import			"crypto/sha256"
+import			"encoding/hex"
+import			"encoding/base32"
+import			"google.golang.org/grpc/metadata"
+import			"github.com/panghostlin/Members/Extended"
+
+/******************************************************************************
+**	The registration is open to anyone by default. In the invite mode, the
+**	sign up requires an invitation code, created by an admin, and the closed
+**	mode refuses every sign up. The CreateMemberRequest of the SDK has no
+**	field for the code : the Proxy forwards it in the x-invitation-code
+**	header. Only an admin may manage the invitations.
+******************************************************************************/
+const	REGISTRATION_OPEN = `open`
+const	REGISTRATION_INVITE = `invite`
+const	REGISTRATION_CLOSED = `closed`
+const	INVITATION_CODE_HEADER = `x-invitation-code`
+const	INVITATION_CODE_SIZE = 15
+const	DEFAULT_INVITATION_EXPIRATION = 7 * 24 * 3600
+const	DEFAULT_INVITATIONS_PAGE_SIZE = 50
+const	MAX_INVITATIONS_PAGE_SIZE = 500
+
+var		invitationCodeEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+func	hashInvitationCode(code string) (string) {
+	hash := sha256.Sum256([]byte(strings.ToUpper(strings.TrimSpace(code))))
+	return hex.EncodeToString(hash[:])
+}
+
+/******************************************************************************
+**	Check that the registration mode allows the sign up, and return the
+**	invitation to use, if a code was given
+******************************************************************************/
+func	registrationRedemption(ctx context.Context) (*sRedemption, error) {
+	if (config.Registration.Mode == REGISTRATION_CLOSED) {
+		return nil, errPermissionDenied(`the registration is closed`)
+	}
+
+	var	code string
+	if incoming, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := incoming.Get(INVITATION_CODE_HEADER); len(values) > 0 {
+			code = strings.TrimSpace(values[0])
+		}
+	}
+	if (code == `` && config.Registration.Mode == REGISTRATION_INVITE) {
+		return nil, errInvalidArgument(`an invitation code is required`, fieldViolation(`invitationCode`, `INVITATION_CODE_REQUIRED`))
+	} else if (code == ``) {
+		return nil, nil
+	}
+	return &sRedemption{CodeHash: hashInvitationCode(code), Now: time.Now().Unix()}, nil
+}
+
+/******************************************************************************
+**	Create an invitation, usable maxUses times (once by default) until it
+**	expires, after expiration seconds. The email, if set, is the only one
+**	allowed to use it, and the members signing up with it get the plan.
+**	Returns the code, which is not stored and can not be retrieved later.
+******************************************************************************/
+func	(s *server) CreateInvitation(ctx context.Context, email, planID string, maxUses, expiration int64) (string, *sInvitation, error) {
+	if (maxUses < 0) {
+		return ``, nil, errInvalidArgument(`the uses must be positive`, fieldViolation(`maxUses`, `LIMIT_NEGATIVE`))
+	} else if (maxUses == 0) {
+		maxUses = 1
+	}
+	if (expiration < 0) {
+		return ``, nil, errInvalidArgument(`the expiration must be positive`, fieldViolation(`expiration`, `EXPIRATION_NEGATIVE`))
+	} else if (expiration == 0) {
+		expiration = config.Registration.InvitationExpiration
+	}
+	if (planID != ``) {
+		if _, err := s.planStore.GetPlan(ctx, planID); err == ErrPlanNotFound {
+			return ``, nil, errNotFound(`plan`, planID)
+		} else if (err != nil) {
+			return ``, nil, err
+		}
+	}
+
+	ID, err := newUUID()
+	if (err != nil) {
+		return ``, nil, err
+	}
+	nonce, err := generateNonce(INVITATION_CODE_SIZE)
+	if (err != nil) {
+		return ``, nil, err
+	}
+	code := invitationCodeEncoding.EncodeToString(nonce)
+
+	now := time.Now()
+	invitation := &sInvitation{
+		ID:			ID,
+		CodeHash:	hashInvitationCode(code),
+		Email:		strings.ToLower(strings.TrimSpace(email)),
+		PlanID:		planID,
+		MaxUses:	maxUses,
+		ExpiresAt:	now.Add(time.Duration(expiration) * time.Second).Unix(),
+		CreatedBy:	callerName(ctx),
+		CreatedAt:	now.Unix(),
+	}
+	if err := s.invitationStore.CreateInvitation(ctx, invitation); err != nil {
+		return ``, nil, err
+	}
+	recordAuditEvent(ctx, AUDIT_ADMIN_ACTION, invitation.CreatedBy, invitation.ID, `invitation_created`)
+	invitation.CodeHash = ``
+	return code, invitation, nil
+}
+
+/******************************************************************************
+**	List the invitations, the most recent first, without their codes
+******************************************************************************/
+func	(s *server) ListInvitations(ctx context.Context, limit int) ([]*sInvitation, error) {
+	if (limit <= 0) {
+		limit = DEFAULT_INVITATIONS_PAGE_SIZE
+	} else if (limit > MAX_INVITATIONS_PAGE_SIZE) {
+		limit = MAX_INVITATIONS_PAGE_SIZE
+	}
+	return s.invitationStore.ListInvitations(ctx, limit)
+}
+
+func	(s *server) RevokeInvitation(ctx context.Context, invitationID string) (error) {
+	if (invitationID == ``) {
+		return errInvalidArgument(`the invitationID is required`, fieldViolation(`invitationID`, `INVITATION_ID_REQUIRED`))
+	}
+	err := s.invitationStore.RevokeInvitation(ctx, invitationID, time.Now().Unix())
+	if (err == ErrInvitationNotFound) {
+		return errNotFound(`invitation`, invitationID)
+	} else if (err != nil) {
+		return err
+	}
+	recordAuditEvent(ctx, AUDIT_ADMIN_ACTION, callerName(ctx), invitationID, `invitation_revoked`)
+	return nil
+}
+
+/******************************************************************************
+**	The invitation RPCs of the MembersExtendedService
+******************************************************************************/
+func	newInvitationMessage(invitation *sInvitation) (*extended.Invitation) {
+	return &extended.Invitation{
+		ID:			invitation.ID,
+		Email:		invitation.Email,
+		PlanID:		invitation.PlanID,
+		MaxUses:	invitation.MaxUses,
+		Uses:		invitation.Uses,
+		ExpiresAt:	invitation.ExpiresAt,
+		CreatedBy:	invitation.CreatedBy,
+		CreatedAt:	invitation.CreatedAt,
+		RevokedAt:	invitation.RevokedAt,
+	}
+}
+
+func	(s *sExtendedServer) CreateInvitation(ctx context.Context, req *extended.CreateInvitationRequest) (*extended.CreateInvitationResponse, error) {
+	code, invitation, err := s.server.CreateInvitation(ctx, req.Email, req.PlanID, req.MaxUses, req.Expiration)
+	if (err != nil) {
+		return nil, err
+	}
+	return &extended.CreateInvitationResponse{Code: code, Invitation: newInvitationMessage(invitation)}, nil
+}
+
+func	(s *sExtendedServer) ListInvitations(ctx context.Context, req *extended.ListInvitationsRequest) (*extended.ListInvitationsResponse, error) {
+	invitations, err := s.server.ListInvitations(ctx, int(req.Limit))
+	if (err != nil) {
+		return nil, err
+	}
+	response := &extended.ListInvitationsResponse{Invitations: []*extended.Invitation{}}
+	for _, invitation := range invitations {
+		response.Invitations = append(response.Invitations, newInvitationMessage(invitation))
+	}
+	return response, nil
+}
+
+func	(s *sExtendedServer) RevokeInvitation(ctx context.Context, req *extended.RevokeInvitationRequest) (*extended.RevokeInvitationResponse, error) {
+	if err := s.server.RevokeInvitation(ctx, req.InvitationID); err != nil {
+		return nil, err
+	}
+	return &extended.RevokeInvitationResponse{}, nil
+}
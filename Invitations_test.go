@@ -0,0 +1,188 @@
+/*******************************************************************************
+** @Author:					Thomas Bouder <Tbouder>
+** @Email:					Tbouder@protonmail.com
+** @Date:					Friday 08 May 2020 - 21:06:52
+** @Filename:				Invitations_test.go
+**
+** @Last modified by:		Tbouder
+** @Last modified time:		Saturday 09 May 2020 - 18:12:40
+*******************************************************************************/
+
+
+package			main
+
+import			"context"
+import			"testing"
+import			"google.golang.org/grpc/codes"
+import			"google.golang.org/grpc/status"
+import			"google.golang.org/grpc/metadata"
+import			"github.com/panghostlin/SDK/Members"
+import			"github.com/panghostlin/Members/Extended"
+
+/******************************************************************************
+**	Sign up through the MembersService, with the invitation code in the
+**	header forwarded by the Proxy
+******************************************************************************/
+func	(c *sExtendedTestClient) signUp(email, code string) (*members.CreateMemberResponse, codes.Code) {
+	ctx := context.Background()
+	if (code != ``) {
+		ctx = metadata.AppendToOutgoingContext(ctx, INVITATION_CODE_HEADER, code)
+	}
+	response, err := members.NewMembersServiceClient(c.conn).CreateMember(ctx, &members.CreateMemberRequest{
+		Email:		email,
+		Password:	TEST_PASSWORD,
+		PublicKey:	`public-key`,
+		PrivateKey:	&members.CryptedPrivate{Key: `private-key`, IV: `private-iv`, Salt: `private-salt`},
+	})
+	return response, status.Code(err)
+}
+
+func	(c *sExtendedTestClient) createInvitation(t *testing.T, request *extended.CreateInvitationRequest) (*extended.CreateInvitationResponse) {
+	response := &extended.CreateInvitationResponse{}
+	if code := c.call(`CreateInvitation`, request, response); code != codes.OK {
+		t.Fatalf("CreateInvitation: expected OK, got %v", code)
+	}
+	return response
+}
+
+func	(c *sExtendedTestClient) invitationUses(t *testing.T, invitationID string) (int64) {
+	for _, invitation := range c.store.invitations {
+		if (invitation.ID == invitationID) {
+			return invitation.Uses
+		}
+	}
+	t.Fatalf("the invitation %s is missing", invitationID)
+	return 0
+}
+
+func	TestRedeemInvitation(t *testing.T) {
+	defer func(mode string) {config.Registration.Mode = mode}(config.Registration.Mode)
+	config.Registration.Mode = REGISTRATION_INVITE
+
+	client := newExtendedTestClient(t)
+	defer client.close()
+	plan := &extended.Plan{}
+	if code := client.call(`CreatePlan`, &extended.Plan{Name: `Family`, StorageQuota: 100}, plan); code != codes.OK {
+		t.Fatalf("CreatePlan: expected OK, got %v", code)
+	}
+
+	t.Run(`a code is required`, func(t *testing.T) {
+		if _, code := client.signUp(`nocode@example.com`, ``); code != codes.InvalidArgument {
+			t.Errorf("expected InvalidArgument, got %v", code)
+		}
+		if _, code := client.signUp(`unknown@example.com`, `UNKNOWNCODE`); code != codes.PermissionDenied {
+			t.Errorf("an unknown code: expected PermissionDenied, got %v", code)
+		}
+	})
+
+	t.Run(`a valid invitation gives its plan`, func(t *testing.T) {
+		invitation := client.createInvitation(t, &extended.CreateInvitationRequest{PlanID: plan.ID})
+		if (invitation.Code == `` || invitation.Invitation.MaxUses != 1 || invitation.Invitation.PlanID != plan.ID) {
+			t.Fatalf("unexpected invitation %+v", invitation)
+		}
+		created, code := client.signUp(`valid@example.com`, invitation.Code)
+		if (code != codes.OK) {
+			t.Fatalf("expected OK, got %v", code)
+		}
+		member, _ := client.store.GetMemberByID(context.Background(), created.GetMemberID())
+		if (member.PlanID != plan.ID) {
+			t.Errorf("expected the plan %s, got %s", plan.ID, member.PlanID)
+		}
+		if uses := client.invitationUses(t, invitation.Invitation.ID); uses != 1 {
+			t.Errorf("expected 1 use, got %d", uses)
+		}
+	})
+
+	t.Run(`an expired invitation is refused`, func(t *testing.T) {
+		invitation := client.createInvitation(t, &extended.CreateInvitationRequest{Expiration: 3600})
+		for _, stored := range client.store.invitations {
+			if (stored.ID == invitation.Invitation.ID) {
+				stored.ExpiresAt = stored.CreatedAt - 1
+			}
+		}
+		if _, code := client.signUp(`expired@example.com`, invitation.Code); code != codes.PermissionDenied {
+			t.Errorf("expected PermissionDenied, got %v", code)
+		}
+		if uses := client.invitationUses(t, invitation.Invitation.ID); uses != 0 {
+			t.Errorf("expected no use, got %d", uses)
+		}
+	})
+
+	t.Run(`a revoked invitation is refused`, func(t *testing.T) {
+		invitation := client.createInvitation(t, &extended.CreateInvitationRequest{})
+		if code := client.call(`RevokeInvitation`, &extended.RevokeInvitationRequest{InvitationID: invitation.Invitation.ID}, &extended.RevokeInvitationResponse{}); code != codes.OK {
+			t.Fatalf("RevokeInvitation: expected OK, got %v", code)
+		}
+		if code := client.call(`RevokeInvitation`, &extended.RevokeInvitationRequest{InvitationID: invitation.Invitation.ID}, &extended.RevokeInvitationResponse{}); code != codes.NotFound {
+			t.Errorf("an already revoked invitation: expected NotFound, got %v", code)
+		}
+		if _, code := client.signUp(`revoked@example.com`, invitation.Code); code != codes.PermissionDenied {
+			t.Errorf("expected PermissionDenied, got %v", code)
+		}
+		if uses := client.invitationUses(t, invitation.Invitation.ID); uses != 0 {
+			t.Errorf("expected no use, got %d", uses)
+		}
+	})
+
+	t.Run(`a used invitation can not be reused`, func(t *testing.T) {
+		invitation := client.createInvitation(t, &extended.CreateInvitationRequest{MaxUses: 2})
+		if _, code := client.signUp(`first@example.com`, invitation.Code); code != codes.OK {
+			t.Fatalf("first use: expected OK, got %v", code)
+		}
+		if _, code := client.signUp(`second@example.com`, invitation.Code); code != codes.OK {
+			t.Fatalf("second use: expected OK, got %v", code)
+		}
+		if _, code := client.signUp(`third@example.com`, invitation.Code); code != codes.PermissionDenied {
+			t.Errorf("third use: expected PermissionDenied, got %v", code)
+		}
+		if uses := client.invitationUses(t, invitation.Invitation.ID); uses != 2 {
+			t.Errorf("expected 2 uses, got %d", uses)
+		}
+		if _, err := client.store.GetMemberByEmail(context.Background(), `third@example.com`); err != ErrMemberNotFound {
+			t.Errorf("the refused member must not be stored, got %v", err)
+		}
+	})
+
+	t.Run(`an invitation is bound to its email`, func(t *testing.T) {
+		invitation := client.createInvitation(t, &extended.CreateInvitationRequest{Email: `Bound@example.com`})
+		if _, code := client.signUp(`other@example.com`, invitation.Code); code != codes.PermissionDenied {
+			t.Errorf("another email: expected PermissionDenied, got %v", code)
+		}
+		if _, code := client.signUp(`bound@example.com`, invitation.Code); code != codes.OK {
+			t.Errorf("the bound email: expected OK, got %v", code)
+		}
+	})
+}
+
+func	TestListInvitations(t *testing.T) {
+	client := newExtendedTestClient(t)
+	defer client.close()
+
+	first := client.createInvitation(t, &extended.CreateInvitationRequest{})
+	second := client.createInvitation(t, &extended.CreateInvitationRequest{Email: `listed@example.com`})
+	if code := client.call(`CreateInvitation`, &extended.CreateInvitationRequest{PlanID: `unknown`}, &extended.CreateInvitationResponse{}); code != codes.NotFound {
+		t.Errorf("an unknown plan: expected NotFound, got %v", code)
+	}
+
+	response := &extended.ListInvitationsResponse{}
+	if code := client.call(`ListInvitations`, &extended.ListInvitationsRequest{}, response); code != codes.OK {
+		t.Fatalf("ListInvitations: expected OK, got %v", code)
+	}
+	if (len(response.Invitations) != 2 || response.Invitations[0].ID != second.Invitation.ID || response.Invitations[1].ID != first.Invitation.ID) {
+		t.Errorf("expected the most recent invitation first, got %+v", response.Invitations)
+	}
+	response = &extended.ListInvitationsResponse{}
+	if code := client.call(`ListInvitations`, &extended.ListInvitationsRequest{Limit: 1}, response); code != codes.OK || len(response.Invitations) != 1 {
+		t.Errorf("expected a single invitation, got %v %+v", code, response.Invitations)
+	}
+}
+
+func	TestInvitationPolicy(t *testing.T) {
+	policy := defaultAuthorizationPolicy()
+	for _, method := range []string{`CreateInvitation`, `ListInvitations`, `RevokeInvitation`} {
+		fullMethod := `/` + EXTENDED_SERVICE_NAME + `/` + method
+		if (!policy.allows(fullMethod, []string{`admin`}) || policy.allows(fullMethod, []string{`proxy`, `pictures`})) {
+			t.Errorf("%s must only be allowed to admin", method)
+		}
+	}
+}
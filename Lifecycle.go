@@ -0,0 +1,107 @@
+/*******************************************************************************
+** @Author:					Thomas Bouder <Tbouder>
+** @Email:					Tbouder@protonmail.com
+** @Date:					Monday 27 April 2020 - 09:48:12
+** @Filename:				Lifecycle.go
+**
+** @Last modified by:		Tbouder
+** @Last modified time:		Saturday 02 May 2020 - 09:36:14
+*******************************************************************************/
+
+
+package			main
+
+import			"os"
+import			"sync"
+import			"time"
+import			"context"
+import			"syscall"
+import			"os/signal"
+import			"google.golang.org/grpc"
+
+/******************************************************************************
+**	On SIGTERM or SIGINT, the health service reports NOT_SERVING, and the
+**	server stops accepting connections and drains the in-flight RPCs for at
+**	most server.shutdownTimeout seconds, before cancelling the remaining
+**	ones. The shutdown hooks are then run, in the
+**	reverse order of their registration, to flush the outboxes before
+**	closing the bridges and the database.
+**	The Docker stop timeout must be greater than the sum of both timeouts.
+******************************************************************************/
+const	DEFAULT_SHUTDOWN_TIMEOUT = 10
+const	SHUTDOWN_HOOKS_TIMEOUT = 5 * time.Second
+
+type	sShutdownHook struct {
+	name	string
+	hook	func(ctx context.Context) error
+}
+
+var		shutdownHooks []sShutdownHook
+var		shutdownHooksMutex sync.Mutex
+
+func	onShutdown(name string, hook func(ctx context.Context) error) {
+	shutdownHooksMutex.Lock()
+	defer shutdownHooksMutex.Unlock()
+	shutdownHooks = append(shutdownHooks, sShutdownHook{name: name, hook: hook})
+}
+
+func	runShutdownHooks(ctx context.Context) {
+	shutdownHooksMutex.Lock()
+	hooks := shutdownHooks
+	shutdownHooks = nil
+	shutdownHooksMutex.Unlock()
+
+	for index := len(hooks) - 1; index >= 0; index-- {
+		if err := hooks[index].hook(ctx); err != nil {
+			logError(ctx, `Shutdown hook failed`, `hook`, hooks[index].name, `error`, err)
+		}
+	}
+}
+
+/******************************************************************************
+**	Serve until a termination signal, or until the server fails, then shut
+**	down. Returns the exit code of the process.
+******************************************************************************/
+func	serveUntilSignal(srv *grpc.Server, serve func() error) (int) {
+	exitCode := 0
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGTERM, syscall.SIGINT)
+	defer signal.Stop(signals)
+
+	served := make(chan error, 1)
+	go func() {served <- serve()}()
+
+	select {
+	case received := <-signals:
+		logWarning(context.Background(), `Shutting down`, `signal`, received.String())
+	case err := <-served:
+		logError(context.Background(), `Failed to serve`, `error`, err)
+		exitCode = 1
+	}
+
+	stopHealth()
+	drainServer(srv, time.Duration(config.Server.ShutdownTimeout) * time.Second)
+
+	ctx, cancel := context.WithTimeout(context.Background(), SHUTDOWN_HOOKS_TIMEOUT)
+	defer cancel()
+	runShutdownHooks(ctx)
+
+	logInfo(context.Background(), `Stopped`)
+	return exitCode
+}
+
+func	drainServer(srv *grpc.Server, timeout time.Duration) {
+	drained := make(chan struct{})
+	go func() {
+		srv.GracefulStop()
+		close(drained)
+	}()
+
+	select {
+	case <-drained:
+	case <-time.After(timeout):
+		logWarning(context.Background(), `The in-flight RPCs did not complete in time, cancelling them`, `timeout`, timeout)
+		srv.Stop()
+		<-drained
+	}
+}
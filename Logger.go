@@ -0,0 +1,302 @@
+/*******************************************************************************
+** @Author:					Thomas Bouder <Tbouder>
+** @Email:					Tbouder@protonmail.com
+** @Date:					Saturday 02 May 2020 - 09:36:14
+** @Filename:				Logger.go
+**
+** @Last modified by:		Tbouder
+** @Last modified time:		Saturday 09 May 2020 - 15:52:18
+*******************************************************************************/
+
+
+package			main
+
+import			"io"
+import			"os"
+import			"fmt"
+import			"sync"
+import			"time"
+import			"regexp"
+import			"context"
+import			"strings"
+import			"crypto/rand"
+import			"encoding/hex"
+import			"encoding/json"
+import			"google.golang.org/grpc"
+import			"google.golang.org/grpc/peer"
+import			"google.golang.org/grpc/status"
+import			"google.golang.org/grpc/metadata"
+import			"go.opentelemetry.io/otel/api/trace"
+
+/******************************************************************************
+**	Every log line is a JSON object with the time, the level, the message
+**	and the fields. The lines logged while handling an RPC also carry the
+**	request ID, the method, the identity of the caller, the member ID once
+**	known and the trace ID. The levels below log.level (debug, info, warning
+**	or error) are dropped.
+**	Nothing sensitive is ever printed : the fields named like a secret
+**	(token, password, key, hash...) are redacted, and the JWTs and the
+**	encoded password hashes are removed from every value and message.
+******************************************************************************/
+const	LOG_DEBUG = `debug`
+const	LOG_INFO = `info`
+const	LOG_WARNING = `warning`
+const	LOG_ERROR = `error`
+const	REDACTED = `[REDACTED]`
+const	REQUEST_ID_HEADER = `x-request-id`
+
+var		logLevels = map[string]int{LOG_DEBUG: 0, LOG_INFO: 1, LOG_WARNING: 2, LOG_ERROR: 3}
+
+type	sLogger struct {
+	mutex	sync.Mutex
+	output	io.Writer
+	level	int
+}
+
+var		logger = &sLogger{output: os.Stdout, level: logLevels[LOG_INFO]}
+
+func	initLogger() {
+	logger.mutex.Lock()
+	defer logger.mutex.Unlock()
+	logger.level = logLevels[config.Log.Level]
+}
+
+/******************************************************************************
+**	Redaction of the secrets, by field name and by value
+******************************************************************************/
+var		secretFieldNames = []string{`token`, `password`, `passwd`, `secret`, `hash`, `cookie`, `salt`, `private`, `pepper`, `authorization`}
+var		secretFieldSegments = map[string]bool{`key`: true, `iv`: true, `jwt`: true}
+var		camelCaseBoundary = regexp.MustCompile(`([a-z0-9])([A-Z])`)
+var		secretValues = []struct{pattern *regexp.Regexp; replacement string}{
+	{regexp.MustCompile(`eyJ[A-Za-z0-9_-]*\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*`), `[REDACTED_JWT]`},
+	{regexp.MustCompile(`\$(argon2id|scrypt|2[aby]|pbkdf2-sha256)\$[^\s"']+`), `[REDACTED_HASH]`},
+	{regexp.MustCompile(`(?i)(bearer|basic)\s+[A-Za-z0-9._~+/=-]+`), `$1 ` + REDACTED},
+}
+
+func	isSecretField(name string) (bool) {
+	name = strings.ToLower(camelCaseBoundary.ReplaceAllString(name, `${1}_${2}`))
+	for _, secret := range secretFieldNames {
+		if (strings.Contains(name, secret)) {
+			return true
+		}
+	}
+	for _, segment := range strings.FieldsFunc(name, func(r rune) bool {return r == '_' || r == '-' || r == '.'}) {
+		if (secretFieldSegments[segment]) {
+			return true
+		}
+	}
+	return false
+}
+
+func	redactValue(value string) (string) {
+	for _, secret := range secretValues {
+		value = secret.pattern.ReplaceAllString(value, secret.replacement)
+	}
+	return value
+}
+
+func	redactField(name string, value interface{}) (interface{}) {
+	if (isSecretField(name)) {
+		return REDACTED
+	}
+	switch typed := value.(type) {
+	case nil:
+		return nil
+	case string:
+		return redactValue(typed)
+	case error:
+		return redactValue(typed.Error())
+	case time.Duration:
+		return typed.String()
+	case fmt.Stringer:
+		return redactValue(typed.String())
+	case bool, int, int32, int64, uint, uint32, uint64, float32, float64:
+		return typed
+	}
+	return redactValue(fmt.Sprint(value))
+}
+
+/******************************************************************************
+**	Fields of the RPC being handled, stored in its context. The handlers add
+**	the member ID once they know it.
+******************************************************************************/
+type	sLogContextKey struct {}
+type	sLogContext struct {
+	mutex	sync.Mutex
+	keys	[]string
+	values	map[string]interface{}
+}
+
+func	withLogContext(ctx context.Context) (context.Context, *sLogContext) {
+	logContext := &sLogContext{values: map[string]interface{}{}}
+	return context.WithValue(ctx, sLogContextKey{}, logContext), logContext
+}
+
+func	setLogField(ctx context.Context, name string, value interface{}) {
+	logContext, ok := ctx.Value(sLogContextKey{}).(*sLogContext)
+	if (!ok) {
+		return
+	}
+	logContext.mutex.Lock()
+	defer logContext.mutex.Unlock()
+	if _, exists := logContext.values[name]; !exists {
+		logContext.keys = append(logContext.keys, name)
+	}
+	logContext.values[name] = value
+}
+
+func	requestIDFromContext(ctx context.Context) (string) {
+	if logContext, ok := ctx.Value(sLogContextKey{}).(*sLogContext); ok {
+		logContext.mutex.Lock()
+		defer logContext.mutex.Unlock()
+		requestID, _ := logContext.values[`request_id`].(string)
+		return requestID
+	}
+	return ``
+}
+
+/******************************************************************************
+**	Write a log line. keyValues alternates the names and the values of the
+**	fields.
+******************************************************************************/
+func	(l *sLogger) write(ctx context.Context, level string, message string, keyValues []interface{}) {
+	if (logLevels[level] < l.level) {
+		return
+	}
+
+	var	builder strings.Builder
+	appendField := func(name string, value interface{}) {
+		encodedName, _ := json.Marshal(name)
+		encodedValue, err := json.Marshal(redactField(name, value))
+		if (err != nil) {
+			encodedValue, _ = json.Marshal(REDACTED)
+		}
+		builder.WriteString(`,`)
+		builder.Write(encodedName)
+		builder.WriteString(`:`)
+		builder.Write(encodedValue)
+	}
+
+	encodedTime, _ := json.Marshal(time.Now().UTC().Format(time.RFC3339Nano))
+	encodedMessage, _ := json.Marshal(redactValue(message))
+	builder.WriteString(`{"time":` + string(encodedTime) + `,"level":"` + level + `","msg":` + string(encodedMessage))
+
+	if (ctx != nil) {
+		if logContext, ok := ctx.Value(sLogContextKey{}).(*sLogContext); ok {
+			logContext.mutex.Lock()
+			for _, name := range logContext.keys {
+				appendField(name, logContext.values[name])
+			}
+			logContext.mutex.Unlock()
+		}
+		if spanContext := trace.SpanFromContext(ctx).SpanContext(); spanContext.IsValid() {
+			appendField(`trace_id`, spanContext.TraceID.String())
+		}
+	}
+	for index := 0; index < len(keyValues); index += 2 {
+		name, ok := keyValues[index].(string)
+		if (!ok) {
+			name = fmt.Sprint(keyValues[index])
+		}
+		if (index + 1 < len(keyValues)) {
+			appendField(name, keyValues[index + 1])
+		} else {
+			appendField(name, nil)
+		}
+	}
+	builder.WriteString("}\n")
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	io.WriteString(l.output, builder.String())
+}
+
+func	logDebug(ctx context.Context, message string, keyValues ...interface{}) {
+	logger.write(ctx, LOG_DEBUG, message, keyValues)
+}
+func	logInfo(ctx context.Context, message string, keyValues ...interface{}) {
+	logger.write(ctx, LOG_INFO, message, keyValues)
+}
+func	logWarning(ctx context.Context, message string, keyValues ...interface{}) {
+	logger.write(ctx, LOG_WARNING, message, keyValues)
+}
+func	logError(ctx context.Context, message string, keyValues ...interface{}) {
+	logger.write(ctx, LOG_ERROR, message, keyValues)
+}
+
+/******************************************************************************
+**	Log an error and stop the process, for the failures on boot
+******************************************************************************/
+func	logFatal(message string, keyValues ...interface{}) {
+	logger.write(context.Background(), LOG_ERROR, message, keyValues)
+	os.Exit(1)
+}
+
+/******************************************************************************
+**	The request ID is taken from the x-request-id metadata when the caller
+**	sets a sane one, or generated, and sent back in the response headers
+******************************************************************************/
+var		requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+func	newRequestID() (string) {
+	buffer := make([]byte, 8)
+	if _, err := rand.Read(buffer); err != nil {
+		return `unknown`
+	}
+	return hex.EncodeToString(buffer)
+}
+
+func	incomingRequestID(ctx context.Context) (string) {
+	if incoming, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := incoming.Get(REQUEST_ID_HEADER); len(values) > 0 && requestIDPattern.MatchString(values[0]) {
+			return values[0]
+		}
+	}
+	return newRequestID()
+}
+
+func	callerName(ctx context.Context) (string) {
+	if identities := callerIdentities(ctx); len(identities) > 0 {
+		return identities[0]
+	}
+	if caller, ok := peer.FromContext(ctx); ok {
+		return caller.Addr.String()
+	}
+	return ``
+}
+
+/******************************************************************************
+**	Unary interceptor preparing the log fields of the RPC, and logging its
+**	outcome. The internal errors are logged as errors, the other failures
+**	are expected (wrong password, missing member...) and logged as info.
+******************************************************************************/
+func	loggingInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	start := time.Now()
+	ctx, _ = withLogContext(ctx)
+	requestID := incomingRequestID(ctx)
+	setLogField(ctx, `request_id`, requestID)
+	setLogField(ctx, `method`, info.FullMethod)
+	setLogField(ctx, `peer`, callerName(ctx))
+	grpc.SetHeader(ctx, metadata.Pairs(REQUEST_ID_HEADER, requestID))
+
+	resp, err := handler(ctx, req)
+
+	code := status.Code(err)
+	level := LOG_INFO
+	switch code.String() {
+	case `Internal`, `Unknown`, `DataLoss`, `Unavailable`:
+		level = LOG_ERROR
+	}
+	logger.write(ctx, level, `rpc completed`, []interface{}{`code`, code.String(), `duration_ms`, time.Since(start).Milliseconds()})
+	return resp, err
+}
+
+/******************************************************************************
+**	Client interceptor forwarding the request ID to the bridged services
+******************************************************************************/
+func	requestIDClientInterceptor(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) (error) {
+	if requestID := requestIDFromContext(ctx); requestID != `` {
+		ctx = metadata.AppendToOutgoingContext(ctx, REQUEST_ID_HEADER, requestID)
+	}
+	return invoker(ctx, method, req, reply, cc, opts...)
+}
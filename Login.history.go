@@ -0,0 +1,168 @@
+/*******************************************************************************
+** @Author:					Thomas Bouder <Tbouder>
+** @Email:					Tbouder@protonmail.com
+** @Date:					Monday 04 May 2020 - 16:21:09
+** @Filename:				Login.history.go
+**
+** @Last modified by:		Tbouder
+** @Last modified time:		Saturday 09 May 2020 - 18:12:40
+*******************************************************************************/
+
+
+package			main
+
+import			"time"
+import			"context"
+import			"net/url"
+import			"crypto/sha256"
+import			"encoding/hex"
+import			"encoding/base64"
+import			"github.com/panghostlin/Members/Extended"
+
+/******************************************************************************
+**	Every successful login is recorded with the device it came from. When a
+**	member logs in from a device never seen for the account, an email is
+**	sent with a link to report the login : the report revokes all the
+**	sessions and requires a password change. The link carries a single-use
+**	token, of which only the hash is stored : once reported, the same token
+**	authorizes the password change.
+******************************************************************************/
+const	LOGIN_ALERT_EXPIRATION = 7 * 24 * time.Hour
+const	DEFAULT_LOGIN_HISTORY_SIZE = 20
+const	MAX_LOGIN_HISTORY_SIZE = 100
+const	UNRECOGNIZED_LOGIN = `unrecognized_login`
+
+func	deviceFingerprint(ip, userAgent string) (string) {
+	hash := sha256.Sum256([]byte(ip + "\n" + userAgent))
+	return hex.EncodeToString(hash[:])
+}
+
+func	hashAlertToken(token string) (string) {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
+
+/******************************************************************************
+**	Record the login of the member. The new devices are notified by email
+**	if notify is set : the device used to sign up is simply remembered. A
+**	failure is logged, but never fails the login.
+******************************************************************************/
+func	(s *server) recordLogin(ctx context.Context, member *sMember, notify bool) {
+	now := time.Now()
+	login := &sLogin{
+		MemberID:		member.ID,
+		IP:				auditClientIP(ctx),
+		UserAgent:		auditUserAgent(ctx),
+		CreatedAt:		now.Unix(),
+	}
+	login.Fingerprint = deviceFingerprint(login.IP, login.UserAgent)
+
+	var	alertToken string
+	if (notify && mailEnabled()) {
+		nonce, err := generateNonce(32)
+		if (err != nil) {
+			logError(ctx, `Could not generate the login alert`, `error`, err)
+			notify = false
+		} else {
+			alertToken = base64.RawURLEncoding.EncodeToString(nonce)
+			login.AlertTokenHash = hashAlertToken(alertToken)
+			login.AlertExp = now.Add(LOGIN_ALERT_EXPIRATION).Unix()
+		}
+	}
+
+	if err := s.loginStore.RecordLogin(ctx, login); err != nil {
+		logError(ctx, `Could not record the login`, `error`, err)
+		return
+	}
+	if (login.NewDevice && notify && alertToken != ``) {
+		queueMail(ctx, newDeviceMail(member.Email, login, alertToken))
+	}
+}
+
+func	newDeviceMail(email string, login *sLogin, alertToken string) (sMail) {
+	link, err := url.Parse(config.Mail.UnrecognizedLoginURL)
+	if (err != nil) {
+		link = &url.URL{}
+	}
+	query := link.Query()
+	query.Set(`token`, alertToken)
+	link.RawQuery = query.Encode()
+
+	return sMail{
+		to:			email,
+		subject:	`New login to your Panghostlin account`,
+		body:		"Your account was accessed from a new device.\n\n" +
+					"Date: " + time.Unix(login.CreatedAt, 0).UTC().Format(time.RFC1123) + "\n" +
+					"IP address: " + login.IP + "\n" +
+					"Device: " + login.UserAgent + "\n\n" +
+					"If this was you, you can ignore this email.\n" +
+					"If this wasn't you, follow this link to log out every session and change your password :\n" +
+					link.String() + "\n",
+	}
+}
+
+/******************************************************************************
+**	List the recent logins of the member owning the access token, the most
+**	recent first
+******************************************************************************/
+func	(s *server) GetLoginHistory(ctx context.Context, accessToken string, limit int) ([]*sLogin, error) {
+	memberID, err := s.authenticateAccessToken(ctx, accessToken)
+	if (err != nil) {
+		return nil, err
+	}
+	if (limit <= 0) {
+		limit = DEFAULT_LOGIN_HISTORY_SIZE
+	} else if (limit > MAX_LOGIN_HISTORY_SIZE) {
+		limit = MAX_LOGIN_HISTORY_SIZE
+	}
+	return s.loginStore.ListLogins(ctx, memberID, limit)
+}
+
+/******************************************************************************
+**	"This wasn't me" : consume the token of the alert, revoke the sessions
+**	and require a password change, with ChangePassword and the same token
+******************************************************************************/
+func	(s *server) ReportUnrecognizedLogin(ctx context.Context, alertToken string) (error) {
+	if (alertToken == ``) {
+		return errInvalidArgument(`the token is required`, fieldViolation(`token`, `TOKEN_REQUIRED`))
+	}
+	memberID, err := s.loginStore.ReportUnrecognizedLogin(ctx, hashAlertToken(alertToken), time.Now().Unix())
+	if (err == ErrLoginAlertNotFound) {
+		return errNotFound(`login alert`, ``)
+	} else if (err != nil) {
+		return err
+	}
+	setLogField(ctx, `member_id`, memberID)
+	logWarning(ctx, `Login reported as unrecognized, the sessions are revoked`)
+	recordAuditEvent(ctx, AUDIT_SESSION_REVOKED, memberID, memberID, UNRECOGNIZED_LOGIN)
+	return nil
+}
+
+/******************************************************************************
+**	The GetLoginHistory and ReportUnrecognizedLogin RPCs of the
+**	MembersExtendedService. The fingerprints and the alerts are not exposed.
+******************************************************************************/
+func	(s *sExtendedServer) GetLoginHistory(ctx context.Context, req *extended.GetLoginHistoryRequest) (*extended.GetLoginHistoryResponse, error) {
+	logins, err := s.server.GetLoginHistory(ctx, req.AccessToken, int(req.Limit))
+	if (err != nil) {
+		return nil, err
+	}
+	response := &extended.GetLoginHistoryResponse{Logins: []*extended.Login{}}
+	for _, login := range logins {
+		response.Logins = append(response.Logins, &extended.Login{
+			ID:			login.ID,
+			IP:			login.IP,
+			UserAgent:	login.UserAgent,
+			NewDevice:	login.NewDevice,
+			CreatedAt:	login.CreatedAt,
+		})
+	}
+	return response, nil
+}
+
+func	(s *sExtendedServer) ReportUnrecognizedLogin(ctx context.Context, req *extended.ReportUnrecognizedLoginRequest) (*extended.ReportUnrecognizedLoginResponse, error) {
+	if err := s.server.ReportUnrecognizedLogin(ctx, req.Token); err != nil {
+		return nil, err
+	}
+	return &extended.ReportUnrecognizedLoginResponse{}, nil
+}
@@ -0,0 +1,160 @@
+/*******************************************************************************
+** @Author:					Thomas Bouder <Tbouder>
+** @Email:					Tbouder@protonmail.com
+** @Date:					Friday 08 May 2020 - 18:02:37
+** @Filename:				Login.history_test.go
+**
+** @Last modified by:		Tbouder
+** @Last modified time:		Saturday 09 May 2020 - 18:12:40
+*******************************************************************************/
+
+
+package			main
+
+import			"time"
+import			"context"
+import			"testing"
+import			"google.golang.org/grpc/codes"
+import			"github.com/panghostlin/SDK/Members"
+import			"github.com/panghostlin/Members/Extended"
+
+const	TEST_NEW_PASSWORD = `Zq7!mR4v-xxB`
+
+/******************************************************************************
+**	A reported login locks the member out until the password is changed with
+**	the token of the alert, the current password alone is refused, and the
+**	new password opens a new session
+******************************************************************************/
+func	TestReportUnrecognizedLoginAndChangePassword(t *testing.T) {
+	client := newExtendedTestClient(t)
+	defer client.close()
+	ctx := context.Background()
+
+	created := createTestMember(t, client.service, `locked@example.com`)
+	client.store.RecordLogin(ctx, &sLogin{
+		MemberID:		created.MemberID,
+		IP:				`203.0.113.7`,
+		Fingerprint:	deviceFingerprint(`203.0.113.7`, `unknown`),
+		CreatedAt:		time.Now().Unix(),
+		AlertTokenHash:	hashAlertToken(`alert-token`),
+		AlertExp:		time.Now().Add(time.Hour).Unix(),
+	})
+
+	history := &extended.GetLoginHistoryResponse{}
+	if code := client.call(`GetLoginHistory`, &extended.GetLoginHistoryRequest{AccessToken: created.AccessToken.Value}, history); code != codes.OK {
+		t.Fatalf("GetLoginHistory: expected OK, got %v", code)
+	}
+	if (len(history.Logins) != 2 || history.Logins[0].IP != `203.0.113.7` || !history.Logins[0].NewDevice) {
+		t.Fatalf("unexpected history %v", history)
+	}
+	for _, accessToken := range []string{``, `forged`, expiredAccessToken(t, created.MemberID)} {
+		if code := client.call(`GetLoginHistory`, &extended.GetLoginHistoryRequest{AccessToken: accessToken}, &extended.GetLoginHistoryResponse{}); code != codes.Unauthenticated {
+			t.Errorf("GetLoginHistory(%q): expected Unauthenticated, got %v", accessToken, code)
+		}
+	}
+
+	if code := client.call(`ReportUnrecognizedLogin`, &extended.ReportUnrecognizedLoginRequest{Token: `alert-token`}, &extended.ReportUnrecognizedLoginResponse{}); code != codes.OK {
+		t.Fatalf("ReportUnrecognizedLogin: expected OK, got %v", code)
+	}
+	if code := client.call(`ReportUnrecognizedLogin`, &extended.ReportUnrecognizedLoginRequest{Token: `alert-token`}, &extended.ReportUnrecognizedLoginResponse{}); code != codes.NotFound {
+		t.Errorf("a reused alert token: expected NotFound, got %v", code)
+	}
+	expectSession(t, client.store, created.MemberID, ``)
+	if code := client.call(`GetLoginHistory`, &extended.GetLoginHistoryRequest{AccessToken: created.AccessToken.Value}, &extended.GetLoginHistoryResponse{}); code != codes.Unauthenticated {
+		t.Errorf("GetLoginHistory with a revoked access token: expected Unauthenticated, got %v", code)
+	}
+
+	_, err := client.service.LoginMember(ctx, &members.LoginMemberRequest{Email: `locked@example.com`, Password: TEST_PASSWORD})
+	if code := statusCode(err); code != codes.FailedPrecondition {
+		t.Fatalf("LoginMember: expected FailedPrecondition, got %v", err)
+	}
+
+	changes := []struct {
+		request	*extended.ChangePasswordRequest
+		code	codes.Code
+	}{
+		{&extended.ChangePasswordRequest{Email: `locked@example.com`, Password: `wrong-` + TEST_PASSWORD, NewPassword: TEST_NEW_PASSWORD, PrivateKey: `k`, PrivateKeyIV: `iv`, PrivateKeySalt: `salt`, Token: `alert-token`}, codes.Unauthenticated},
+		{&extended.ChangePasswordRequest{Email: `locked@example.com`, Password: TEST_PASSWORD, NewPassword: TEST_NEW_PASSWORD, PrivateKey: `k`, PrivateKeyIV: `iv`, PrivateKeySalt: `salt`}, codes.PermissionDenied},
+		{&extended.ChangePasswordRequest{Email: `locked@example.com`, Password: TEST_PASSWORD, NewPassword: TEST_NEW_PASSWORD, PrivateKey: `k`, PrivateKeyIV: `iv`, PrivateKeySalt: `salt`, Token: `other-token`}, codes.PermissionDenied},
+		{&extended.ChangePasswordRequest{Email: `locked@example.com`, Password: TEST_PASSWORD, NewPassword: TEST_PASSWORD, PrivateKey: `k`, PrivateKeyIV: `iv`, PrivateKeySalt: `salt`, Token: `alert-token`}, codes.InvalidArgument},
+		{&extended.ChangePasswordRequest{Email: `locked@example.com`, Password: TEST_PASSWORD, NewPassword: TEST_NEW_PASSWORD, Token: `alert-token`}, codes.InvalidArgument},
+		{&extended.ChangePasswordRequest{Email: `locked@example.com`, Password: TEST_PASSWORD, NewPassword: `short`, PrivateKey: `k`, PrivateKeyIV: `iv`, PrivateKeySalt: `salt`, Token: `alert-token`}, codes.InvalidArgument},
+	}
+	for _, change := range changes {
+		if code := client.call(`ChangePassword`, change.request, &extended.ChangePasswordResponse{}); code != change.code {
+			t.Errorf("ChangePassword(%v): expected %v, got %v", change.request, change.code, code)
+		}
+	}
+	if member, _ := client.store.GetMemberByID(ctx, created.MemberID); !member.PasswordChangeRequired || member.PrivateKey != `private-key` {
+		t.Fatalf("a refused change updated the member %+v", member)
+	}
+
+	changed := &extended.ChangePasswordResponse{}
+	request := &extended.ChangePasswordRequest{Email: `locked@example.com`, Password: TEST_PASSWORD, NewPassword: TEST_NEW_PASSWORD, PrivateKey: `new-key`, PrivateKeyIV: `new-iv`, PrivateKeySalt: `new-salt`, Token: `alert-token`}
+	if code := client.call(`ChangePassword`, request, changed); code != codes.OK {
+		t.Fatalf("ChangePassword: expected OK, got %v", code)
+	}
+	member, _ := client.store.GetMemberByID(ctx, created.MemberID)
+	if (member.PasswordChangeRequired || member.PasswordChangeTokenHash != `` || member.PrivateKey != `new-key` || member.PrivateKeyIV != `new-iv` || member.PrivateKeySalt != `new-salt`) {
+		t.Fatalf("unexpected stored member %+v", member)
+	}
+	expectSession(t, client.store, created.MemberID, changed.AccessToken)
+
+	if _, err := client.service.LoginMember(ctx, &members.LoginMemberRequest{Email: `locked@example.com`, Password: TEST_PASSWORD}); statusCode(err) != codes.Unauthenticated {
+		t.Errorf("the previous password: expected Unauthenticated, got %v", err)
+	}
+	login, err := client.service.LoginMember(ctx, &members.LoginMemberRequest{Email: `locked@example.com`, Password: TEST_NEW_PASSWORD})
+	if (err != nil || login.Keys.PrivateKey != `new-key`) {
+		t.Fatalf("the new password: unexpected login %v (%v)", login, err)
+	}
+}
+
+/******************************************************************************
+**	The token of the reported alert authorizes a single password change, on
+**	every store
+******************************************************************************/
+func	TestPasswordChangeToken(t *testing.T) {
+	for _, backend := range serviceStores {
+		t.Run(backend.name, func(t *testing.T) {
+			store, close := backend.open(t)
+			defer close()
+			s := newServerWithStore(store)
+			ctx := context.Background()
+
+			created := createTestMember(t, s, `reported@example.com`)
+			store.RecordLogin(ctx, &sLogin{
+				MemberID:		created.MemberID,
+				Fingerprint:	deviceFingerprint(`203.0.113.7`, `unknown`),
+				CreatedAt:		time.Now().Unix(),
+				AlertTokenHash:	hashAlertToken(`alert-token`),
+				AlertExp:		time.Now().Add(time.Hour).Unix(),
+			})
+			if err := s.ReportUnrecognizedLogin(ctx, `alert-token`); err != nil {
+				t.Fatalf("ReportUnrecognizedLogin: %v", err)
+			}
+			member, err := store.GetMemberByID(ctx, created.MemberID)
+			if (err != nil || !member.PasswordChangeRequired || member.PasswordChangeTokenHash != hashAlertToken(`alert-token`)) {
+				t.Fatalf("expected the token of the alert on the member, got %+v (%v)", member, err)
+			}
+
+			request := &extended.ChangePasswordRequest{Email: `reported@example.com`, Password: TEST_PASSWORD, NewPassword: TEST_NEW_PASSWORD, PrivateKey: `k`, PrivateKeyIV: `iv`, PrivateKeySalt: `salt`}
+			if _, err := s.ChangePassword(ctx, request); statusCode(err) != codes.PermissionDenied {
+				t.Errorf("the current password alone: expected PermissionDenied, got %v", err)
+			}
+
+			stale := *member
+			stale.PasswordChangeTokenHash = hashAlertToken(`used-token`)
+			if err := store.ChangePassword(ctx, &stale, &sSession{}); err != ErrMemberNotFound {
+				t.Errorf("a change with a used token: expected ErrMemberNotFound, got %v", err)
+			}
+
+			request.Token = `alert-token`
+			if _, err := s.ChangePassword(ctx, request); err != nil {
+				t.Fatalf("ChangePassword with the token: %v", err)
+			}
+			if member, _ := store.GetMemberByID(ctx, created.MemberID); member.PasswordChangeRequired || member.PasswordChangeTokenHash != `` {
+				t.Errorf("the token must be consumed by the change, got %+v", member)
+			}
+		})
+	}
+}
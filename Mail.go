@@ -0,0 +1,151 @@
+/*******************************************************************************
+** @Author:					Thomas Bouder <Tbouder>
+** @Email:					Tbouder@protonmail.com
+** @Date:					Monday 04 May 2020 - 16:21:09
+** @Filename:				Mail.go
+**
+** @Last modified by:		Tbouder
+** @Last modified time:		Monday 04 May 2020 - 16:21:09
+*******************************************************************************/
+
+
+package			main
+
+import			"fmt"
+import			"sync"
+import			"time"
+import			"errors"
+import			"context"
+import			"strings"
+import			"net/mail"
+import			"net/smtp"
+import			"github.com/prometheus/client_golang/prometheus"
+import			"github.com/prometheus/client_golang/prometheus/promauto"
+
+/******************************************************************************
+**	The emails to the members are sent through the SMTP server set in
+**	mail.host, in the background : the RPCs never wait for the SMTP server.
+**	Without mail.host, no email is sent. The emails still queued on
+**	shutdown are sent, within the shutdown timeout.
+******************************************************************************/
+const	DEFAULT_MAIL_PORT = `587`
+const	MAIL_OUTBOX_SIZE = 256
+
+type	sMail struct {
+	to		string
+	subject	string
+	body	string
+}
+
+type	sMailOutbox struct {
+	mutex	sync.Mutex
+	closed	bool
+	mails	chan sMail
+	done	chan struct{}
+}
+
+var		mailOutbox *sMailOutbox
+var		sendMail = smtp.SendMail
+var		mailsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name:		`members_mails_total`,
+	Help:		`Emails to the members, by result : sent, failed or dropped.`,
+}, []string{`result`})
+
+func	startMailOutbox() (*sMailOutbox) {
+	if (config.Mail.Host == ``) {
+		logInfo(context.Background(), `No mail.host, the emails are disabled`)
+		return nil
+	}
+	outbox := &sMailOutbox{
+		mails:	make(chan sMail, MAIL_OUTBOX_SIZE),
+		done:	make(chan struct{}),
+	}
+	go outbox.run()
+	onShutdown(`mail`, outbox.shutdown)
+	return outbox
+}
+
+func	mailEnabled() (bool) {
+	return mailOutbox != nil
+}
+
+/******************************************************************************
+**	Queue an email. It is dropped if the outbox is full or closed.
+******************************************************************************/
+func	queueMail(ctx context.Context, message sMail) {
+	if (mailOutbox == nil) {
+		return
+	}
+	mailOutbox.mutex.Lock()
+	defer mailOutbox.mutex.Unlock()
+
+	if (!mailOutbox.closed) {
+		select {
+		case mailOutbox.mails <- message:
+			return
+		default:
+		}
+	}
+	mailsTotal.WithLabelValues(`dropped`).Inc()
+	logError(ctx, `Email dropped, the outbox is full`, `subject`, message.subject)
+}
+
+func	(o *sMailOutbox) run() {
+	defer close(o.done)
+	for message := range o.mails {
+		if err := deliverMail(message); err != nil {
+			mailsTotal.WithLabelValues(`failed`).Inc()
+			logError(context.Background(), `Could not send the email`, `subject`, message.subject, `error`, err)
+		} else {
+			mailsTotal.WithLabelValues(`sent`).Inc()
+		}
+	}
+}
+
+func	(o *sMailOutbox) shutdown(ctx context.Context) (error) {
+	o.mutex.Lock()
+	o.closed = true
+	close(o.mails)
+	o.mutex.Unlock()
+
+	select {
+	case <-o.done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("%d emails not sent: %v", len(o.mails), ctx.Err())
+	}
+}
+
+/******************************************************************************
+**	Send a plain text email. The recipient and the subject are checked so
+**	that no header can be injected.
+******************************************************************************/
+func	deliverMail(message sMail) (error) {
+	from, err := mail.ParseAddress(config.Mail.From)
+	if (err != nil) {
+		return err
+	}
+	to, err := mail.ParseAddress(message.to)
+	if (err != nil) {
+		return err
+	}
+	if (strings.ContainsAny(message.subject, "\r\n")) {
+		return errors.New("invalid subject")
+	}
+
+	var	auth smtp.Auth
+	if (config.Mail.Username != ``) {
+		auth = smtp.PlainAuth(``, config.Mail.Username, config.Mail.Password, config.Mail.Host)
+	}
+	body := strings.Join([]string{
+		`From: ` + from.String(),
+		`To: ` + to.String(),
+		`Subject: ` + message.subject,
+		`Date: ` + time.Now().Format(time.RFC1123Z),
+		`MIME-Version: 1.0`,
+		`Content-Type: text/plain; charset=UTF-8`,
+		``,
+		strings.Replace(message.body, "\n", "\r\n", -1),
+	}, "\r\n")
+	return sendMail(config.Mail.Host + `:` + config.Mail.Port, auth, from.Address, []string{to.Address}, []byte(body))
+}
@@ -0,0 +1,144 @@
+/*******************************************************************************
+** @Author:					Thomas Bouder <Tbouder>
+** @Email:					Tbouder@protonmail.com
+** @Date:					Wednesday 29 April 2020 - 16:48:20
+** @Filename:				Metrics.go
+**
+** @Last modified by:		Tbouder
+** @Last modified time:		Saturday 09 May 2020 - 16:47:10
+*******************************************************************************/
+
+
+package			main
+
+import			"time"
+import			"context"
+import			"net/http"
+import			"google.golang.org/grpc"
+import			"github.com/prometheus/client_golang/prometheus"
+import			"github.com/prometheus/client_golang/prometheus/promauto"
+import			"github.com/prometheus/client_golang/prometheus/promhttp"
+import			grpc_prometheus "github.com/grpc-ecosystem/go-grpc-prometheus"
+
+/******************************************************************************
+**	The Prometheus metrics are served on /metrics, on metrics.port (empty
+**	to disable the listener). Along with the gRPC metrics per method, the
+**	service exports the logins and the access token checks by result, the
+**	hashing durations, the database latency, and the number of members and
+**	of active sessions, refreshed every STORE_GAUGES_INTERVAL.
+******************************************************************************/
+const	DEFAULT_METRICS_PORT = `9010`
+const	STORE_GAUGES_INTERVAL = 30 * time.Second
+
+var		grpcMetrics = grpc_prometheus.DefaultServerMetrics
+
+var		loginsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name:		`members_logins_total`,
+	Help:		`Login attempts, by result (success or failure) and reason.`,
+}, []string{`result`, `reason`})
+var		accessTokenChecksTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name:		`members_access_token_checks_total`,
+	Help:		`Access token checks, by result (valid, refreshed, invalid, expired, revoked or error).`,
+}, []string{`result`})
+var		passwordHashDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+	Name:		`members_password_hash_duration_seconds`,
+	Help:		`Duration of the password hashes and verifications, by algorithm.`,
+	Buckets:	prometheus.ExponentialBuckets(0.005, 2, 12),
+}, []string{`algorithm`, `operation`})
+var		membersGauge = promauto.NewGauge(prometheus.GaugeOpts{
+	Name:		`members_members`,
+	Help:		`Number of registered members.`,
+})
+var		activeSessionsGauge = promauto.NewGauge(prometheus.GaugeOpts{
+	Name:		`members_active_sessions`,
+	Help:		`Number of sessions whose refresh token has not expired.`,
+})
+
+/******************************************************************************
+**	Reasons of the login attempts. Only `success` is a successful login.
+******************************************************************************/
+const	LOGIN_SUCCESS = `success`
+const	LOGIN_UNKNOWN_EMAIL = `unknown_email`
+const	LOGIN_WRONG_PASSWORD = `wrong_password`
+const	LOGIN_HASH_POOL_EXHAUSTED = `hash_pool_exhausted`
+const	LOGIN_PASSWORD_CHANGE_REQUIRED = `password_change_required`
+const	LOGIN_INVALID_CHANGE_TOKEN = `invalid_change_token`
+const	LOGIN_ERROR = `error`
+
+func	countLogin(reason string) {
+	result := `failure`
+	if (reason == LOGIN_SUCCESS) {
+		result = `success`
+	}
+	loginsTotal.WithLabelValues(result, reason).Inc()
+}
+
+/******************************************************************************
+**	A missing member while checking a token is an invalid token, any other
+**	error is an internal error
+******************************************************************************/
+func	accessTokenErrorResult(err error) (string) {
+	if (err == ErrMemberNotFound) {
+		return `invalid`
+	}
+	return `error`
+}
+
+func	observePasswordHash(algorithm, operation string, start time.Time) {
+	passwordHashDuration.WithLabelValues(algorithm, operation).Observe(time.Since(start).Seconds())
+}
+
+func	updateStoreGauges(memberStore MemberStore, sessionStore SessionStore) {
+	ctx, cancel := context.WithTimeout(context.Background(), HEALTH_CHECK_TIMEOUT)
+	defer cancel()
+
+	if count, err := memberStore.CountMembers(ctx); err == nil {
+		membersGauge.Set(float64(count))
+	}
+	if count, err := sessionStore.CountActiveSessions(ctx, time.Now().Unix()); err == nil {
+		activeSessionsGauge.Set(float64(count))
+	}
+}
+
+func	watchStoreGauges(memberStore MemberStore, sessionStore SessionStore, stop chan struct{}) {
+	ticker := time.NewTicker(STORE_GAUGES_INTERVAL)
+	defer ticker.Stop()
+
+	for {
+		updateStoreGauges(memberStore, sessionStore)
+		select {
+		case <-ticker.C:
+		case <-stop:
+			return
+		}
+	}
+}
+
+/******************************************************************************
+**	Initialize the gRPC metrics of the registered methods, and serve the
+**	metrics until the shutdown
+******************************************************************************/
+func	serveMetrics(srv *grpc.Server, service *server) {
+	grpc_prometheus.EnableHandlingTimeHistogram()
+	grpcMetrics.InitializeMetrics(srv)
+	if (config.Metrics.Port == ``) {
+		return
+	}
+
+	stop := make(chan struct{})
+	go watchStoreGauges(service.memberStore, service.sessionStore, stop)
+
+	mux := http.NewServeMux()
+	mux.Handle(`/metrics`, promhttp.Handler())
+	httpServer := &http.Server{Addr: `:` + config.Metrics.Port, Handler: mux}
+	go func() {
+		if err := httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			logError(context.Background(), `Failed to serve the metrics`, `error`, err)
+		}
+	}()
+	onShutdown(`metrics`, func(ctx context.Context) error {
+		close(stop)
+		return httpServer.Shutdown(ctx)
+	})
+	logInfo(context.Background(), `Serving the metrics`, `port`, config.Metrics.Port)
+}
@@ -0,0 +1,601 @@
+/*******************************************************************************
+** @Author:					Thomas Bouder <Tbouder>
+** @Email:					Tbouder@protonmail.com
+** @Date:					Saturday 11 April 2020 - 10:33:05
+** @Filename:				Migrations.go
+**
+** @Last modified by:		Tbouder
+** @Last modified time:		Saturday 09 May 2020 - 16:47:10
+*******************************************************************************/
+
+package			main
+
+import			"fmt"
+import			"errors"
+import			"context"
+import			"database/sql"
+
+/******************************************************************************
+**	The schema of the database is managed by versioned migrations, applied
+**	in order and recorded in the schema_migrations table. A migration should
+**	never be modified once released : any schema change is a new migration
+**	appended to the list, with the `up` script and the `down` script to
+**	revert it, for Postgre and for SQLite. SQLite has no uuid-ossp and no
+**	plpgsql : the UUIDs are generated by the store, and the emails are
+**	lowercased by plain SQL triggers.
+******************************************************************************/
+type	sMigration struct {
+	version		int64
+	name		string
+	up			string
+	down		string
+	sqliteUp	string
+	sqliteDown	string
+}
+
+func	(m sMigration) script(isUp bool) (string) {
+	if (databaseDriver == DRIVER_SQLITE) {
+		if (isUp) {
+			return m.sqliteUp
+		}
+		return m.sqliteDown
+	}
+	if (isUp) {
+		return m.up
+	}
+	return m.down
+}
+
+var		migrations = []sMigration{
+	{
+		version:	1,
+		name:		`create_members`,
+		up:			`
+			CREATE extension if not exists "uuid-ossp";
+			CREATE TABLE if not exists members(
+				ID uuid NOT NULL DEFAULT uuid_generate_v4(),
+				Email varchar NULL,
+				AccessToken varchar NULL,
+				AccessExp bigint,
+				RefreshToken varchar,
+				RefreshExp bigint,
+
+				PublicKey varchar NULL,
+				PrivateKey varchar NULL,
+				PrivateKeyIV varchar NULL,
+				PrivateKeySalt varchar NULL,
+
+				PasswordArgon2Hash varchar NULL,
+				PasswordArgon2IV varchar NULL,
+				PasswordScryptHash varchar NULL,
+				PasswordScryptIV varchar NULL,
+
+				UsedStorage float8 NOT NULL DEFAULT 0,
+				FullUsedStorage float8 NOT NULL DEFAULT 0,
+
+				CONSTRAINT members_pk PRIMARY KEY (ID),
+				CONSTRAINT members_un UNIQUE (Email)
+			);
+			CREATE or REPLACE function tolowercase() RETURNS trigger language plpgsql as $$ BEGIN new.Email := lower(new.Email); return new; END; $$;
+			DROP trigger if exists emailToLowerCase on members;
+			CREATE trigger emailToLowerCase BEFORE INSERT or UPDATE on members for each row execute function tolowercase();
+		`,
+		down:		`
+			DROP TABLE if exists members;
+			DROP function if exists tolowercase();
+		`,
+		sqliteUp:	`
+			CREATE TABLE if not exists members(
+				ID text NOT NULL,
+				Email text NULL,
+				AccessToken text NULL,
+				AccessExp bigint,
+				RefreshToken text,
+				RefreshExp bigint,
+
+				PublicKey text NULL,
+				PrivateKey text NULL,
+				PrivateKeyIV text NULL,
+				PrivateKeySalt text NULL,
+
+				PasswordArgon2Hash text NULL,
+				PasswordArgon2IV text NULL,
+				PasswordScryptHash text NULL,
+				PasswordScryptIV text NULL,
+
+				UsedStorage real NOT NULL DEFAULT 0,
+				FullUsedStorage real NOT NULL DEFAULT 0,
+
+				CONSTRAINT members_pk PRIMARY KEY (ID),
+				CONSTRAINT members_un UNIQUE (Email)
+			);
+			CREATE trigger if not exists emailToLowerCaseOnInsert AFTER INSERT on members BEGIN
+				UPDATE members SET Email = lower(new.Email) WHERE ID = new.ID;
+			END;
+			CREATE trigger if not exists emailToLowerCaseOnUpdate AFTER UPDATE OF Email on members BEGIN
+				UPDATE members SET Email = lower(new.Email) WHERE ID = new.ID;
+			END;
+		`,
+		sqliteDown:	`
+			DROP TABLE if exists members;
+		`,
+	},
+	{
+		version:	2,
+		name:		`create_audit_events`,
+		up:			`
+			CREATE TABLE if not exists audit_events(
+				ID bigserial NOT NULL,
+				Type varchar NOT NULL,
+				ActorID varchar NOT NULL DEFAULT '',
+				TargetID varchar NOT NULL DEFAULT '',
+				Reason varchar NOT NULL DEFAULT '',
+				IP varchar NOT NULL DEFAULT '',
+				UserAgent varchar NOT NULL DEFAULT '',
+				CreatedAt bigint NOT NULL,
+				PreviousHash varchar NOT NULL,
+				Hash varchar NOT NULL,
+
+				CONSTRAINT audit_events_pk PRIMARY KEY (ID)
+			);
+			CREATE INDEX if not exists audit_events_actor_idx ON audit_events (ActorID, ID);
+			CREATE INDEX if not exists audit_events_target_idx ON audit_events (TargetID, ID);
+			CREATE or REPLACE function forbid_audit_events_change() RETURNS trigger language plpgsql as $$ BEGIN RAISE EXCEPTION 'audit_events is append-only'; END; $$;
+			DROP trigger if exists auditEventsAppendOnly on audit_events;
+			CREATE trigger auditEventsAppendOnly BEFORE UPDATE or DELETE on audit_events for each row execute function forbid_audit_events_change();
+		`,
+		down:		`
+			DROP TABLE if exists audit_events;
+			DROP function if exists forbid_audit_events_change();
+		`,
+		sqliteUp:	`
+			CREATE TABLE if not exists audit_events(
+				ID integer PRIMARY KEY AUTOINCREMENT,
+				Type text NOT NULL,
+				ActorID text NOT NULL DEFAULT '',
+				TargetID text NOT NULL DEFAULT '',
+				Reason text NOT NULL DEFAULT '',
+				IP text NOT NULL DEFAULT '',
+				UserAgent text NOT NULL DEFAULT '',
+				CreatedAt bigint NOT NULL,
+				PreviousHash text NOT NULL,
+				Hash text NOT NULL
+			);
+			CREATE INDEX if not exists audit_events_actor_idx ON audit_events (ActorID, ID);
+			CREATE INDEX if not exists audit_events_target_idx ON audit_events (TargetID, ID);
+			CREATE trigger if not exists auditEventsNoUpdate BEFORE UPDATE on audit_events BEGIN
+				SELECT RAISE(ABORT, 'audit_events is append-only');
+			END;
+			CREATE trigger if not exists auditEventsNoDelete BEFORE DELETE on audit_events BEGIN
+				SELECT RAISE(ABORT, 'audit_events is append-only');
+			END;
+		`,
+		sqliteDown:	`
+			DROP TABLE if exists audit_events;
+		`,
+	},
+	{
+		version:	3,
+		name:		`create_login_history`,
+		up:			`
+			ALTER TABLE members ADD COLUMN if not exists PasswordChangeRequired boolean NOT NULL DEFAULT false;
+			CREATE TABLE if not exists login_history(
+				ID bigserial NOT NULL,
+				MemberID uuid NOT NULL REFERENCES members(ID) ON DELETE CASCADE,
+				IP varchar NOT NULL DEFAULT '',
+				UserAgent varchar NOT NULL DEFAULT '',
+				Fingerprint varchar NOT NULL,
+				NewDevice boolean NOT NULL DEFAULT false,
+				CreatedAt bigint NOT NULL,
+				AlertTokenHash varchar NOT NULL DEFAULT '',
+				AlertExp bigint NOT NULL DEFAULT 0,
+
+				CONSTRAINT login_history_pk PRIMARY KEY (ID)
+			);
+			CREATE INDEX if not exists login_history_member_idx ON login_history (MemberID, ID);
+			CREATE INDEX if not exists login_history_alert_idx ON login_history (AlertTokenHash) WHERE AlertTokenHash <> '';
+			CREATE TABLE if not exists known_devices(
+				MemberID uuid NOT NULL REFERENCES members(ID) ON DELETE CASCADE,
+				Fingerprint varchar NOT NULL,
+				FirstSeen bigint NOT NULL,
+				LastSeen bigint NOT NULL,
+
+				CONSTRAINT known_devices_pk PRIMARY KEY (MemberID, Fingerprint)
+			);
+		`,
+		down:		`
+			DROP TABLE if exists known_devices;
+			DROP TABLE if exists login_history;
+			ALTER TABLE members DROP COLUMN if exists PasswordChangeRequired;
+		`,
+		sqliteUp:	`
+			ALTER TABLE members ADD COLUMN PasswordChangeRequired boolean NOT NULL DEFAULT 0;
+			CREATE TABLE if not exists login_history(
+				ID integer PRIMARY KEY AUTOINCREMENT,
+				MemberID text NOT NULL REFERENCES members(ID) ON DELETE CASCADE,
+				IP text NOT NULL DEFAULT '',
+				UserAgent text NOT NULL DEFAULT '',
+				Fingerprint text NOT NULL,
+				NewDevice boolean NOT NULL DEFAULT 0,
+				CreatedAt bigint NOT NULL,
+				AlertTokenHash text NOT NULL DEFAULT '',
+				AlertExp bigint NOT NULL DEFAULT 0
+			);
+			CREATE INDEX if not exists login_history_member_idx ON login_history (MemberID, ID);
+			CREATE INDEX if not exists login_history_alert_idx ON login_history (AlertTokenHash) WHERE AlertTokenHash <> '';
+			CREATE TABLE if not exists known_devices(
+				MemberID text NOT NULL REFERENCES members(ID) ON DELETE CASCADE,
+				Fingerprint text NOT NULL,
+				FirstSeen bigint NOT NULL,
+				LastSeen bigint NOT NULL,
+
+				CONSTRAINT known_devices_pk PRIMARY KEY (MemberID, Fingerprint)
+			);
+		`,
+		sqliteDown:	`
+			DROP TABLE if exists known_devices;
+			DROP TABLE if exists login_history;
+			ALTER TABLE members DROP COLUMN PasswordChangeRequired;
+		`,
+	},
+	{
+		version:	4,
+		name:		`create_storage_reservations`,
+		up:			`
+			ALTER TABLE members ADD COLUMN if not exists StorageQuota bigint NOT NULL DEFAULT 0;
+			ALTER TABLE members ADD COLUMN if not exists ReservedStorage bigint NOT NULL DEFAULT 0;
+			CREATE TABLE if not exists storage_reservations(
+				ID uuid NOT NULL,
+				MemberID uuid NOT NULL REFERENCES members(ID) ON DELETE CASCADE,
+				Bytes bigint NOT NULL,
+				ExpiresAt bigint NOT NULL,
+
+				CONSTRAINT storage_reservations_pk PRIMARY KEY (ID)
+			);
+			CREATE INDEX if not exists storage_reservations_member_idx ON storage_reservations (MemberID, ExpiresAt);
+		`,
+		down:		`
+			DROP TABLE if exists storage_reservations;
+			ALTER TABLE members DROP COLUMN if exists ReservedStorage;
+			ALTER TABLE members DROP COLUMN if exists StorageQuota;
+		`,
+		sqliteUp:	`
+			ALTER TABLE members ADD COLUMN StorageQuota bigint NOT NULL DEFAULT 0;
+			ALTER TABLE members ADD COLUMN ReservedStorage bigint NOT NULL DEFAULT 0;
+			CREATE TABLE if not exists storage_reservations(
+				ID text NOT NULL,
+				MemberID text NOT NULL REFERENCES members(ID) ON DELETE CASCADE,
+				Bytes bigint NOT NULL,
+				ExpiresAt bigint NOT NULL,
+
+				CONSTRAINT storage_reservations_pk PRIMARY KEY (ID)
+			);
+			CREATE INDEX if not exists storage_reservations_member_idx ON storage_reservations (MemberID, ExpiresAt);
+		`,
+		sqliteDown:	`
+			DROP TABLE if exists storage_reservations;
+			ALTER TABLE members DROP COLUMN ReservedStorage;
+			ALTER TABLE members DROP COLUMN StorageQuota;
+		`,
+	},
+	{
+		version:	5,
+		name:		`create_plans`,
+		up:			`
+			CREATE TABLE if not exists plans(
+				ID uuid NOT NULL DEFAULT uuid_generate_v4(),
+				Name varchar NOT NULL,
+				StorageQuota bigint NOT NULL DEFAULT 0,
+				MaxPictures bigint NOT NULL DEFAULT 0,
+				MaxAlbums bigint NOT NULL DEFAULT 0,
+				MaxSessions bigint NOT NULL DEFAULT 0,
+				Features varchar NOT NULL DEFAULT '',
+
+				CONSTRAINT plans_pk PRIMARY KEY (ID),
+				CONSTRAINT plans_un UNIQUE (Name)
+			);
+			ALTER TABLE members ADD COLUMN if not exists PlanID uuid NULL REFERENCES plans(ID);
+		`,
+		down:		`
+			ALTER TABLE members DROP COLUMN if exists PlanID;
+			DROP TABLE if exists plans;
+		`,
+		sqliteUp:	`
+			CREATE TABLE if not exists plans(
+				ID text NOT NULL,
+				Name text NOT NULL,
+				StorageQuota bigint NOT NULL DEFAULT 0,
+				MaxPictures bigint NOT NULL DEFAULT 0,
+				MaxAlbums bigint NOT NULL DEFAULT 0,
+				MaxSessions bigint NOT NULL DEFAULT 0,
+				Features text NOT NULL DEFAULT '',
+
+				CONSTRAINT plans_pk PRIMARY KEY (ID),
+				CONSTRAINT plans_un UNIQUE (Name)
+			);
+			ALTER TABLE members ADD COLUMN PlanID text NULL REFERENCES plans(ID);
+		`,
+		sqliteDown:	`
+			ALTER TABLE members DROP COLUMN PlanID;
+			DROP TABLE if exists plans;
+		`,
+	},
+	{
+		version:	6,
+		name:		`create_invitations`,
+		up:			`
+			CREATE TABLE if not exists invitations(
+				ID uuid NOT NULL DEFAULT uuid_generate_v4(),
+				CodeHash varchar NOT NULL,
+				Email varchar NOT NULL DEFAULT '',
+				PlanID uuid NULL REFERENCES plans(ID),
+				MaxUses bigint NOT NULL DEFAULT 1,
+				Uses bigint NOT NULL DEFAULT 0,
+				ExpiresAt bigint NOT NULL,
+				CreatedBy varchar NOT NULL DEFAULT '',
+				CreatedAt bigint NOT NULL,
+				RevokedAt bigint NOT NULL DEFAULT 0,
+
+				CONSTRAINT invitations_pk PRIMARY KEY (ID),
+				CONSTRAINT invitations_un UNIQUE (CodeHash)
+			);
+		`,
+		down:		`
+			DROP TABLE if exists invitations;
+		`,
+		sqliteUp:	`
+			CREATE TABLE if not exists invitations(
+				ID text NOT NULL,
+				CodeHash text NOT NULL,
+				Email text NOT NULL DEFAULT '',
+				PlanID text NULL REFERENCES plans(ID),
+				MaxUses bigint NOT NULL DEFAULT 1,
+				Uses bigint NOT NULL DEFAULT 0,
+				ExpiresAt bigint NOT NULL,
+				CreatedBy text NOT NULL DEFAULT '',
+				CreatedAt bigint NOT NULL,
+				RevokedAt bigint NOT NULL DEFAULT 0,
+
+				CONSTRAINT invitations_pk PRIMARY KEY (ID),
+				CONSTRAINT invitations_un UNIQUE (CodeHash)
+			);
+		`,
+		sqliteDown:	`
+			DROP TABLE if exists invitations;
+		`,
+	},
+	{
+		version:	7,
+		name:		`add_password_change_token`,
+		up:			`
+			ALTER TABLE members ADD COLUMN if not exists PasswordChangeTokenHash varchar NOT NULL DEFAULT '';
+		`,
+		down:		`
+			ALTER TABLE members DROP COLUMN if exists PasswordChangeTokenHash;
+		`,
+		sqliteUp:	`
+			ALTER TABLE members ADD COLUMN PasswordChangeTokenHash text NOT NULL DEFAULT '';
+		`,
+		sqliteDown:	`
+			ALTER TABLE members DROP COLUMN PasswordChangeTokenHash;
+		`,
+	},
+}
+
+/******************************************************************************
+**	The advisory lock prevents two replicas, booting at the same time, from
+**	running the migrations concurrently. SQLite is only used by single-node
+**	installs and does not need it.
+******************************************************************************/
+const	MIGRATIONS_ADVISORY_LOCK = 80100001
+
+var		ErrNoMigrationToRevert = errors.New("no migration to revert")
+var		ErrPendingMigrations = errors.New("the schema is out of date, run `members migrate up` first")
+
+/******************************************************************************
+**	Get a connection holding the migration lock. The lock is released, and
+**	the connection closed, by the returned function.
+******************************************************************************/
+func	lockMigrations(ctx context.Context) (*sql.Conn, func(), error) {
+	conn, err := DB.Conn(ctx)
+	if (err != nil) {
+		return nil, nil, err
+	}
+	if (databaseDriver == DRIVER_SQLITE) {
+		_, err = conn.ExecContext(ctx, `CREATE TABLE if not exists schema_migrations(
+			Version bigint NOT NULL,
+			Name text NOT NULL,
+			AppliedAt timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			CONSTRAINT schema_migrations_pk PRIMARY KEY (Version)
+		);`)
+		if (err != nil) {
+			conn.Close()
+			return nil, nil, err
+		}
+		return conn, func() {conn.Close()}, nil
+	}
+
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, MIGRATIONS_ADVISORY_LOCK); err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+	unlock := func() {
+		conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, MIGRATIONS_ADVISORY_LOCK)
+		conn.Close()
+	}
+
+	_, err = conn.ExecContext(ctx, `CREATE TABLE if not exists schema_migrations(
+		Version bigint NOT NULL,
+		Name varchar NOT NULL,
+		AppliedAt timestamptz NOT NULL DEFAULT now(),
+		CONSTRAINT schema_migrations_pk PRIMARY KEY (Version)
+	);`)
+	if (err != nil) {
+		unlock()
+		return nil, nil, err
+	}
+	return conn, unlock, nil
+}
+
+func	getAppliedMigrations(ctx context.Context, conn *sql.Conn) (map[int64]bool, error) {
+	rows, err := conn.QueryContext(ctx, `SELECT Version FROM schema_migrations`)
+	if (err != nil) {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := map[int64]bool{}
+	for rows.Next() {
+		var	version int64
+		if err := rows.Scan(&version); err != nil {
+			return nil, err
+		}
+		applied[version] = true
+	}
+	return applied, rows.Err()
+}
+
+/******************************************************************************
+**	Run a migration script and record it, or remove its record, in the same
+**	transaction : a failing migration leaves the schema untouched
+******************************************************************************/
+func	runMigration(ctx context.Context, conn *sql.Conn, migration sMigration, isUp bool) (error) {
+	tx, err := conn.BeginTx(ctx, nil)
+	if (err != nil) {
+		return err
+	}
+
+	if (isUp) {
+		_, err = tx.ExecContext(ctx, migration.script(true))
+		if (err == nil) {
+			_, err = tx.ExecContext(ctx, `INSERT INTO schema_migrations (Version, Name) VALUES ($1, $2)`, migration.version, migration.name)
+		}
+	} else {
+		_, err = tx.ExecContext(ctx, migration.script(false))
+		if (err == nil) {
+			_, err = tx.ExecContext(ctx, `DELETE FROM schema_migrations WHERE Version=$1`, migration.version)
+		}
+	}
+	if (err != nil) {
+		tx.Rollback()
+		return fmt.Errorf("migration %d_%s: %v", migration.version, migration.name, err)
+	}
+	return tx.Commit()
+}
+
+/******************************************************************************
+**	Apply all the pending migrations, in order
+******************************************************************************/
+func	migrateUp(ctx context.Context) (error) {
+	conn, unlock, err := lockMigrations(ctx)
+	if (err != nil) {
+		return err
+	}
+	defer unlock()
+
+	applied, err := getAppliedMigrations(ctx, conn)
+	if (err != nil) {
+		return err
+	}
+	for _, migration := range migrations {
+		if (applied[migration.version]) {
+			continue
+		}
+		if err := runMigration(ctx, conn, migration, true); err != nil {
+			return err
+		}
+		logInfo(context.Background(), `Migration applied`, `version`, migration.version, `name`, migration.name)
+	}
+	return nil
+}
+
+/******************************************************************************
+**	Revert the last applied migration
+******************************************************************************/
+func	migrateDown(ctx context.Context) (error) {
+	conn, unlock, err := lockMigrations(ctx)
+	if (err != nil) {
+		return err
+	}
+	defer unlock()
+
+	applied, err := getAppliedMigrations(ctx, conn)
+	if (err != nil) {
+		return err
+	}
+	for index := len(migrations) - 1; index >= 0; index-- {
+		migration := migrations[index]
+		if (!applied[migration.version]) {
+			continue
+		}
+		if err := runMigration(ctx, conn, migration, false); err != nil {
+			return err
+		}
+		logInfo(context.Background(), `Migration reverted`, `version`, migration.version, `name`, migration.name)
+		return nil
+	}
+	return ErrNoMigrationToRevert
+}
+
+/******************************************************************************
+**	Check that every known migration is applied, for the commands which must
+**	not change the schema
+******************************************************************************/
+func	checkMigrationsApplied(ctx context.Context) (error) {
+	conn, unlock, err := lockMigrations(ctx)
+	if (err != nil) {
+		return err
+	}
+	defer unlock()
+
+	applied, err := getAppliedMigrations(ctx, conn)
+	if (err != nil) {
+		return err
+	}
+	for _, migration := range migrations {
+		if (!applied[migration.version]) {
+			return fmt.Errorf("%w: %d_%s is pending", ErrPendingMigrations, migration.version, migration.name)
+		}
+	}
+	return nil
+}
+
+/******************************************************************************
+**	Print every known migration, and whether it is applied or pending
+******************************************************************************/
+func	migrateStatus(ctx context.Context) (error) {
+	conn, unlock, err := lockMigrations(ctx)
+	if (err != nil) {
+		return err
+	}
+	defer unlock()
+
+	applied, err := getAppliedMigrations(ctx, conn)
+	if (err != nil) {
+		return err
+	}
+	for _, migration := range migrations {
+		state := `pending`
+		if (applied[migration.version]) {
+			state = `applied`
+		}
+		fmt.Printf("%d_%s\t%s\n", migration.version, migration.name, state)
+	}
+	return nil
+}
+
+/******************************************************************************
+**	Handle the `members migrate up|down|status` command
+******************************************************************************/
+func	runMigrateCommand(args []string) (error) {
+	if (len(args) != 1) {
+		return errors.New("usage: members migrate up|down|status")
+	}
+
+	switch args[0] {
+	case `up`:		return migrateUp(context.Background())
+	case `down`:	return migrateDown(context.Background())
+	case `status`:	return migrateStatus(context.Background())
+	}
+	return errors.New("usage: members migrate up|down|status")
+}
@@ -0,0 +1,115 @@
+/*******************************************************************************
+** @Author:					Thomas Bouder <Tbouder>
+** @Email:					Tbouder@protonmail.com
+** @Date:					Saturday 09 May 2020 - 11:58:33
+** @Filename:				Migrations_test.go
+**
+** @Last modified by:		Tbouder
+** @Last modified time:		Saturday 09 May 2020 - 16:03:45
+*******************************************************************************/
+
+
+package			main
+
+import			"errors"
+import			"context"
+import			"testing"
+import			"database/sql"
+
+/******************************************************************************
+**	The schema, as SQLite stores it, to compare it between two migrations
+******************************************************************************/
+func	sqliteSchema(t *testing.T, db *sql.DB) (map[string]string) {
+	rows, err := db.Query(`SELECT name, COALESCE(sql, '') FROM sqlite_master WHERE name NOT LIKE 'sqlite_%'`)
+	if (err != nil) {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+
+	schema := map[string]string{}
+	for rows.Next() {
+		var	name, statement string
+		if err := rows.Scan(&name, &statement); err != nil {
+			t.Fatal(err)
+		}
+		schema[name] = statement
+	}
+	return schema
+}
+
+func	appliedMigrationsCount(t *testing.T) (int) {
+	conn, unlock, err := lockMigrations(context.Background())
+	if (err != nil) {
+		t.Fatal(err)
+	}
+	defer unlock()
+	applied, err := getAppliedMigrations(context.Background(), conn)
+	if (err != nil) {
+		t.Fatal(err)
+	}
+	return len(applied)
+}
+
+/******************************************************************************
+**	Every migration can be reverted, and applied again to the same schema
+******************************************************************************/
+func	TestMigrateUpDownUpSQLite(t *testing.T) {
+	db, err := sql.Open(DRIVER_SQLITE, `:memory:`)
+	if (err != nil) {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec(`PRAGMA foreign_keys=ON;`); err != nil {
+		t.Fatal(err)
+	}
+	defer func(db *sql.DB, driver string) {DB, databaseDriver = db, driver}(DB, databaseDriver)
+	DB, databaseDriver = db, DRIVER_SQLITE
+
+	if err := runMigrateCommand([]string{`up`}); err != nil {
+		t.Fatalf("migrate up: %v", err)
+	}
+	if count := appliedMigrationsCount(t); count != len(migrations) {
+		t.Fatalf("expected %d applied migrations, got %d", len(migrations), count)
+	}
+	schema := sqliteSchema(t, db)
+	if err := checkMigrationsApplied(context.Background()); err != nil {
+		t.Errorf("expected an up to date schema, got %v", err)
+	}
+
+	for index := range migrations {
+		if err := runMigrateCommand([]string{`down`}); err != nil {
+			t.Fatalf("migrate down %d: %v", index, err)
+		}
+	}
+	if err := runMigrateCommand([]string{`down`}); err != ErrNoMigrationToRevert {
+		t.Errorf("expected ErrNoMigrationToRevert, got %v", err)
+	}
+	if err := checkMigrationsApplied(context.Background()); !errors.Is(err, ErrPendingMigrations) {
+		t.Errorf("expected ErrPendingMigrations, got %v", err)
+	}
+	for name := range sqliteSchema(t, db) {
+		if (name != `schema_migrations`) {
+			t.Errorf("%s is left after reverting every migration", name)
+		}
+	}
+
+	if err := runMigrateCommand([]string{`up`}); err != nil {
+		t.Fatalf("migrate up again: %v", err)
+	}
+	again := sqliteSchema(t, db)
+	if (len(again) != len(schema)) {
+		t.Errorf("expected %d tables and indexes, got %d", len(schema), len(again))
+	}
+	for name, statement := range schema {
+		if (again[name] != statement) {
+			t.Errorf("%s differs after migrating down and up : %q", name, again[name])
+		}
+	}
+
+	store := newSQLiteStore(db)
+	member := &sMember{ID: `00000000-0000-0000-0000-000000000001`, Email: `migrated@example.com`}
+	if err := store.CreateMember(context.Background(), member, &sSession{}, nil); err != nil {
+		t.Errorf("CreateMember on the migrated schema: %v", err)
+	}
+}
@@ -0,0 +1,100 @@
+/*******************************************************************************
+** @Author:					Thomas Bouder <Tbouder>
+** @Email:					Tbouder@protonmail.com
+** @Date:					Friday 08 May 2020 - 18:02:37
+** @Filename:				Password.change.go
+**
+** @Last modified by:		Tbouder
+** @Last modified time:		Saturday 09 May 2020 - 18:12:40
+*******************************************************************************/
+
+
+package			main
+
+import			"context"
+import			"crypto/subtle"
+import			"github.com/panghostlin/Members/Extended"
+
+/******************************************************************************
+**	The member changes it's password with the current one, and sends it's
+**	private key encrypted with the new one. It is the only way out of
+**	PasswordChangeRequired, set when a login is reported as unrecognized :
+**	the login is refused until the password is changed. As the password may
+**	be known by someone else, the change then also requires the token of the
+**	reported alert, sent to the email of the member, and consumes it. The
+**	change revokes the previous session and opens a new one.
+******************************************************************************/
+func	(s *server) ChangePassword(ctx context.Context, req *extended.ChangePasswordRequest) (*extended.ChangePasswordResponse, error) {
+	if (req.PrivateKey == `` || req.PrivateKeyIV == `` || req.PrivateKeySalt == ``) {
+		return nil, errInvalidArgument(`the private key encrypted with the new password is required`, fieldViolation(`privateKey`, `PRIVATE_KEY_REQUIRED`))
+	}
+	if (req.NewPassword == req.Password) {
+		return nil, errInvalidArgument(`the new password must differ from the current one`, fieldViolation(`newPassword`, `PASSWORD_UNCHANGED`))
+	}
+
+	/**************************************************************************
+	**	The failed attempts are audited like the failed logins
+	**************************************************************************/
+	member, _, reason, err := s.authenticateMember(ctx, req.Email, req.Password)
+	if (err != nil) {
+		memberID := ``
+		if (member != nil) {
+			memberID = member.ID
+		}
+		recordLoginAttempt(ctx, memberID, reason)
+		return nil, err
+	}
+	if (member.PasswordChangeRequired && !isPasswordChangeToken(member, req.Token)) {
+		recordLoginAttempt(ctx, member.ID, LOGIN_INVALID_CHANGE_TOKEN)
+		return nil, errPermissionDenied(`the token of the unrecognized login alert is required`)
+	}
+
+	member.PrivateKey = req.PrivateKey
+	member.PrivateKeyIV = req.PrivateKeyIV
+	member.PrivateKeySalt = req.PrivateKeySalt
+	if err := setNewPassword(ctx, member, req.NewPassword); err != nil {
+		return nil, err
+	}
+
+	accessToken, accessExpiration, err := SetAccessToken(member.ID)
+	if (err != nil) {
+		return nil, err
+	}
+	refreshToken, refreshExpiration, err := SetRefreshToken(member.ID)
+	if (err != nil) {
+		return nil, err
+	}
+	err = s.memberStore.ChangePassword(ctx, member, &sSession{
+		AccessToken: accessToken,
+		AccessExp: accessExpiration,
+		RefreshToken: refreshToken,
+		RefreshExp: refreshExpiration,
+	})
+	if (err == ErrMemberNotFound && member.PasswordChangeRequired) {
+		return nil, errPermissionDenied(`the token of the unrecognized login alert was already used`)
+	} else if (err == ErrMemberNotFound) {
+		return nil, errNotFound(`member`, member.ID)
+	} else if (err != nil) {
+		return nil, err
+	}
+
+	if (member.PasswordChangeRequired) {
+		recordAuditEvent(ctx, AUDIT_PASSWORD_CHANGED, member.ID, member.ID, LOGIN_PASSWORD_CHANGE_REQUIRED)
+	} else {
+		recordAuditEvent(ctx, AUDIT_PASSWORD_CHANGED, member.ID, member.ID, ``)
+	}
+	s.recordLogin(ctx, member, false)
+
+	return &extended.ChangePasswordResponse{
+		MemberID: member.ID,
+		AccessToken: accessToken,
+		AccessExpiration: accessExpiration,
+	}, nil
+}
+
+func	isPasswordChangeToken(member *sMember, token string) (bool) {
+	if (token == `` || member.PasswordChangeTokenHash == ``) {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(hashAlertToken(token)), []byte(member.PasswordChangeTokenHash)) == 1
+}
@@ -0,0 +1,252 @@
+/*******************************************************************************
+** @Author:					Thomas Bouder <Tbouder>
+** @Email:					Tbouder@protonmail.com
+** @Date:					Wednesday 08 April 2020 - 09:54:13
+** @Filename:				Password.policy.go
+**
+** @Last modified by:		Tbouder
+** @Last modified time:		Saturday 09 May 2020 - 10:47:12
+*******************************************************************************/
+
+package			main
+
+import			"os"
+import			"fmt"
+import			"errors"
+import			"math"
+import			"bufio"
+import			"context"
+import			"strings"
+import			"unicode"
+import			"crypto/sha1"
+import			"encoding/hex"
+import			"path/filepath"
+import			"google.golang.org/genproto/googleapis/rpc/errdetails"
+
+/******************************************************************************
+**	Machine-readable reasons sent back to the UI, in the description of the
+**	BadRequest field violations, when a password is refused
+******************************************************************************/
+const	PASSWORD_TOO_SHORT = `PASSWORD_TOO_SHORT`
+const	PASSWORD_TOO_WEAK = `PASSWORD_TOO_WEAK`
+const	PASSWORD_BANNED = `PASSWORD_BANNED`
+const	PASSWORD_BREACHED = `PASSWORD_BREACHED`
+
+const	DEFAULT_PASSWORD_MIN_LENGTH = 8
+const	DEFAULT_PASSWORD_MIN_SCORE = 2
+
+var		ErrBreachedRangeMissing = errors.New("missing breached passwords range")
+
+/******************************************************************************
+**	The password policy is configured with :
+**	- password.minLength : the minimum number of characters
+**	- password.minScore : the minimum strength score, from 0 to 4
+**	- password.bannedList : a file with one banned password per line
+**	- password.breachedDir : a local copy of the HIBP range files, one file
+**	  per SHA-1 prefix (`ABCDE` or `ABCDE.txt`) with `SUFFIX:COUNT` lines
+**	- password.breachedFailOpen : accept the passwords which could not be
+**	  checked against the breached ones, instead of refusing them
+******************************************************************************/
+type	sPasswordPolicy struct {
+	minLength			int
+	minScore			int
+	banned				map[string]bool
+	breachedDir			string
+	breachedFailOpen	bool
+}
+var		passwordPolicy *sPasswordPolicy
+
+func	initPasswordPolicy() (*sPasswordPolicy, error) {
+	policy := &sPasswordPolicy{
+		minLength:			int(config.Password.MinLength),
+		minScore:			int(config.Password.MinScore),
+		banned:				map[string]bool{},
+		breachedDir:		config.Password.BreachedDir,
+		breachedFailOpen:	config.Password.BreachedFailOpen,
+	}
+
+	if bannedList := config.Password.BannedList; bannedList != `` {
+		banned, err := loadBannedPasswords(bannedList)
+		if (err != nil) {
+			return nil, err
+		}
+		policy.banned = banned
+	}
+	return policy, nil
+}
+
+func	loadBannedPasswords(path string) (map[string]bool, error) {
+	file, err := os.Open(path)
+	if (err != nil) {
+		return nil, err
+	}
+	defer file.Close()
+
+	banned := map[string]bool{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != `` {
+			banned[strings.ToLower(line)] = true
+		}
+	}
+	return banned, scanner.Err()
+}
+
+/******************************************************************************
+**	Check the password against the policy. The returned error is an
+**	InvalidArgument status listing every violated rule, or nil if the
+**	password is accepted. The passwords which could not be checked against
+**	the breached ones are refused with Unavailable, unless the policy fails
+**	open.
+******************************************************************************/
+func	(p *sPasswordPolicy) validate(ctx context.Context, password, email string) (error) {
+	var	reasons []string
+
+	if (len([]rune(password)) < p.minLength) {
+		reasons = append(reasons, PASSWORD_TOO_SHORT)
+	}
+	if (p.banned[strings.ToLower(password)]) {
+		reasons = append(reasons, PASSWORD_BANNED)
+	}
+	if (estimatePasswordScore(password, p.banned, email) < p.minScore) {
+		reasons = append(reasons, PASSWORD_TOO_WEAK)
+	}
+	if breached, err := p.isBreached(password); err != nil && p.breachedFailOpen {
+		logError(ctx, `Could not check the breached passwords, the password is accepted`, `error`, err)
+	} else if (err != nil) {
+		logError(ctx, `Could not check the breached passwords`, `error`, err)
+		return errUnavailable(`the password could not be checked, please retry later`)
+	} else if (breached) {
+		reasons = append(reasons, PASSWORD_BREACHED)
+	}
+
+	if (len(reasons) == 0) {
+		return nil
+	}
+
+	violations := []*errdetails.BadRequest_FieldViolation{}
+	for _, reason := range reasons {
+		violations = append(violations, fieldViolation(`password`, reason))
+	}
+	return errInvalidArgument(`the password does not match the password policy`, violations...)
+}
+
+/******************************************************************************
+**	Every new password, at sign up or when it is changed, is checked against
+**	the policy before being hashed into the member
+******************************************************************************/
+func	setNewPassword(ctx context.Context, member *sMember, password string) (error) {
+	if err := passwordPolicy.validate(ctx, password, member.Email); err != nil {
+		return err
+	}
+	return setPasswordHashes(ctx, member, password)
+}
+
+/******************************************************************************
+**	Look for the SHA-1 of the password in the local range file matching its
+**	5 first hexadecimal characters, without calling any external service. A
+**	complete copy has a file for every prefix : a missing one means that the
+**	copy is incomplete, and the password can not be checked.
+******************************************************************************/
+func	(p *sPasswordPolicy) isBreached(password string) (bool, error) {
+	if (p.breachedDir == ``) {
+		return false, nil
+	}
+
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	prefix, suffix := hash[:5], hash[5:]
+
+	file, err := os.Open(filepath.Join(p.breachedDir, prefix + `.txt`))
+	if (os.IsNotExist(err)) {
+		file, err = os.Open(filepath.Join(p.breachedDir, prefix))
+	}
+	if (os.IsNotExist(err)) {
+		return false, fmt.Errorf("%w: %s", ErrBreachedRangeMissing, prefix)
+	} else if (err != nil) {
+		return false, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.SplitN(strings.TrimSpace(scanner.Text()), `:`, 2)
+		if (strings.ToUpper(line[0]) == suffix) {
+			return true, nil
+		}
+	}
+	return false, scanner.Err()
+}
+
+/******************************************************************************
+**	Estimate the strength of a password, zxcvbn-style, with a score between
+**	0 (too guessable) and 4 (very unguessable). The number of guesses is
+**	estimated by bruteforcing the password, where repeated or sequential
+**	characters barely count, and where the banned words and the user inputs
+**	found in the password are replaced by a single dictionary token.
+******************************************************************************/
+func	estimatePasswordScore(password string, dictionary map[string]bool, userInputs ...string) (int) {
+	lowered := strings.ToLower(password)
+	guessesLog10 := 0.0
+
+	/**************************************************************************
+	**	Dictionary matches : the user inputs (email, local part) and the
+	**	banned words are replaced by a token worth the size of the dictionary
+	**************************************************************************/
+	words := []string{}
+	for _, input := range userInputs {
+		input = strings.ToLower(input)
+		words = append(words, input)
+		if index := strings.Index(input, `@`); index > 0 {
+			words = append(words, input[:index])
+		}
+	}
+	for word := range dictionary {
+		words = append(words, word)
+	}
+	for _, word := range words {
+		if (len(word) >= 3 && strings.Contains(lowered, word)) {
+			lowered = strings.Replace(lowered, word, ``, -1)
+			guessesLog10 += math.Log10(float64(len(dictionary) + len(userInputs) + 1))
+		}
+	}
+
+	/**************************************************************************
+	**	Bruteforce : 10 guesses per character, repeated and sequential
+	**	characters only count for a quarter of a character
+	**************************************************************************/
+	runes := []rune(lowered)
+	for index, char := range runes {
+		if (index > 0 && (char == runes[index - 1] || char == runes[index - 1] + 1 || char == runes[index - 1] - 1)) {
+			guessesLog10 += 0.25
+		} else {
+			guessesLog10 += 1
+		}
+	}
+
+	/**************************************************************************
+	**	Mixing character classes makes a bruteforce longer
+	**************************************************************************/
+	var	hasLower, hasUpper, hasDigit, hasSymbol bool
+	for _, char := range password {
+		switch {
+		case unicode.IsLower(char):	hasLower = true
+		case unicode.IsUpper(char):	hasUpper = true
+		case unicode.IsDigit(char):	hasDigit = true
+		default:					hasSymbol = true
+		}
+	}
+	for _, class := range []bool{hasLower, hasUpper, hasDigit, hasSymbol} {
+		if (class) {
+			guessesLog10 += 0.5
+		}
+	}
+
+	switch {
+	case guessesLog10 < 3:	return 0
+	case guessesLog10 < 6:	return 1
+	case guessesLog10 < 8:	return 2
+	case guessesLog10 < 10:	return 3
+	default:				return 4
+	}
+}
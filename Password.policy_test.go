@@ -0,0 +1,66 @@
+/*******************************************************************************
+** @Author:					Thomas Bouder <Tbouder>
+** @Email:					Tbouder@protonmail.com
+** @Date:					Saturday 09 May 2020 - 10:47:12
+** @Filename:				Password.policy_test.go
+**
+** @Last modified by:		Tbouder
+** @Last modified time:		Saturday 09 May 2020 - 10:47:12
+*******************************************************************************/
+
+
+package			main
+
+import			"os"
+import			"errors"
+import			"strings"
+import			"context"
+import			"testing"
+import			"io/ioutil"
+import			"crypto/sha1"
+import			"encoding/hex"
+import			"path/filepath"
+import			"google.golang.org/grpc/codes"
+
+/******************************************************************************
+**	A range directory holding the range file of a single breached password
+******************************************************************************/
+func	newBreachedTestDir(t *testing.T, breached string) (string, func()) {
+	dir, err := ioutil.TempDir(``, `breached`)
+	if (err != nil) {
+		t.Fatal(err)
+	}
+	sum := sha1.Sum([]byte(breached))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	content := `0000000000000000000000000000000000A:1` + "\n" + hash[5:] + `:42` + "\n"
+	if err := ioutil.WriteFile(filepath.Join(dir, hash[:5] + `.txt`), []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return dir, func() {os.RemoveAll(dir)}
+}
+
+func	TestBreachedPasswords(t *testing.T) {
+	dir, remove := newBreachedTestDir(t, TEST_PASSWORD)
+	defer remove()
+	policy := &sPasswordPolicy{breachedDir: dir}
+
+	if breached, err := policy.isBreached(TEST_PASSWORD); !breached || err != nil {
+		t.Errorf("expected a breached password, got %v %v", breached, err)
+	}
+	if _, err := policy.isBreached(TEST_NEW_PASSWORD); !errors.Is(err, ErrBreachedRangeMissing) {
+		t.Errorf("a missing range file: expected ErrBreachedRangeMissing, got %v", err)
+	}
+
+	err := policy.validate(context.Background(), TEST_NEW_PASSWORD, `member@example.com`)
+	if code := statusCode(err); code != codes.Unavailable {
+		t.Errorf("a missing range file: expected Unavailable, got %v", code)
+	}
+	policy.breachedFailOpen = true
+	if err := policy.validate(context.Background(), TEST_NEW_PASSWORD, `member@example.com`); err != nil {
+		t.Errorf("a missing range file, failing open: expected no error, got %v", err)
+	}
+	err = policy.validate(context.Background(), TEST_PASSWORD, `member@example.com`)
+	if code := statusCode(err); code != codes.InvalidArgument {
+		t.Errorf("a breached password: expected InvalidArgument, got %v", code)
+	}
+}
@@ -0,0 +1,280 @@
+/*******************************************************************************
+** @Author:					Thomas Bouder <Tbouder>
+** @Email:					Tbouder@protonmail.com
+** @Date:					Thursday 07 May 2020 - 10:24:16
+** @Filename:				Plans.go
+**
+** @Last modified by:		Tbouder
+** @Last modified time:		Saturday 09 May 2020 - 18:12:40
+*******************************************************************************/
+
+
+package			main
+
+import			"sort"
+import			"context"
+import			"strings"
+import			"github.com/panghostlin/Members/Extended"
+
+/******************************************************************************
+**	The plans give different limits to the members, like more storage to a
+**	family member than to a guest. The storage quota is enforced by the
+**	reservations, the other limits by the Pictures service, which reads them
+**	with GetMemberLimits. The members hold a single session for now : the
+**	MaxSessions limit is only reported. Only an admin may manage the plans.
+******************************************************************************/
+const	MAX_PLAN_NAME_LENGTH = 64
+const	PLAN_FEATURE_SHARING = `sharing`
+
+var		planFeatures = map[string]bool{
+	PLAN_FEATURE_SHARING: true,
+}
+
+type	sMemberLimits struct {
+	MemberID		string
+	PlanID			string
+	PlanName		string
+	StorageQuota	int64
+	UsedStorage		float64
+	ReservedStorage	int64
+	MaxPictures		int64
+	MaxAlbums		int64
+	MaxSessions		int64
+	Features		[]string
+}
+
+/******************************************************************************
+**	Check the plan, trim it's name and sort it's features
+******************************************************************************/
+func	validatePlan(plan *sPlan) (error) {
+	plan.Name = strings.TrimSpace(plan.Name)
+	if (plan.Name == ``) {
+		return errInvalidArgument(`the name is required`, fieldViolation(`name`, `NAME_REQUIRED`))
+	} else if (len(plan.Name) > MAX_PLAN_NAME_LENGTH) {
+		return errInvalidArgument(`the name is too long`, fieldViolation(`name`, `NAME_TOO_LONG`))
+	}
+
+	limits := map[string]int64{
+		`storageQuota`:	plan.StorageQuota,
+		`maxPictures`:	plan.MaxPictures,
+		`maxAlbums`:	plan.MaxAlbums,
+		`maxSessions`:	plan.MaxSessions,
+	}
+	for field, limit := range limits {
+		if (limit < 0) {
+			return errInvalidArgument(`the limits must be positive`, fieldViolation(field, `LIMIT_NEGATIVE`))
+		}
+	}
+
+	features := map[string]bool{}
+	for _, feature := range plan.Features {
+		if (!planFeatures[feature]) {
+			return errInvalidArgument(`unknown feature ` + feature, fieldViolation(`features`, `FEATURE_UNKNOWN`))
+		}
+		features[feature] = true
+	}
+	plan.Features = []string{}
+	for feature := range features {
+		plan.Features = append(plan.Features, feature)
+	}
+	sort.Strings(plan.Features)
+	return nil
+}
+
+/******************************************************************************
+**	Create a plan, with a new ID
+******************************************************************************/
+func	(s *server) CreatePlan(ctx context.Context, plan *sPlan) (*sPlan, error) {
+	if err := validatePlan(plan); err != nil {
+		return nil, err
+	}
+	ID, err := newUUID()
+	if (err != nil) {
+		return nil, err
+	}
+	plan.ID = ID
+
+	err = s.planStore.CreatePlan(ctx, plan)
+	if (err == ErrPlanAlreadyExists) {
+		return nil, errAlreadyExists(`plan`, plan.Name)
+	} else if (err != nil) {
+		return nil, err
+	}
+	recordAuditEvent(ctx, AUDIT_ADMIN_ACTION, callerName(ctx), plan.ID, `plan_created`)
+	return plan, nil
+}
+
+/******************************************************************************
+**	Replace the name, the limits and the features of a plan. The members of
+**	the plan get the new limits right away.
+******************************************************************************/
+func	(s *server) UpdatePlan(ctx context.Context, plan *sPlan) (*sPlan, error) {
+	if (plan.ID == ``) {
+		return nil, errInvalidArgument(`the planID is required`, fieldViolation(`planID`, `PLAN_ID_REQUIRED`))
+	}
+	if err := validatePlan(plan); err != nil {
+		return nil, err
+	}
+
+	err := s.planStore.UpdatePlan(ctx, plan)
+	if (err == ErrPlanNotFound) {
+		return nil, errNotFound(`plan`, plan.ID)
+	} else if (err == ErrPlanAlreadyExists) {
+		return nil, errAlreadyExists(`plan`, plan.Name)
+	} else if (err != nil) {
+		return nil, err
+	}
+	recordAuditEvent(ctx, AUDIT_ADMIN_ACTION, callerName(ctx), plan.ID, `plan_updated`)
+	return plan, nil
+}
+
+func	(s *server) ListPlans(ctx context.Context) ([]*sPlan, error) {
+	return s.planStore.ListPlans(ctx)
+}
+
+/******************************************************************************
+**	Move a member to a plan, or out of any plan with an empty planID
+******************************************************************************/
+func	(s *server) SetMemberPlan(ctx context.Context, memberID, planID string) (error) {
+	if (memberID == ``) {
+		return errInvalidArgument(`the memberID is required`, fieldViolation(`memberID`, `MEMBER_ID_REQUIRED`))
+	}
+	setLogField(ctx, `member_id`, memberID)
+
+	err := s.planStore.SetMemberPlan(ctx, memberID, planID)
+	if (err == ErrPlanNotFound) {
+		return errNotFound(`plan`, planID)
+	} else if (err == ErrMemberNotFound) {
+		return errNotFound(`member`, memberID)
+	} else if (err != nil) {
+		return err
+	}
+	recordAuditEvent(ctx, AUDIT_ADMIN_ACTION, callerName(ctx), memberID, `member_plan_changed`)
+	return nil
+}
+
+/******************************************************************************
+**	The limits of a member, from it's plan. The storage quota of the member
+**	replaces the one of the plan, and the default quota applies without
+**	either.
+******************************************************************************/
+func	(s *server) GetMemberLimits(ctx context.Context, memberID string) (*sMemberLimits, error) {
+	if (memberID == ``) {
+		return nil, errInvalidArgument(`the memberID is required`, fieldViolation(`memberID`, `MEMBER_ID_REQUIRED`))
+	}
+	setLogField(ctx, `member_id`, memberID)
+
+	member, err := s.memberStore.GetMemberByID(ctx, memberID)
+	if (err == ErrMemberNotFound) {
+		return nil, errNotFound(`member`, memberID)
+	} else if (err != nil) {
+		return nil, err
+	}
+
+	plan := &sPlan{Features: []string{}}
+	if (member.PlanID != ``) {
+		plan, err = s.planStore.GetPlan(ctx, member.PlanID)
+		if (err != nil) {
+			return nil, err
+		}
+	}
+	limits := &sMemberLimits{
+		MemberID:			member.ID,
+		PlanID:				plan.ID,
+		PlanName:			plan.Name,
+		StorageQuota:		member.StorageQuota,
+		UsedStorage:		member.UsedStorage,
+		ReservedStorage:	member.ReservedStorage,
+		MaxPictures:		plan.MaxPictures,
+		MaxAlbums:			plan.MaxAlbums,
+		MaxSessions:		plan.MaxSessions,
+		Features:			plan.Features,
+	}
+	if (limits.StorageQuota <= 0) {
+		limits.StorageQuota = plan.StorageQuota
+	}
+	if (limits.StorageQuota <= 0) {
+		limits.StorageQuota = config.Storage.DefaultQuota
+	}
+	return limits, nil
+}
+
+/******************************************************************************
+**	The plan RPCs of the MembersExtendedService
+******************************************************************************/
+func	newPlanMessage(plan *sPlan) (*extended.Plan) {
+	return &extended.Plan{
+		ID:				plan.ID,
+		Name:			plan.Name,
+		StorageQuota:	plan.StorageQuota,
+		MaxPictures:	plan.MaxPictures,
+		MaxAlbums:		plan.MaxAlbums,
+		MaxSessions:	plan.MaxSessions,
+		Features:		plan.Features,
+	}
+}
+func	newPlan(m *extended.Plan) (*sPlan) {
+	return &sPlan{
+		ID:				m.ID,
+		Name:			m.Name,
+		StorageQuota:	m.StorageQuota,
+		MaxPictures:	m.MaxPictures,
+		MaxAlbums:		m.MaxAlbums,
+		MaxSessions:	m.MaxSessions,
+		Features:		m.Features,
+	}
+}
+
+func	(s *sExtendedServer) CreatePlan(ctx context.Context, req *extended.Plan) (*extended.Plan, error) {
+	plan, err := s.server.CreatePlan(ctx, newPlan(req))
+	if (err != nil) {
+		return nil, err
+	}
+	return newPlanMessage(plan), nil
+}
+
+func	(s *sExtendedServer) UpdatePlan(ctx context.Context, req *extended.Plan) (*extended.Plan, error) {
+	plan, err := s.server.UpdatePlan(ctx, newPlan(req))
+	if (err != nil) {
+		return nil, err
+	}
+	return newPlanMessage(plan), nil
+}
+
+func	(s *sExtendedServer) ListPlans(ctx context.Context, req *extended.ListPlansRequest) (*extended.ListPlansResponse, error) {
+	plans, err := s.server.ListPlans(ctx)
+	if (err != nil) {
+		return nil, err
+	}
+	response := &extended.ListPlansResponse{Plans: []*extended.Plan{}}
+	for _, plan := range plans {
+		response.Plans = append(response.Plans, newPlanMessage(plan))
+	}
+	return response, nil
+}
+
+func	(s *sExtendedServer) SetMemberPlan(ctx context.Context, req *extended.SetMemberPlanRequest) (*extended.SetMemberPlanResponse, error) {
+	if err := s.server.SetMemberPlan(ctx, req.MemberID, req.PlanID); err != nil {
+		return nil, err
+	}
+	return &extended.SetMemberPlanResponse{}, nil
+}
+
+func	(s *sExtendedServer) GetMemberLimits(ctx context.Context, req *extended.GetMemberLimitsRequest) (*extended.MemberLimits, error) {
+	limits, err := s.server.GetMemberLimits(ctx, req.MemberID)
+	if (err != nil) {
+		return nil, err
+	}
+	return &extended.MemberLimits{
+		MemberID:			limits.MemberID,
+		PlanID:				limits.PlanID,
+		PlanName:			limits.PlanName,
+		StorageQuota:		limits.StorageQuota,
+		UsedStorage:		limits.UsedStorage,
+		ReservedStorage:	limits.ReservedStorage,
+		MaxPictures:		limits.MaxPictures,
+		MaxAlbums:			limits.MaxAlbums,
+		MaxSessions:		limits.MaxSessions,
+		Features:			limits.Features,
+	}, nil
+}
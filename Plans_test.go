@@ -0,0 +1,89 @@
+/*******************************************************************************
+** @Author:					Thomas Bouder <Tbouder>
+** @Email:					Tbouder@protonmail.com
+** @Date:					Friday 08 May 2020 - 20:41:17
+** @Filename:				Plans_test.go
+**
+** @Last modified by:		Tbouder
+** @Last modified time:		Saturday 09 May 2020 - 18:12:40
+*******************************************************************************/
+
+
+package			main
+
+import			"context"
+import			"testing"
+import			"google.golang.org/grpc/codes"
+import			"github.com/panghostlin/Members/Extended"
+
+func	TestPlanRPCs(t *testing.T) {
+	client := newExtendedTestClient(t)
+	defer client.close()
+	created := createTestMember(t, client.service, `plans@example.com`)
+
+	family := &extended.Plan{}
+	request := &extended.Plan{Name: ` Family `, StorageQuota: 100, MaxPictures: 10, MaxAlbums: 2, MaxSessions: 1, Features: []string{PLAN_FEATURE_SHARING, PLAN_FEATURE_SHARING}}
+	if code := client.call(`CreatePlan`, request, family); code != codes.OK {
+		t.Fatalf("CreatePlan: expected OK, got %v", code)
+	}
+	if (family.ID == `` || family.Name != `Family` || len(family.Features) != 1) {
+		t.Errorf("unexpected created plan %+v", family)
+	}
+	if code := client.call(`CreatePlan`, &extended.Plan{Name: `Family`}, &extended.Plan{}); code != codes.AlreadyExists {
+		t.Errorf("a duplicated name: expected AlreadyExists, got %v", code)
+	}
+	if code := client.call(`CreatePlan`, &extended.Plan{Name: `Guest`, Features: []string{`unknown`}}, &extended.Plan{}); code != codes.InvalidArgument {
+		t.Errorf("an unknown feature: expected InvalidArgument, got %v", code)
+	}
+
+	family.MaxPictures = 20
+	if code := client.call(`UpdatePlan`, family, &extended.Plan{}); code != codes.OK {
+		t.Fatalf("UpdatePlan: expected OK, got %v", code)
+	}
+	if code := client.call(`UpdatePlan`, &extended.Plan{ID: `unknown`, Name: `Unknown`}, &extended.Plan{}); code != codes.NotFound {
+		t.Errorf("an unknown plan: expected NotFound, got %v", code)
+	}
+
+	plans := &extended.ListPlansResponse{}
+	if code := client.call(`ListPlans`, &extended.ListPlansRequest{}, plans); code != codes.OK {
+		t.Fatalf("ListPlans: expected OK, got %v", code)
+	}
+	if (len(plans.Plans) != 1 || plans.Plans[0].MaxPictures != 20) {
+		t.Errorf("unexpected plans %+v", plans.Plans)
+	}
+
+	if code := client.call(`SetMemberPlan`, &extended.SetMemberPlanRequest{MemberID: created.MemberID, PlanID: `unknown`}, &extended.SetMemberPlanResponse{}); code != codes.NotFound {
+		t.Errorf("an unknown plan: expected NotFound, got %v", code)
+	}
+	if code := client.call(`SetMemberPlan`, &extended.SetMemberPlanRequest{MemberID: created.MemberID, PlanID: family.ID}, &extended.SetMemberPlanResponse{}); code != codes.OK {
+		t.Fatalf("SetMemberPlan: expected OK, got %v", code)
+	}
+	member, _ := client.store.GetMemberByID(context.Background(), created.MemberID)
+	if (member.PlanID != family.ID) {
+		t.Errorf("expected the plan %s, got %s", family.ID, member.PlanID)
+	}
+
+	limits := &extended.MemberLimits{}
+	if code := client.call(`GetMemberLimits`, &extended.GetMemberLimitsRequest{MemberID: created.MemberID}, limits); code != codes.OK {
+		t.Fatalf("GetMemberLimits: expected OK, got %v", code)
+	}
+	if (limits.PlanName != `Family` || limits.StorageQuota != 100 || limits.MaxPictures != 20 || len(limits.Features) != 1) {
+		t.Errorf("unexpected limits %+v", limits)
+	}
+	if code := client.call(`GetMemberLimits`, &extended.GetMemberLimitsRequest{MemberID: `unknown`}, &extended.MemberLimits{}); code != codes.NotFound {
+		t.Errorf("an unknown member: expected NotFound, got %v", code)
+	}
+}
+
+func	TestPlanPolicy(t *testing.T) {
+	policy := defaultAuthorizationPolicy()
+	for _, method := range []string{`CreatePlan`, `UpdatePlan`, `ListPlans`, `SetMemberPlan`} {
+		fullMethod := `/` + EXTENDED_SERVICE_NAME + `/` + method
+		if (!policy.allows(fullMethod, []string{`admin`}) || policy.allows(fullMethod, []string{`proxy`, `pictures`})) {
+			t.Errorf("%s must only be allowed to admin", method)
+		}
+	}
+	if (!policy.allows(`/` + EXTENDED_SERVICE_NAME + `/GetMemberLimits`, []string{`pictures`})) {
+		t.Errorf("GetMemberLimits must be allowed to pictures")
+	}
+}
@@ -0,0 +1,37 @@
+/*******************************************************************************
+** @Author:					Thomas Bouder <Tbouder>
+** @Email:					Tbouder@protonmail.com
+** @Date:					Friday 08 May 2020 - 16:40:22
+** @Filename:				Service.extended.go
+**
+** @Last modified by:		Tbouder
+** @Last modified time:		Saturday 09 May 2020 - 18:12:40
+*******************************************************************************/
+
+
+package			main
+
+import			"google.golang.org/grpc"
+import			"github.com/panghostlin/Members/Extended"
+
+/******************************************************************************
+**	The MembersService of the SDK only declares the RPCs of the Proxy. The
+**	other RPCs (audit, login history, storage, plans and invitations) are
+**	served by the MembersExtendedService, generated from
+**	Members.extended.proto in the Extended package (make proto).
+******************************************************************************/
+const	EXTENDED_SERVICE_NAME = `MembersExtendedService`
+
+/******************************************************************************
+**	The handlers of the extended RPCs. Each one converts the messages and
+**	calls the method of the server with the same name.
+******************************************************************************/
+type	sExtendedServer struct {
+	*server
+}
+
+var		_ extended.MembersExtendedServiceServer = (*sExtendedServer)(nil)
+
+func	registerExtendedService(srv *grpc.Server, service *server) {
+	extended.RegisterMembersExtendedServiceServer(srv, &sExtendedServer{service})
+}
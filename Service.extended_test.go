@@ -0,0 +1,110 @@
+/*******************************************************************************
+** @Author:					Thomas Bouder <Tbouder>
+** @Email:					Tbouder@protonmail.com
+** @Date:					Friday 08 May 2020 - 16:40:22
+** @Filename:				Service.extended_test.go
+**
+** @Last modified by:		Tbouder
+** @Last modified time:		Saturday 09 May 2020 - 18:12:40
+*******************************************************************************/
+
+
+package			main
+
+import			"net"
+import			"context"
+import			"testing"
+import			"google.golang.org/grpc"
+import			"google.golang.org/grpc/codes"
+import			"google.golang.org/grpc/status"
+import			"google.golang.org/grpc/health"
+import			"google.golang.org/grpc/test/bufconn"
+import			"google.golang.org/grpc/health/grpc_health_v1"
+import			"github.com/golang/protobuf/proto"
+import			"github.com/panghostlin/SDK/Members"
+import			"github.com/panghostlin/Members/Extended"
+
+/******************************************************************************
+**	The extended RPCs are called through a gRPC connection, in memory, to
+**	check the generated messages along with the handlers
+******************************************************************************/
+type	sExtendedTestClient struct {
+	srv		*grpc.Server
+	conn	*grpc.ClientConn
+	service	*server
+	store	*sMemoryStore
+}
+
+func	newExtendedTestClient(t *testing.T) (*sExtendedTestClient) {
+	listener := bufconn.Listen(1 << 20)
+	service, store := newTestServer()
+	srv := grpc.NewServer(grpc.ChainUnaryInterceptor(errorsInterceptor))
+	members.RegisterMembersServiceServer(srv, service)
+	registerExtendedService(srv, service)
+	grpc_health_v1.RegisterHealthServer(srv, health.NewServer())
+	go srv.Serve(listener)
+
+	dialer := func(ctx context.Context, address string) (net.Conn, error) {return listener.Dial()}
+	conn, err := grpc.Dial(`bufnet`, grpc.WithContextDialer(dialer), grpc.WithInsecure())
+	if (err != nil) {
+		t.Fatal(err)
+	}
+	return &sExtendedTestClient{srv: srv, conn: conn, service: service, store: store}
+}
+
+func	(c *sExtendedTestClient) close() {
+	c.conn.Close()
+	c.srv.Stop()
+}
+
+func	(c *sExtendedTestClient) call(method string, request, response proto.Message) (codes.Code) {
+	err := c.conn.Invoke(context.Background(), `/` + EXTENDED_SERVICE_NAME + `/` + method, request, response)
+	return status.Code(err)
+}
+
+/******************************************************************************
+**	Every method of the default policy must be served, and every extended
+**	RPC must be in the default policy
+******************************************************************************/
+func	TestExtendedServicePolicy(t *testing.T) {
+	client := newExtendedTestClient(t)
+	defer client.close()
+
+	if err := defaultAuthorizationPolicy().check(client.srv.GetServiceInfo()); err != nil {
+		t.Fatal(err)
+	}
+	for _, method := range client.srv.GetServiceInfo()[EXTENDED_SERVICE_NAME].Methods {
+		if _, ok := defaultAuthorizationPolicy().Methods[`/` + EXTENDED_SERVICE_NAME + `/` + method.Name]; !ok {
+			t.Errorf("%s is missing from the default policy", method.Name)
+		}
+	}
+}
+
+func	TestListAuditEvents(t *testing.T) {
+	client := newExtendedTestClient(t)
+	defer client.close()
+
+	client.store.AppendAuditEvents(context.Background(), []*sAuditEvent{
+		{Type: AUDIT_SIGNUP, ActorID: `member-1`, TargetID: `member-1`, CreatedAt: 1},
+		{Type: AUDIT_LOGIN_FAILED, TargetID: `member-1`, Reason: LOGIN_WRONG_PASSWORD, CreatedAt: 2},
+		{Type: AUDIT_SIGNUP, ActorID: `member-2`, TargetID: `member-2`, CreatedAt: 3},
+	})
+
+	response := &extended.ListAuditEventsResponse{}
+	if code := client.call(`ListAuditEvents`, &extended.ListAuditEventsRequest{MemberID: `member-1`, Limit: 1}, response); code != codes.OK {
+		t.Fatalf("expected OK, got %v", code)
+	}
+	if (len(response.Events) != 1 || response.Events[0].Type != AUDIT_LOGIN_FAILED || response.Events[0].Reason != LOGIN_WRONG_PASSWORD || response.NextBeforeID == 0) {
+		t.Fatalf("unexpected first page %v", response)
+	}
+
+	next := &extended.ListAuditEventsResponse{}
+	client.call(`ListAuditEvents`, &extended.ListAuditEventsRequest{MemberID: `member-1`, BeforeID: response.NextBeforeID, Limit: 1}, next)
+	if (len(next.Events) != 1 || next.Events[0].Type != AUDIT_SIGNUP || next.Events[0].Hash == ``) {
+		t.Fatalf("unexpected second page %v", next)
+	}
+
+	if code := client.call(`ListAuditEvents`, &extended.ListAuditEventsRequest{Type: `unknown`}, response); code != codes.InvalidArgument {
+		t.Errorf("expected InvalidArgument for an unknown type, got %v", code)
+	}
+}
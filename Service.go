@@ -5,38 +5,34 @@
 ** @Filename:				service.go
 **
 ** @Last modified by:		Tbouder
-** @Last modified time:		Wednesday 01 April 2020 - 11:56:53
+** @Last modified time:		Saturday 09 May 2020 - 16:18:22
 *******************************************************************************/
 
 package			main
 
 import			"time"
 import			"context"
-import			"strconv"
-import			"errors"
 import			"strings"
 import			"encoding/base64"
-import			"github.com/microgolang/logs"
 import			"github.com/panghostlin/SDK/Members"
-import			P "github.com/microgolang/postgre"
 
 func (s *server) CheckAccessToken(ctx context.Context, req *members.CheckAccessTokenRequest) (*members.CheckAccessTokenResponse, error) {
 	var	isTokenExpiredByError bool
 	var	isTokenExpired bool
 
-	var	memberID string
-	var	memberAccessToken string
-	var	memberAccessExp int64
-	var	memberRefreshToken string
+	result := `error`
+	defer func() {accessTokenChecksTotal.WithLabelValues(result).Inc()}()
 
 	accessToken, accessClaims, err := GetAccessToken(req.GetAccessToken())
 	if (err != nil) {
 		if (strings.Contains(err.Error(), `token is expired by`)) {
 			isTokenExpiredByError = true
 		} else {
-			return &members.CheckAccessTokenResponse{Success: false}, err
+			result = `invalid`
+			return &members.CheckAccessTokenResponse{Success: false}, errUnauthenticated(`invalid access token`)
 		}
 	}
+	setLogField(ctx, `member_id`, accessClaims.MemberID)
 
 	/**************************************************************************
 	**	Check if the JWT is valid : if it has not expired according to the
@@ -49,67 +45,70 @@ func (s *server) CheckAccessToken(ctx context.Context, req *members.CheckAccessT
 		**	from the database, check if it's valid, and regenerate a new access
 		**	token if it's valid
 		**************************************************************************/
-		err = P.NewSelector(PGR).Select(`RefreshToken`).From(`members`).Where(
-			P.S_SelectorWhere{Key: `ID`, Value: accessClaims.MemberID},
-		).One(&memberRefreshToken)
+		session, err := s.sessionStore.GetSession(ctx, accessClaims.MemberID)
 		if (err != nil) {
-			return &members.CheckAccessTokenResponse{Success: false}, err
+			result = accessTokenErrorResult(err)
+			return &members.CheckAccessTokenResponse{Success: false}, asUnauthenticated(err)
 		}
 
-		refreshToken, refreshClaims, err := GetRefreshToken(memberRefreshToken)
+		refreshToken, refreshClaims, err := GetRefreshToken(session.RefreshToken)
 		if (err != nil) {
-			return &members.CheckAccessTokenResponse{Success: false}, err
+			result = `expired`
+			return &members.CheckAccessTokenResponse{Success: false}, errUnauthenticated(`invalid refresh token`)
 		} else if (!refreshToken.Valid) {
-			logs.Error(`AccessToken & refreshtoken are no longer valids`)
+			result = `expired`
+			logInfo(ctx, `The access token and the refresh token have expired`)
 			return &members.CheckAccessTokenResponse{Success: false}, nil
 		} else if (refreshClaims.MemberID == accessClaims.MemberID) {
 			/******************************************************************
 			**	Check if the JWT memberID is the same as in the Database
 			*******************************************************************/
-			err = P.NewSelector(PGR).Select(`ID`).From(`members`).Where(
-				P.S_SelectorWhere{Key: `ID`, Value: refreshClaims.MemberID},
-			).One(&memberID)
+			member, err := s.memberStore.GetMemberByID(ctx, refreshClaims.MemberID)
 			if (err != nil) {
-				return &members.CheckAccessTokenResponse{Success: false}, err
-			} else if (memberRefreshToken != refreshToken.Raw) {
-				logs.Error(memberRefreshToken, refreshToken.Raw)
+				result = accessTokenErrorResult(err)
+				return &members.CheckAccessTokenResponse{Success: false}, asUnauthenticated(err)
+			} else if (session.RefreshToken != refreshToken.Raw) {
+				result = `revoked`
+				logWarning(ctx, `The refresh token does not match the session, it was revoked`)
 				return &members.CheckAccessTokenResponse{Success: false}, nil
 			}
 
 			/******************************************************************
 			**	If the refresh token is valid, we refresh the access token
 			******************************************************************/
-			authCookie, expTime, err := SetAccessToken(memberID)
+			authCookie, expTime, err := SetAccessToken(member.ID)
 			if (err != nil) {
 				return &members.CheckAccessTokenResponse{Success: false}, err
 			}
 
-			P.NewUpdator(PGR).Set(
-				P.S_UpdatorSetter{Key: `AccessToken`, Value: authCookie},
-				P.S_UpdatorSetter{Key: `AccessExp`, Value: strconv.FormatInt(expTime, 10)},
-			).Where(
-				P.S_UpdatorWhere{Key: `ID`, Value: memberID},
-			).Into(`members`).Do()
+			err = s.sessionStore.SetAccessToken(ctx, member.ID, authCookie, expTime)
+			if (err != nil) {
+				return &members.CheckAccessTokenResponse{Success: false}, err
+			}
+			result = `refreshed`
+			recordAuditEvent(ctx, AUDIT_TOKEN_REFRESHED, member.ID, member.ID, ``)
 			return &members.CheckAccessTokenResponse{
 				Success: true,
-				MemberID: memberID,
+				MemberID: member.ID,
 				AccessToken: &members.Cookie{Value: authCookie, Expiration: expTime},
 			}, nil
 		} else {
+			result = `invalid`
 			return &members.CheckAccessTokenResponse{Success: false}, nil
 		}
 	} else if (!accessToken.Valid) {
+		result = `invalid`
 		return &members.CheckAccessTokenResponse{Success: false}, err
 	} else {
 		/**********************************************************************
 		**	Check if the JWT memberID is the same as in the Database
 		***********************************************************************/
-		err = P.NewSelector(PGR).Select(`ID`, `AccessToken`, `AccessExp`).From(`members`).Where(
-			P.S_SelectorWhere{Key: `ID`, Value: accessClaims.MemberID},
-		).One(&memberID, &memberAccessToken, &memberAccessExp)
+		session, err := s.sessionStore.GetSession(ctx, accessClaims.MemberID)
 		if (err != nil) {
-			return &members.CheckAccessTokenResponse{Success: false}, err
-		} else if (memberAccessToken != req.GetAccessToken()) {
+			result = accessTokenErrorResult(err)
+			return &members.CheckAccessTokenResponse{Success: false}, asUnauthenticated(err)
+		} else if (session.AccessToken != req.GetAccessToken()) {
+			result = `revoked`
 			return &members.CheckAccessTokenResponse{Success: false}, nil
 		}
 		/**********************************************************************
@@ -124,71 +123,95 @@ func (s *server) CheckAccessToken(ctx context.Context, req *members.CheckAccessT
 		// member.AccessToken.Expiration = expTime
 		// Collection.UpdateId(member.ID, member)
 
+		result = `valid`
 		return &members.CheckAccessTokenResponse{
 			Success: true,
-			MemberID: memberID,
-			AccessToken: &members.Cookie{Value: memberAccessToken, Expiration: memberAccessExp},
+			MemberID: accessClaims.MemberID,
+			AccessToken: &members.Cookie{Value: session.AccessToken, Expiration: session.AccessExp},
 		}, nil
 	}
 }
 
 func (s *server) CreateMember(ctx context.Context, req *members.CreateMemberRequest) (*members.CreateMemberResponse, error) {
-	ID, err := P.NewInsertor(PGR).Values(P.S_InsertorWhere{Key: `Email`, Value: req.GetEmail()}).Into(`members`).Do()
+	/**************************************************************************
+	**	Refuse the sign up if the registration mode does not allow it, the
+	**	requests without email, and the passwords which does not match the
+	**	password policy
+	**************************************************************************/
+	redemption, err := registrationRedemption(ctx)
 	if (err != nil) {
 		return &members.CreateMemberResponse{}, err
 	}
+	if (req.GetEmail() == ``) {
+		return &members.CreateMemberResponse{}, errInvalidArgument(`the email is required`, fieldViolation(`email`, `EMAIL_REQUIRED`))
+	}
 
 	/**************************************************************************
-	**	Create an access token for this user
+	**	The ID is generated up front, to create the tokens and store the
+	**	whole member at once
 	**************************************************************************/
-	refreshToken, refreshExpiration, err := SetRefreshToken(ID)
+	ID, err := newUUID()
 	if (err != nil) {
 		return &members.CreateMemberResponse{}, err
 	}
+	member := &sMember{
+		ID: ID,
+		Email: req.GetEmail(),
+		PublicKey: req.GetPublicKey(),
+		PrivateKey: req.GetPrivateKey().GetKey(),
+		PrivateKeyIV: req.GetPrivateKey().GetIV(),
+		PrivateKeySalt: req.GetPrivateKey().GetSalt(),
+	}
 
 	/**************************************************************************
-	**	Create a refresh token for this user
+	**	Check the password against the policy and generate the hashes for
+	**	this user
 	**************************************************************************/
-	accessToken, accessExpiration, err := SetAccessToken(ID)
-	if (err != nil) {
+	if err := setNewPassword(ctx, member, req.GetPassword()); err != nil {
 		return &members.CreateMemberResponse{}, err
 	}
-	
+
 	/**************************************************************************
-	**	Generate the hashes for this user
+	**	Create an access token for this user
 	**************************************************************************/
-	plainArgon2Hash, plainScryptHash, block, err := GeneratePasswordHash(req.GetPassword())
-	argon2Hash, argon2IV, scryptHash, scryptIV, err := EncryptPasswordHash(plainArgon2Hash, plainScryptHash, block)
+	refreshToken, refreshExpiration, err := SetRefreshToken(ID)
 	if (err != nil) {
 		return &members.CreateMemberResponse{}, err
 	}
 
 	/**************************************************************************
-	**	Insert the new user in the database
+	**	Create a refresh token for this user
 	**************************************************************************/
-	err = P.NewUpdator(PGR).Set(
-		P.S_UpdatorSetter{Key: `AccessToken`, Value: accessToken},
-		P.S_UpdatorSetter{Key: `AccessExp`, Value: strconv.FormatInt(accessExpiration, 10)},
-		P.S_UpdatorSetter{Key: `RefreshToken`, Value: refreshToken},
-		P.S_UpdatorSetter{Key: `RefreshExp`, Value: strconv.FormatInt(refreshExpiration, 10)},
-		P.S_UpdatorSetter{Key: `PublicKey`, Value: req.GetPublicKey()},
-		P.S_UpdatorSetter{Key: `PrivateKey`, Value: req.GetPrivateKey().GetKey()},
-		P.S_UpdatorSetter{Key: `PrivateKeyIV`, Value: req.GetPrivateKey().GetIV()},
-		P.S_UpdatorSetter{Key: `PrivateKeySalt`, Value: req.GetPrivateKey().GetSalt()},
-		P.S_UpdatorSetter{Key: `PasswordArgon2Hash`, Value: base64.RawStdEncoding.EncodeToString(argon2Hash)},
-		P.S_UpdatorSetter{Key: `PasswordArgon2IV`, Value: base64.RawStdEncoding.EncodeToString(argon2IV)},
-		P.S_UpdatorSetter{Key: `PasswordScryptHash`, Value: base64.RawStdEncoding.EncodeToString(scryptHash)},
-		P.S_UpdatorSetter{Key: `PasswordScryptIV`, Value: base64.RawStdEncoding.EncodeToString(scryptIV)},
-
-	).Where(
-		P.S_UpdatorWhere{Key: `ID`, Value: ID},
-	).Into(`members`).Do()
-
+	accessToken, accessExpiration, err := SetAccessToken(ID)
 	if (err != nil) {
-		P.NewDeletor(PGR).Into(`members`).Where(P.S_DeletorWhere{Key: `ID`, Value: ID}).Do()
 		return &members.CreateMemberResponse{}, err
 	}
-	
+
+	/**************************************************************************
+	**	Insert the new user in the database, in a single transaction with
+	**	the use of it's invitation
+	**************************************************************************/
+	err = s.memberStore.CreateMember(ctx, member, &sSession{
+		AccessToken: accessToken,
+		AccessExp: accessExpiration,
+		RefreshToken: refreshToken,
+		RefreshExp: refreshExpiration,
+	}, redemption)
+	setLogField(ctx, `member_id`, ID)
+	if (err == ErrMemberAlreadyExists) {
+		return &members.CreateMemberResponse{}, errAlreadyExists(`member`, req.GetEmail())
+	} else if (err == ErrInvitationInvalid) {
+		return &members.CreateMemberResponse{}, errPermissionDenied(`the invitation code is invalid, expired or already used`)
+	} else if (err != nil) {
+		return &members.CreateMemberResponse{}, err
+	}
+	if (redemption != nil) {
+		recordAuditEvent(ctx, AUDIT_SIGNUP, ID, ID, `invitation:` + redemption.InvitationID)
+	} else {
+		recordAuditEvent(ctx, AUDIT_SIGNUP, ID, ID, ``)
+	}
+	s.recordLogin(ctx, &sMember{ID: ID, Email: req.GetEmail()}, false)
+
 	return &members.CreateMemberResponse{
 		MemberID: ID,
 		AccessToken: &members.Cookie{
@@ -204,136 +227,186 @@ func (s *server) CreateMember(ctx context.Context, req *members.CreateMemberRequ
 	}, nil
 }
 
-func (s *server) LoginMember(ctx context.Context, req *members.LoginMemberRequest) (*members.LoginMemberResponse, error) {
-	var	memberID string
-	var	B64PasswordArgon2Hash string
-	var	B64PasswordArgon2IV string
-	var	B64PasswordScryptHash string
-	var	B64PasswordScryptIV string
-	var	PublicKey string
-	var	PrivateKey string
-	var	PrivateKeyIV string
-	var	PrivateKeySalt string
-	var	err error
-
-	/**************************************************************************
-	**	SELECT the member matching the requested Email from the member Table
-	**	and get it's ID
-	**************************************************************************/
-	err = P.NewSelector(PGR).Select(
-		`ID`,
-		`PasswordArgon2Hash`,
-		`PasswordArgon2IV`,
-		`PasswordScryptHash`,
-		`PasswordScryptIV`,
-		`PublicKey`,
-		`PrivateKey`,
-		`PrivateKeyIV`,
-		`PrivateKeySalt`,
-	).From(`members`).Where(
-		P.S_SelectorWhere{Key: `Email`, Value: req.GetEmail()},
-	).One(
-		&memberID,
-		&B64PasswordArgon2Hash,
-		&B64PasswordArgon2IV,
-		&B64PasswordScryptHash,
-		&B64PasswordScryptIV,
-		&PublicKey,
-		&PrivateKey,
-		&PrivateKeyIV,
-		&PrivateKeySalt,
-	)
+/******************************************************************************
+**	Check the access token of a member, as CheckAccessToken does, and return
+**	the ID of the member it belongs to. An expired token is refused : the
+**	caller must refresh it with CheckAccessToken first.
+******************************************************************************/
+func	(s *server) authenticateAccessToken(ctx context.Context, accessToken string) (string, error) {
+	token, claims, err := GetAccessToken(accessToken)
+	if (err != nil || !token.Valid || time.Now().Unix() > claims.ExpiresAt) {
+		return ``, errUnauthenticated(`invalid access token`)
+	}
+	session, err := s.sessionStore.GetSession(ctx, claims.MemberID)
 	if (err != nil) {
-		return &members.LoginMemberResponse{}, err
+		return ``, asUnauthenticated(err)
+	} else if (session.AccessToken != accessToken) {
+		return ``, errUnauthenticated(`revoked access token`)
+	}
+	setLogField(ctx, `member_id`, claims.MemberID)
+	return claims.MemberID, nil
+}
+
+/******************************************************************************
+**	Check the password of the member with the email. If the email is
+**	unknown, the password is checked against a dummy hash to spend the same
+**	time as for a known email, and the same error is returned, to avoid
+**	leaking which emails are registered. The member is returned whenever the
+**	email is known, with the reason counted by the login metrics.
+******************************************************************************/
+func	(s *server) authenticateMember(ctx context.Context, email, password string) (*sMember, bool, string, error) {
+	member, err := s.memberStore.GetMemberByEmail(ctx, email)
+	if (err != nil && err != ErrMemberNotFound) {
+		return nil, false, LOGIN_ERROR, err
+	}
+
+	var	argon2Hash []byte
+	var	scryptHash []byte
+	if (member == nil) {
+		argon2Hash, scryptHash, err = getDummyPasswordHash()
+		if (err != nil) {
+			return nil, false, LOGIN_ERROR, err
+		}
+	} else {
+		setLogField(ctx, `member_id`, member.ID)
+		PasswordArgon2Hash, _ := base64.RawStdEncoding.DecodeString(member.PasswordArgon2Hash)
+		PasswordArgon2IV, _ := base64.RawStdEncoding.DecodeString(member.PasswordArgon2IV)
+		PasswordScryptHash, _ := base64.RawStdEncoding.DecodeString(member.PasswordScryptHash)
+		PasswordScryptIV, _ := base64.RawStdEncoding.DecodeString(member.PasswordScryptIV)
+
+		argon2Hash, scryptHash, err = DecryptPasswordHash(PasswordArgon2Hash, PasswordArgon2IV, PasswordScryptHash, PasswordScryptIV)
+		if (err != nil) {
+			return member, false, LOGIN_ERROR, err
+		}
+	}
+
+	release, err := hashPool.acquire(ctx)
+	if (isHashPoolExhausted(err)) {
+		return member, false, LOGIN_HASH_POOL_EXHAUSTED, err
+	} else if (err != nil) {
+		return member, false, LOGIN_ERROR, err
 	}
+	hashMatches, needsUpgrade := verifyMemberPasswordHash(ctx, password, string(argon2Hash), string(scryptHash))
+	release()
+	if (member == nil) {
+		return nil, false, LOGIN_UNKNOWN_EMAIL, ErrInvalidCredentials
+	} else if (!hashMatches) {
+		return member, false, LOGIN_WRONG_PASSWORD, ErrInvalidCredentials
+	}
+	return member, needsUpgrade, LOGIN_SUCCESS, nil
+}
 
+func (s *server) LoginMember(ctx context.Context, req *members.LoginMemberRequest) (*members.LoginMemberResponse, error) {
 	/**************************************************************************
-	**	We got the memberID, we can not check the password hash with the
-	**	password send as argument
+	**	Check the password of the member matching the requested Email and get
+	**	it's ID
 	**************************************************************************/
-	PasswordArgon2Hash, _ := base64.RawStdEncoding.DecodeString(B64PasswordArgon2Hash)
-	PasswordArgon2IV, _ := base64.RawStdEncoding.DecodeString(B64PasswordArgon2IV)
-	PasswordScryptHash, _ := base64.RawStdEncoding.DecodeString(B64PasswordScryptHash)
-	PasswordScryptIV, _ := base64.RawStdEncoding.DecodeString(B64PasswordScryptIV)
+	var	memberID string
+	reason := LOGIN_ERROR
+	defer func() {
+		countLogin(reason)
+		recordLoginAttempt(ctx, memberID, reason)
+	}()
 
-	argon2Hash, scryptHash, err := DecryptPasswordHash(PasswordArgon2Hash, PasswordArgon2IV, PasswordScryptHash, PasswordScryptIV)
+	member, needsUpgrade, result, err := s.authenticateMember(ctx, req.GetEmail(), req.GetPassword())
+	if (member != nil) {
+		memberID = member.ID
+	}
 	if (err != nil) {
+		reason = result
 		return &members.LoginMemberResponse{}, err
 	}
-
-	hashMatches := verifyMemberPasswordHash(req.GetPassword(), string(argon2Hash), string(scryptHash))
-	if (!hashMatches) {
-		return &members.LoginMemberResponse{}, errors.New(`The hashes does not matches`)
+	if (member.PasswordChangeRequired) {
+		reason = LOGIN_PASSWORD_CHANGE_REQUIRED
+		return &members.LoginMemberResponse{}, errFailedPrecondition(`the password must be changed`, `PASSWORD_CHANGE_REQUIRED`)
 	}
 
 	/**************************************************************************
 	**	The password matches, we can now regenerate the member access token
 	**	from it's memberID
 	**************************************************************************/
-	accessToken, accessExpiration, err := SetAccessToken(memberID)
+	accessToken, accessExpiration, err := SetAccessToken(member.ID)
 	if (err != nil) {
 		return &members.LoginMemberResponse{}, err
 	}
 	/**************************************************************************
 	**	Same for refresh -> New login
 	**************************************************************************/
-	refreshToken, refreshExpiration, err := SetRefreshToken(memberID)
+	refreshToken, refreshExpiration, err := SetRefreshToken(member.ID)
 	if (err != nil) {
 		return &members.LoginMemberResponse{}, err
 	}
 
+	/**************************************************************************
+	**	The member was imported with a legacy hash : now that the password is
+	**	verified, we can replace it with the native argon2 and scrypt hashes
+	**************************************************************************/
+	if (needsUpgrade) {
+		if err := setPasswordHashes(ctx, member, req.GetPassword()); err != nil {
+			return &members.LoginMemberResponse{}, err
+		}
+		if err := s.memberStore.UpdateMember(ctx, member); err != nil {
+			return &members.LoginMemberResponse{}, err
+		}
+	}
+
 	/**************************************************************************
 	**	We can now update the user in the database
 	**************************************************************************/
-	err = P.NewUpdator(PGR).Set(
-		P.S_UpdatorSetter{Key: `AccessToken`, Value: accessToken},
-		P.S_UpdatorSetter{Key: `AccessExp`, Value: strconv.FormatInt(accessExpiration, 10)},
-		P.S_UpdatorSetter{Key: `RefreshToken`, Value: refreshToken},
-		P.S_UpdatorSetter{Key: `RefreshExp`, Value: strconv.FormatInt(refreshExpiration, 10)},
-	).Where(
-		P.S_UpdatorWhere{Key: `ID`, Value: memberID},
-	).Into(`members`).Do()
+	err = s.sessionStore.SetSession(ctx, member.ID, &sSession{
+		AccessToken: accessToken,
+		AccessExp: accessExpiration,
+		RefreshToken: refreshToken,
+		RefreshExp: refreshExpiration,
+	})
 	if (err != nil) {
 		return &members.LoginMemberResponse{}, err
 	}
 
+	s.recordLogin(ctx, member, true)
+
 	/**************************************************************************
 	**	Send back the informations to the Proxy
 	**************************************************************************/
+	reason = LOGIN_SUCCESS
 	return &members.LoginMemberResponse{
-		MemberID: memberID,
+		MemberID: member.ID,
 		AccessToken: &members.Cookie{
 			Value: accessToken,
 			Expiration: accessExpiration,
 		},
 		Keys: &members.Keys{
-			PrivateKey: PrivateKey,
-			PrivateSalt: PrivateKeySalt,
-			PrivateIV: PrivateKeyIV,
-			PublicKey: PublicKey,
+			PrivateKey: member.PrivateKey,
+			PrivateSalt: member.PrivateKeySalt,
+			PrivateIV: member.PrivateKeyIV,
+			PublicKey: member.PublicKey,
 		},
 	}, nil
 }
 
 func (s *server) GetMember(ctx context.Context, req *members.GetMemberRequest) (*members.GetMemberResponse, error) {
-	var	err error
-	var	response members.GetMemberResponse
-
 	/**************************************************************************
 	**	SELECT the member matching the requested Email from the member Table
 	**	and get it's ID
 	**************************************************************************/
-	err = P.NewSelector(PGR).
-	Select(`ID`, `Email`, `UsedStorage`, `FullUsedStorage`).
-	From(`members`).
-	Where(P.S_SelectorWhere{Key: `ID`, Value: req.GetMemberID()}).
-	One(&response.MemberID, &response.Email, &response.UsedStorage, &response.FullUsedStorage)
-	if (err != nil) {
+	if (req.GetMemberID() == ``) {
+		return &members.GetMemberResponse{}, errInvalidArgument(`the memberID is required`, fieldViolation(`memberID`, `MEMBER_ID_REQUIRED`))
+	}
+	setLogField(ctx, `member_id`, req.GetMemberID())
+	member, err := s.memberStore.GetMemberByID(ctx, req.GetMemberID())
+	if (err == ErrMemberNotFound) {
+		return &members.GetMemberResponse{}, errNotFound(`member`, req.GetMemberID())
+	} else if (err != nil) {
 		return &members.GetMemberResponse{}, err
 	}
 
 	/**************************************************************************
 	**	Send back the informations to the Proxy
 	**************************************************************************/
-	return &response, nil
+	return &members.GetMemberResponse{
+		MemberID: member.ID,
+		Email: member.Email,
+		UsedStorage: float32(member.UsedStorage),
+		FullUsedStorage: float32(member.FullUsedStorage),
+	}, nil
 }
@@ -0,0 +1,287 @@
+/*******************************************************************************
+** @Author:					Thomas Bouder <Tbouder>
+** @Email:					Tbouder@protonmail.com
+** @Date:					Friday 08 May 2020 - 11:20:52
+** @Filename:				Service_test.go
+**
+** @Last modified by:		Tbouder
+** @Last modified time:		Saturday 09 May 2020 - 14:51:40
+*******************************************************************************/
+
+
+package			main
+
+import			"time"
+import			"context"
+import			"testing"
+import			"google.golang.org/grpc/codes"
+import			"github.com/panghostlin/SDK/Members"
+import			jwtGo "github.com/dgrijalva/jwt-go"
+
+/******************************************************************************
+**	Every case runs against a new server and a new store, in memory and on
+**	SQLite. run calls the RPCs and returns the response to check with the
+**	error, and check verifies what the store holds afterwards.
+******************************************************************************/
+type	sServiceCase struct {
+	name	string
+	run		func(t *testing.T, s *server) (interface{}, error)
+	code	codes.Code
+	check	func(t *testing.T, store sStore, response interface{})
+}
+
+func	createTestMember(t *testing.T, s *server, email string) (*members.CreateMemberResponse) {
+	response, err := s.CreateMember(context.Background(), &members.CreateMemberRequest{
+		Email:		email,
+		Password:	TEST_PASSWORD,
+		PublicKey:	`public-key`,
+		PrivateKey:	&members.CryptedPrivate{Key: `private-key`, IV: `private-iv`, Salt: `private-salt`},
+	})
+	if (err != nil) {
+		t.Fatalf("CreateMember(%s): %v", email, err)
+	}
+	return response
+}
+
+func	expiredAccessToken(t *testing.T, memberID string) (string) {
+	claims := &JWTClaims{
+		MemberID:		memberID,
+		StandardClaims:	jwtGo.StandardClaims{ExpiresAt: time.Now().Add(-time.Minute).Unix()},
+	}
+	token, err := jwtGo.NewWithClaims(jwtGo.SigningMethodHS256, claims).SignedString([]byte(config.Keys.JWTAccess))
+	if (err != nil) {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func	expectMemberCount(t *testing.T, store sStore, expected int64) {
+	count, err := store.CountMembers(context.Background())
+	if (err != nil || count != expected) {
+		t.Errorf("expected %d stored members, got %d (%v)", expected, count, err)
+	}
+}
+
+func	expectSession(t *testing.T, store sStore, memberID, accessToken string) {
+	session, err := store.GetSession(context.Background(), memberID)
+	if (err != nil) {
+		t.Fatalf("GetSession: %v", err)
+	}
+	if (session.AccessToken != accessToken) {
+		t.Errorf("expected the stored access token %.16s..., got %.16s...", accessToken, session.AccessToken)
+	}
+}
+
+var		serviceCases = []sServiceCase{
+	{
+		name:	`CreateMember stores the member and its session`,
+		run:	func(t *testing.T, s *server) (interface{}, error) {
+			return s.CreateMember(context.Background(), &members.CreateMemberRequest{Email: `New@Example.com`, Password: TEST_PASSWORD, PublicKey: `public-key`})
+		},
+		code:	codes.OK,
+		check:	func(t *testing.T, store sStore, response interface{}) {
+			created := response.(*members.CreateMemberResponse)
+			member, err := store.GetMemberByID(context.Background(), created.MemberID)
+			if (err != nil || member.Email != `new@example.com` || member.PublicKey != `public-key` || member.PasswordArgon2Hash == ``) {
+				t.Errorf("unexpected stored member %+v (%v)", member, err)
+			}
+			expectSession(t, store, created.MemberID, created.AccessToken.Value)
+		},
+	},
+	{
+		name:	`CreateMember refuses a duplicate email`,
+		run:	func(t *testing.T, s *server) (interface{}, error) {
+			createTestMember(t, s, `taken@example.com`)
+			return s.CreateMember(context.Background(), &members.CreateMemberRequest{Email: `TAKEN@example.com`, Password: TEST_PASSWORD})
+		},
+		code:	codes.AlreadyExists,
+		check:	func(t *testing.T, store sStore, response interface{}) {
+			expectMemberCount(t, store, 1)
+		},
+	},
+	{
+		name:	`CreateMember refuses an empty email`,
+		run:	func(t *testing.T, s *server) (interface{}, error) {
+			return s.CreateMember(context.Background(), &members.CreateMemberRequest{Email: ``, Password: TEST_PASSWORD})
+		},
+		code:	codes.InvalidArgument,
+		check:	func(t *testing.T, store sStore, response interface{}) {
+			expectMemberCount(t, store, 0)
+		},
+	},
+	{
+		name:	`LoginMember replaces the session`,
+		run:	func(t *testing.T, s *server) (interface{}, error) {
+			createTestMember(t, s, `login@example.com`)
+			return s.LoginMember(context.Background(), &members.LoginMemberRequest{Email: `Login@example.com`, Password: TEST_PASSWORD})
+		},
+		code:	codes.OK,
+		check:	func(t *testing.T, store sStore, response interface{}) {
+			login := response.(*members.LoginMemberResponse)
+			if (login.Keys.GetPrivateKey() != `private-key` || login.Keys.GetPrivateSalt() != `private-salt`) {
+				t.Errorf("unexpected keys %+v", login.Keys)
+			}
+			expectSession(t, store, login.MemberID, login.AccessToken.Value)
+		},
+	},
+	{
+		name:	`LoginMember records the login of the known device`,
+		run:	func(t *testing.T, s *server) (interface{}, error) {
+			createTestMember(t, s, `history@example.com`)
+			return s.LoginMember(context.Background(), &members.LoginMemberRequest{Email: `history@example.com`, Password: TEST_PASSWORD})
+		},
+		code:	codes.OK,
+		check:	func(t *testing.T, store sStore, response interface{}) {
+			logins, err := store.ListLogins(context.Background(), response.(*members.LoginMemberResponse).MemberID, 10)
+			if (err != nil || len(logins) != 2) {
+				t.Fatalf("expected the sign up and the login, got %d (%v)", len(logins), err)
+			}
+			if (logins[0].ID <= logins[1].ID || logins[0].NewDevice || logins[1].NewDevice) {
+				t.Errorf("unexpected logins %+v %+v", logins[0], logins[1])
+			}
+		},
+	},
+	{
+		name:	`LoginMember refuses a wrong password`,
+		run:	func(t *testing.T, s *server) (interface{}, error) {
+			created := createTestMember(t, s, `wrong@example.com`)
+			_, err := s.LoginMember(context.Background(), &members.LoginMemberRequest{Email: `wrong@example.com`, Password: `not-` + TEST_PASSWORD})
+			return created, err
+		},
+		code:	codes.Unauthenticated,
+		check:	func(t *testing.T, store sStore, response interface{}) {
+			created := response.(*members.CreateMemberResponse)
+			expectSession(t, store, created.MemberID, created.AccessToken.Value)
+		},
+	},
+	{
+		name:	`LoginMember refuses an unknown email`,
+		run:	func(t *testing.T, s *server) (interface{}, error) {
+			return s.LoginMember(context.Background(), &members.LoginMemberRequest{Email: `unknown@example.com`, Password: TEST_PASSWORD})
+		},
+		code:	codes.Unauthenticated,
+		check:	func(t *testing.T, store sStore, response interface{}) {
+			expectMemberCount(t, store, 0)
+			if _, err := store.GetMemberByEmail(context.Background(), `unknown@example.com`); err != ErrMemberNotFound {
+				t.Errorf("expected no member, got %v", err)
+			}
+		},
+	},
+	{
+		name:	`CheckAccessToken accepts the current access token`,
+		run:	func(t *testing.T, s *server) (interface{}, error) {
+			created := createTestMember(t, s, `valid@example.com`)
+			return s.CheckAccessToken(context.Background(), &members.CheckAccessTokenRequest{AccessToken: created.AccessToken.Value})
+		},
+		code:	codes.OK,
+		check:	func(t *testing.T, store sStore, response interface{}) {
+			checked := response.(*members.CheckAccessTokenResponse)
+			if (!checked.Success) {
+				t.Fatalf("expected a valid token")
+			}
+			expectSession(t, store, checked.MemberID, checked.AccessToken.Value)
+		},
+	},
+	{
+		name:	`CheckAccessToken refreshes an expired access token`,
+		run:	func(t *testing.T, s *server) (interface{}, error) {
+			created := createTestMember(t, s, `expired@example.com`)
+			return s.CheckAccessToken(context.Background(), &members.CheckAccessTokenRequest{AccessToken: expiredAccessToken(t, created.MemberID)})
+		},
+		code:	codes.OK,
+		check:	func(t *testing.T, store sStore, response interface{}) {
+			checked := response.(*members.CheckAccessTokenResponse)
+			if (!checked.Success || checked.AccessToken.Expiration <= time.Now().Unix()) {
+				t.Fatalf("expected a refreshed token, got %+v", checked)
+			}
+			expectSession(t, store, checked.MemberID, checked.AccessToken.Value)
+		},
+	},
+	{
+		name:	`CheckAccessToken refuses an expired access token once the session is revoked`,
+		run:	func(t *testing.T, s *server) (interface{}, error) {
+			created := createTestMember(t, s, `revoked@example.com`)
+			s.sessionStore.SetSession(context.Background(), created.MemberID, &sSession{})
+			_, err := s.CheckAccessToken(context.Background(), &members.CheckAccessTokenRequest{AccessToken: expiredAccessToken(t, created.MemberID)})
+			return created, err
+		},
+		code:	codes.Unauthenticated,
+		check:	func(t *testing.T, store sStore, response interface{}) {
+			expectSession(t, store, response.(*members.CreateMemberResponse).MemberID, ``)
+		},
+	},
+	{
+		name:	`GetMember returns the created member`,
+		run:	func(t *testing.T, s *server) (interface{}, error) {
+			created := createTestMember(t, s, `Get@Example.com`)
+			return s.GetMember(context.Background(), &members.GetMemberRequest{MemberID: created.MemberID})
+		},
+		code:	codes.OK,
+		check:	func(t *testing.T, store sStore, response interface{}) {
+			got := response.(*members.GetMemberResponse)
+			member, err := store.GetMemberByID(context.Background(), got.MemberID)
+			if (err != nil || got.Email != `get@example.com` || member.Email != got.Email) {
+				t.Errorf("unexpected member %+v, stored %+v (%v)", got, member, err)
+			}
+		},
+	},
+	{
+		name:	`GetMember refuses an unknown member`,
+		run:	func(t *testing.T, s *server) (interface{}, error) {
+			return s.GetMember(context.Background(), &members.GetMemberRequest{MemberID: `00000000-0000-4000-8000-000000000000`})
+		},
+		code:	codes.NotFound,
+		check:	func(t *testing.T, store sStore, response interface{}) {
+			expectMemberCount(t, store, 0)
+		},
+	},
+	{
+		name:	`ListAuditEvents returns the chained events`,
+		run:	func(t *testing.T, s *server) (interface{}, error) {
+			err := s.auditStore.AppendAuditEvents(context.Background(), []*sAuditEvent{
+				{Type: AUDIT_SIGNUP, ActorID: `member-1`, TargetID: `member-1`, CreatedAt: 1},
+				{Type: AUDIT_LOGIN_FAILED, TargetID: `member-1`, Reason: LOGIN_WRONG_PASSWORD, CreatedAt: 2},
+			})
+			if (err != nil) {
+				t.Fatalf("AppendAuditEvents: %v", err)
+			}
+			events, _, err := s.ListAuditEvents(context.Background(), sAuditFilter{MemberID: `member-1`})
+			return events, err
+		},
+		code:	codes.OK,
+		check:	func(t *testing.T, store sStore, response interface{}) {
+			events := response.([]*sAuditEvent)
+			if (len(events) != 2 || events[0].Type != AUDIT_LOGIN_FAILED || events[0].PreviousHash != events[1].Hash) {
+				t.Fatalf("unexpected events %+v", events)
+			}
+			if checked, err := verifyAuditChain(context.Background(), store); err != nil || checked != 2 {
+				t.Errorf("expected a valid chain of 2 events, got %d (%v)", checked, err)
+			}
+		},
+	},
+}
+
+var		serviceStores = []struct {
+	name	string
+	open	func(t *testing.T) (sStore, func())
+}{
+	{`memory`, func(t *testing.T) (sStore, func()) {return newMemoryStore(), func() {}}},
+	{`sqlite`, func(t *testing.T) (sStore, func()) {return newSQLiteTestStore(t)}},
+}
+
+func	TestService(t *testing.T) {
+	for _, backend := range serviceStores {
+		for _, test := range serviceCases {
+			t.Run(backend.name + `/` + test.name, func(t *testing.T) {
+				store, close := backend.open(t)
+				defer close()
+				s := newServerWithStore(store)
+				response, err := test.run(t, s)
+				if (statusCode(err) != test.code) {
+					t.Fatalf("expected %v, got %v", test.code, err)
+				}
+				test.check(t, store, response)
+			})
+		}
+	}
+}
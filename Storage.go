@@ -0,0 +1,161 @@
+/*******************************************************************************
+** @Author:					Thomas Bouder <Tbouder>
+** @Email:					Tbouder@protonmail.com
+** @Date:					Tuesday 05 May 2020 - 11:47:32
+** @Filename:				Storage.go
+**
+** @Last modified by:		Tbouder
+** @Last modified time:		Saturday 09 May 2020 - 18:12:40
+*******************************************************************************/
+
+
+package			main
+
+import			"time"
+import			"context"
+import			"github.com/panghostlin/Members/Extended"
+
+/******************************************************************************
+**	The Pictures service reserves the storage of an upload before accepting
+**	it, then commits the reservation once the upload is stored, or releases
+**	it if the upload failed. The storage of the deleted pictures is freed
+**	with ReleaseStorage, without a reservation. Only the Pictures service
+**	may update the counters, and only an admin may change a quota.
+******************************************************************************/
+const	DEFAULT_STORAGE_RESERVATION_TIMEOUT = 3600
+
+func	validateStorageRequest(memberID string, bytes int64) (error) {
+	if (memberID == ``) {
+		return errInvalidArgument(`the memberID is required`, fieldViolation(`memberID`, `MEMBER_ID_REQUIRED`))
+	}
+	if (bytes < 0) {
+		return errInvalidArgument(`the size must be positive`, fieldViolation(`bytes`, `SIZE_NEGATIVE`))
+	}
+	return nil
+}
+
+/******************************************************************************
+**	Reserve the bytes of an upload. Returns the ID of the reservation and
+**	its expiration, or a ResourceExhausted error if the quota is exceeded.
+******************************************************************************/
+func	(s *server) ReserveStorage(ctx context.Context, memberID string, bytes int64) (string, int64, error) {
+	if err := validateStorageRequest(memberID, bytes); err != nil {
+		return ``, 0, err
+	}
+	setLogField(ctx, `member_id`, memberID)
+
+	ID, err := newUUID()
+	if (err != nil) {
+		return ``, 0, err
+	}
+	now := time.Now()
+	reservation := &sReservation{
+		ID:			ID,
+		MemberID:	memberID,
+		Bytes:		bytes,
+		ExpiresAt:	now.Add(time.Duration(config.Storage.ReservationTimeout) * time.Second).Unix(),
+	}
+	err = s.storageStore.ReserveStorage(ctx, reservation, config.Storage.DefaultQuota, now.Unix())
+	if (err == ErrQuotaExceeded) {
+		return ``, 0, errQuotaExceeded(`member:` + memberID, `the storage quota is exceeded`)
+	} else if (err == ErrMemberNotFound) {
+		return ``, 0, errNotFound(`member`, memberID)
+	} else if (err != nil) {
+		return ``, 0, err
+	}
+	return reservation.ID, reservation.ExpiresAt, nil
+}
+
+/******************************************************************************
+**	Add the reserved bytes to the used storage. fullBytes is the size of the
+**	upload with its generated versions, the reserved bytes if lower.
+******************************************************************************/
+func	(s *server) CommitStorage(ctx context.Context, memberID, reservationID string, fullBytes int64) (error) {
+	if err := validateStorageRequest(memberID, fullBytes); err != nil {
+		return err
+	}
+	setLogField(ctx, `member_id`, memberID)
+
+	err := s.storageStore.CommitStorage(ctx, memberID, reservationID, fullBytes, time.Now().Unix())
+	if (err == ErrReservationNotFound) {
+		return errNotFound(`storage reservation`, reservationID)
+	}
+	return err
+}
+
+/******************************************************************************
+**	Release a reservation, or, without reservationID, free the storage of
+**	deleted content
+******************************************************************************/
+func	(s *server) ReleaseStorage(ctx context.Context, memberID, reservationID string, bytes, fullBytes int64) (error) {
+	if err := validateStorageRequest(memberID, bytes); err != nil {
+		return err
+	} else if err := validateStorageRequest(memberID, fullBytes); err != nil {
+		return err
+	}
+	setLogField(ctx, `member_id`, memberID)
+
+	if (reservationID != ``) {
+		err := s.storageStore.ReleaseReservation(ctx, memberID, reservationID)
+		if (err == ErrReservationNotFound) {
+			return errNotFound(`storage reservation`, reservationID)
+		}
+		return err
+	}
+	err := s.storageStore.ReleaseStorage(ctx, memberID, bytes, fullBytes)
+	if (err == ErrMemberNotFound) {
+		return errNotFound(`member`, memberID)
+	}
+	return err
+}
+
+/******************************************************************************
+**	Set the quota of a member, in bytes. 0 restores the quota of the plan.
+******************************************************************************/
+func	(s *server) SetStorageQuota(ctx context.Context, memberID string, quota int64) (error) {
+	if err := validateStorageRequest(memberID, quota); err != nil {
+		return err
+	}
+	setLogField(ctx, `member_id`, memberID)
+
+	err := s.storageStore.SetStorageQuota(ctx, memberID, quota)
+	if (err == ErrMemberNotFound) {
+		return errNotFound(`member`, memberID)
+	} else if (err != nil) {
+		return err
+	}
+	recordAuditEvent(ctx, AUDIT_ADMIN_ACTION, callerName(ctx), memberID, `storage_quota_changed`)
+	return nil
+}
+
+/******************************************************************************
+**	The storage RPCs of the MembersExtendedService
+******************************************************************************/
+func	(s *sExtendedServer) ReserveStorage(ctx context.Context, req *extended.ReserveStorageRequest) (*extended.ReserveStorageResponse, error) {
+	reservationID, expiresAt, err := s.server.ReserveStorage(ctx, req.MemberID, req.Bytes)
+	if (err != nil) {
+		return nil, err
+	}
+	return &extended.ReserveStorageResponse{ReservationID: reservationID, ExpiresAt: expiresAt}, nil
+}
+
+func	(s *sExtendedServer) CommitStorage(ctx context.Context, req *extended.CommitStorageRequest) (*extended.StorageResponse, error) {
+	if err := s.server.CommitStorage(ctx, req.MemberID, req.ReservationID, req.FullBytes); err != nil {
+		return nil, err
+	}
+	return &extended.StorageResponse{}, nil
+}
+
+func	(s *sExtendedServer) ReleaseStorage(ctx context.Context, req *extended.ReleaseStorageRequest) (*extended.StorageResponse, error) {
+	if err := s.server.ReleaseStorage(ctx, req.MemberID, req.ReservationID, req.Bytes, req.FullBytes); err != nil {
+		return nil, err
+	}
+	return &extended.StorageResponse{}, nil
+}
+
+func	(s *sExtendedServer) SetStorageQuota(ctx context.Context, req *extended.SetStorageQuotaRequest) (*extended.StorageResponse, error) {
+	if err := s.server.SetStorageQuota(ctx, req.MemberID, req.Quota); err != nil {
+		return nil, err
+	}
+	return &extended.StorageResponse{}, nil
+}
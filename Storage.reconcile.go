@@ -0,0 +1,250 @@
+/*******************************************************************************
+** @Author:					Thomas Bouder <Tbouder>
+** @Email:					Tbouder@protonmail.com
+** @Date:					Saturday 09 May 2020 - 17:12:36
+** @Filename:				Storage.reconcile.go
+**
+** @Last modified by:		Tbouder
+** @Last modified time:		Saturday 09 May 2020 - 17:12:36
+*******************************************************************************/
+
+
+package			main
+
+import			"io"
+import			"os"
+import			"fmt"
+import			"flag"
+import			"time"
+import			"errors"
+import			"context"
+import			"strconv"
+import			"encoding/csv"
+import			"github.com/prometheus/client_golang/prometheus"
+import			"github.com/prometheus/client_golang/prometheus/promauto"
+
+/******************************************************************************
+**	The used storage of the members is only maintained by the reservations,
+**	and drifts if the Pictures service misses a commit or a release. The
+**	reconciler compares it with the actual usage given by a source, corrects
+**	the drift and records each correction in the audit log.
+**	The PicturesService of the SDK does not report the size of the pictures
+**	yet : the actual usage is read from an export of the Pictures database
+**	(storage.usageFile), a CSV file with one `memberID,usedBytes,fullBytes`
+**	line per member. The reconciler runs every storage.reconcileInterval
+**	seconds when the file is set, or on demand with
+**	`members reconcile [--usage FILE] [--member ID]`.
+******************************************************************************/
+const	DEFAULT_RECONCILE_INTERVAL = 86400
+const	RECONCILE_PAGE_SIZE = 500
+const	RECONCILER_ACTOR = `reconciler`
+
+var		ErrUsageUnknown = errors.New("the storage usage of the member is not reported")
+var		ErrNoUsageSource = errors.New("the pictures service does not report the storage usage : export it to a file, and set --usage or storage.usageFile")
+
+var		reconciledMembers = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name:		`members_storage_reconciled_total`,
+	Help:		`Members whose storage was reconciled, by result : unchanged, corrected, skipped or failed.`,
+}, []string{`result`})
+
+/******************************************************************************
+**	Source of the actual storage usage of a member. Returns ErrUsageUnknown
+**	if the source does not know the member.
+******************************************************************************/
+type	sUsageSource interface {
+	MemberUsage(ctx context.Context, memberID string) (sStorageUsage, error)
+}
+
+type	sUsageFileSource struct {
+	usage	map[string]sStorageUsage
+}
+
+func	loadUsageFile(path string) (*sUsageFileSource, error) {
+	file, err := os.Open(path)
+	if (err != nil) {
+		return nil, err
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.Comment = '#'
+	reader.FieldsPerRecord = 3
+	reader.TrimLeadingSpace = true
+
+	source := &sUsageFileSource{usage: map[string]sStorageUsage{}}
+	for entry := 1; ; entry++ {
+		record, err := reader.Read()
+		if (err == io.EOF) {
+			return source, nil
+		} else if (err != nil) {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+		used, err := strconv.ParseInt(record[1], 10, 64)
+		if (err != nil || used < 0) {
+			return nil, fmt.Errorf("%s: entry %d: invalid used bytes %q", path, entry, record[1])
+		}
+		full, err := strconv.ParseInt(record[2], 10, 64)
+		if (err != nil || full < 0) {
+			return nil, fmt.Errorf("%s: entry %d: invalid full bytes %q", path, entry, record[2])
+		}
+		source.usage[record[0]] = sStorageUsage{Used: float64(used), Full: float64(full)}
+	}
+}
+
+func	(s *sUsageFileSource) MemberUsage(ctx context.Context, memberID string) (sStorageUsage, error) {
+	usage, ok := s.usage[memberID]
+	if (!ok) {
+		return sStorageUsage{}, ErrUsageUnknown
+	}
+	return usage, nil
+}
+
+type	sReconciler struct {
+	members		MemberStore
+	storage		StorageStore
+	source		sUsageSource
+}
+
+/******************************************************************************
+**	Reconcile the storage of a member. Returns true if it was corrected. A
+**	usage changed by a concurrent upload is left for the next run.
+******************************************************************************/
+func	(r *sReconciler) reconcileMember(ctx context.Context, memberID string) (bool, error) {
+	member, err := r.members.GetMemberByID(ctx, memberID)
+	if (err != nil) {
+		return false, err
+	}
+	actual, err := r.source.MemberUsage(ctx, memberID)
+	if (err != nil) {
+		return false, err
+	}
+	previous := sStorageUsage{Used: member.UsedStorage, Full: member.FullUsedStorage}
+	if (previous == actual) {
+		return false, nil
+	}
+
+	if err := r.storage.CorrectStorageUsage(ctx, memberID, previous, actual); err != nil {
+		return false, err
+	}
+	reason := fmt.Sprintf("used %.0f to %.0f, full %.0f to %.0f", previous.Used, actual.Used, previous.Full, actual.Full)
+	logWarning(ctx, `Storage usage corrected`, `member_id`, memberID, `correction`, reason)
+	recordAuditEvent(ctx, AUDIT_STORAGE_RECONCILED, RECONCILER_ACTOR, memberID, reason)
+	return true, nil
+}
+
+/******************************************************************************
+**	Reconcile every member, and return the number of corrections. A member
+**	which can not be reconciled is skipped.
+******************************************************************************/
+func	(r *sReconciler) reconcileAll(ctx context.Context) (int, error) {
+	var	corrected int
+	var	afterID string
+
+	for {
+		IDs, err := r.members.ListMemberIDs(ctx, afterID, RECONCILE_PAGE_SIZE)
+		if (err != nil) {
+			return corrected, err
+		}
+		for _, memberID := range IDs {
+			if (ctx.Err() != nil) {
+				return corrected, ctx.Err()
+			}
+			changed, err := r.reconcileMember(ctx, memberID)
+			switch {
+			case err == ErrUsageUnknown || err == ErrUsageChanged || err == ErrMemberNotFound:
+				reconciledMembers.WithLabelValues(`skipped`).Inc()
+			case err != nil:
+				reconciledMembers.WithLabelValues(`failed`).Inc()
+				logError(ctx, `Could not reconcile the storage`, `member_id`, memberID, `error`, err)
+			case changed:
+				corrected++
+				reconciledMembers.WithLabelValues(`corrected`).Inc()
+			default:
+				reconciledMembers.WithLabelValues(`unchanged`).Inc()
+			}
+			afterID = memberID
+		}
+		if (len(IDs) < RECONCILE_PAGE_SIZE) {
+			return corrected, nil
+		}
+	}
+}
+
+/******************************************************************************
+**	Reconcile every storage.reconcileInterval seconds, with the last export
+**	of the usage, until the shutdown
+******************************************************************************/
+func	startReconciler(service *server) {
+	if (config.Storage.UsageFile == ``) {
+		logWarning(context.Background(), `The storage usage is not reconciled, storage.usageFile is not set`)
+		return
+	}
+	stop := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(time.Duration(config.Storage.ReconcileInterval) * time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				reconcileFromFile(context.Background(), service, config.Storage.UsageFile)
+			case <-stop:
+				return
+			}
+		}
+	}()
+	onShutdown(`reconciler`, func(ctx context.Context) error {
+		close(stop)
+		return nil
+	})
+}
+
+func	reconcileFromFile(ctx context.Context, service *server, path string) {
+	source, err := loadUsageFile(path)
+	if (err != nil) {
+		logError(ctx, `Could not load the storage usage`, `path`, path, `error`, err)
+		return
+	}
+	reconciler := &sReconciler{members: service.memberStore, storage: service.storageStore, source: source}
+	corrected, err := reconciler.reconcileAll(ctx)
+	if (err != nil) {
+		logError(ctx, `Storage reconciliation stopped`, `corrected`, corrected, `error`, err)
+		return
+	}
+	logInfo(ctx, `Storage reconciled`, `corrected`, corrected)
+}
+
+/******************************************************************************
+**	Handle the `members reconcile [--usage FILE] [--member ID]` command
+******************************************************************************/
+func	runReconcileCommand(args []string, service *server) (error) {
+	flags := flag.NewFlagSet(`reconcile`, flag.ContinueOnError)
+	usageFile := flags.String(`usage`, config.Storage.UsageFile, `export of the storage usage of the members`)
+	memberID := flags.String(`member`, ``, `reconcile only this member`)
+	if err := flags.Parse(args); err != nil || flags.NArg() > 0 {
+		return errors.New("usage: members reconcile [--usage FILE] [--member ID]")
+	}
+	if (*usageFile == ``) {
+		return ErrNoUsageSource
+	}
+	source, err := loadUsageFile(*usageFile)
+	if (err != nil) {
+		return err
+	}
+
+	ctx := context.Background()
+	reconciler := &sReconciler{members: service.memberStore, storage: service.storageStore, source: source}
+	if (*memberID != ``) {
+		corrected, err := reconciler.reconcileMember(ctx, *memberID)
+		if (err != nil) {
+			return err
+		}
+		fmt.Printf("member %s: corrected=%t\n", *memberID, corrected)
+		return nil
+	}
+	corrected, err := reconciler.reconcileAll(ctx)
+	if (err != nil) {
+		return err
+	}
+	fmt.Printf("%d members corrected\n", corrected)
+	return nil
+}
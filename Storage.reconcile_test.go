@@ -0,0 +1,180 @@
+/*******************************************************************************
+** @Author:					Thomas Bouder <Tbouder>
+** @Email:					Tbouder@protonmail.com
+** @Date:					Saturday 09 May 2020 - 17:31:02
+** @Filename:				Storage.reconcile_test.go
+**
+** @Last modified by:		Tbouder
+** @Last modified time:		Saturday 09 May 2020 - 17:31:02
+*******************************************************************************/
+
+
+package			main
+
+import			"os"
+import			"strings"
+import			"context"
+import			"testing"
+import			"io/ioutil"
+import			"path/filepath"
+
+const	TEST_DRIFTED_MEMBER = `00000000-0000-0000-0000-00000000000a`
+const	TEST_EXACT_MEMBER = `00000000-0000-0000-0000-00000000000b`
+const	TEST_UNREPORTED_MEMBER = `00000000-0000-0000-0000-00000000000c`
+
+type	sFakeUsageSource map[string]sStorageUsage
+
+func	(s sFakeUsageSource) MemberUsage(ctx context.Context, memberID string) (sStorageUsage, error) {
+	usage, ok := s[memberID]
+	if (!ok) {
+		return sStorageUsage{}, ErrUsageUnknown
+	}
+	return usage, nil
+}
+
+/******************************************************************************
+**	Three members : one whose usage drifted from 100/200 to 150/300, one
+**	whose usage is exact and one unknown to the source
+******************************************************************************/
+func	createReconcileTestMembers(t *testing.T, store sStore) (sFakeUsageSource) {
+	ctx := context.Background()
+	for _, ID := range []string{TEST_DRIFTED_MEMBER, TEST_EXACT_MEMBER, TEST_UNREPORTED_MEMBER} {
+		if err := store.CreateMember(ctx, &sMember{ID: ID, Email: ID + `@example.com`}, &sSession{}, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := store.CorrectStorageUsage(ctx, TEST_DRIFTED_MEMBER, sStorageUsage{}, sStorageUsage{Used: 100, Full: 200}); err != nil {
+		t.Fatal(err)
+	}
+	return sFakeUsageSource{
+		TEST_DRIFTED_MEMBER:	{Used: 150, Full: 300},
+		TEST_EXACT_MEMBER:		{},
+	}
+}
+
+func	expectStorageUsage(t *testing.T, store sStore, memberID string, expected sStorageUsage) {
+	member, err := store.GetMemberByID(context.Background(), memberID)
+	if (err != nil) {
+		t.Fatal(err)
+	}
+	if usage := (sStorageUsage{Used: member.UsedStorage, Full: member.FullUsedStorage}); usage != expected {
+		t.Errorf("%s: expected the usage %+v, got %+v", memberID, expected, usage)
+	}
+}
+
+/******************************************************************************
+**	Run the audit outbox for the duration of a test, and return the function
+**	flushing it
+******************************************************************************/
+func	startTestAuditOutbox(store AuditStore) (func()) {
+	previousHooks, previousOutbox := shutdownHooks, auditOutbox
+	shutdownHooks = nil
+	auditOutbox = startAuditOutbox(store)
+	return func() {
+		runShutdownHooks(context.Background())
+		shutdownHooks, auditOutbox = previousHooks, previousOutbox
+	}
+}
+
+func	TestReconcileStorage(t *testing.T) {
+	for _, backend := range serviceStores {
+		t.Run(backend.name, func(t *testing.T) {
+			store, close := backend.open(t)
+			defer close()
+			ctx := context.Background()
+			source := createReconcileTestMembers(t, store)
+
+			flush := startTestAuditOutbox(store)
+			reconciler := &sReconciler{members: store, storage: store, source: source}
+			corrected, err := reconciler.reconcileAll(ctx)
+			flush()
+			if (err != nil || corrected != 1) {
+				t.Fatalf("expected a single correction, got %d (%v)", corrected, err)
+			}
+			expectStorageUsage(t, store, TEST_DRIFTED_MEMBER, sStorageUsage{Used: 150, Full: 300})
+			expectStorageUsage(t, store, TEST_EXACT_MEMBER, sStorageUsage{})
+			expectStorageUsage(t, store, TEST_UNREPORTED_MEMBER, sStorageUsage{})
+
+			events, err := store.ListAuditEvents(ctx, sAuditFilter{Type: AUDIT_STORAGE_RECONCILED, Limit: 10})
+			if (err != nil) {
+				t.Fatal(err)
+			}
+			if (len(events) != 1 || events[0].TargetID != TEST_DRIFTED_MEMBER || events[0].ActorID != RECONCILER_ACTOR ||
+				events[0].Reason != `used 100 to 150, full 200 to 300`) {
+				t.Fatalf("expected the audit event of the correction, got %+v", events)
+			}
+
+			if corrected, err := reconciler.reconcileAll(ctx); err != nil || corrected != 0 {
+				t.Errorf("a second run: expected no correction, got %d (%v)", corrected, err)
+			}
+			if _, err := reconciler.reconcileMember(ctx, TEST_UNREPORTED_MEMBER); err != ErrUsageUnknown {
+				t.Errorf("a member unknown to the source: expected ErrUsageUnknown, got %v", err)
+			}
+			err = store.CorrectStorageUsage(ctx, TEST_DRIFTED_MEMBER, sStorageUsage{Used: 100, Full: 200}, sStorageUsage{})
+			if (err != ErrUsageChanged) {
+				t.Errorf("a usage changed in the meantime: expected ErrUsageChanged, got %v", err)
+			}
+		})
+	}
+}
+
+func	newUsageTestFile(t *testing.T, content string) (string, func()) {
+	dir, err := ioutil.TempDir(``, `usage`)
+	if (err != nil) {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, `usage.csv`)
+	if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path, func() {os.RemoveAll(dir)}
+}
+
+func	TestLoadUsageFile(t *testing.T) {
+	path, remove := newUsageTestFile(t, "# memberID,usedBytes,fullBytes\n" + TEST_DRIFTED_MEMBER + ",150,300\n" + TEST_EXACT_MEMBER + ", 0, 0\n")
+	defer remove()
+	source, err := loadUsageFile(path)
+	if (err != nil) {
+		t.Fatal(err)
+	}
+	if usage, err := source.MemberUsage(context.Background(), TEST_DRIFTED_MEMBER); err != nil || usage != (sStorageUsage{Used: 150, Full: 300}) {
+		t.Errorf("expected the usage of the file, got %+v (%v)", usage, err)
+	}
+	if _, err := source.MemberUsage(context.Background(), TEST_UNREPORTED_MEMBER); err != ErrUsageUnknown {
+		t.Errorf("a member missing from the file: expected ErrUsageUnknown, got %v", err)
+	}
+
+	for _, content := range []string{TEST_DRIFTED_MEMBER + ",150\n", TEST_DRIFTED_MEMBER + ",-1,0\n", TEST_DRIFTED_MEMBER + ",150,many\n"} {
+		invalid, remove := newUsageTestFile(t, content)
+		if _, err := loadUsageFile(invalid); err == nil {
+			t.Errorf("%q: expected an error", content)
+		}
+		remove()
+	}
+}
+
+func	TestRunReconcileCommand(t *testing.T) {
+	store := newMemoryStore()
+	createReconcileTestMembers(t, store)
+	s := newServerWithStore(store)
+
+	if err := runReconcileCommand(nil, s); err != ErrNoUsageSource {
+		t.Errorf("without a usage file: expected ErrNoUsageSource, got %v", err)
+	}
+	if err := runReconcileCommand([]string{`extra`}, s); err == nil || !strings.HasPrefix(err.Error(), `usage:`) {
+		t.Errorf("an unexpected argument: expected the usage, got %v", err)
+	}
+
+	path, remove := newUsageTestFile(t, TEST_DRIFTED_MEMBER + ",150,300\n" + TEST_EXACT_MEMBER + ",10,10\n")
+	defer remove()
+	if err := runReconcileCommand([]string{`--usage`, path, `--member`, TEST_DRIFTED_MEMBER}, s); err != nil {
+		t.Fatalf("reconcile --member: %v", err)
+	}
+	expectStorageUsage(t, store, TEST_DRIFTED_MEMBER, sStorageUsage{Used: 150, Full: 300})
+	expectStorageUsage(t, store, TEST_EXACT_MEMBER, sStorageUsage{})
+
+	if err := runReconcileCommand([]string{`--usage`, path}, s); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	expectStorageUsage(t, store, TEST_EXACT_MEMBER, sStorageUsage{Used: 10, Full: 10})
+}
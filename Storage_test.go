@@ -0,0 +1,109 @@
+/*******************************************************************************
+** @Author:					Thomas Bouder <Tbouder>
+** @Email:					Tbouder@protonmail.com
+** @Date:					Friday 08 May 2020 - 19:26:48
+** @Filename:				Storage_test.go
+**
+** @Last modified by:		Tbouder
+** @Last modified time:		Saturday 09 May 2020 - 18:12:40
+*******************************************************************************/
+
+
+package			main
+
+import			"context"
+import			"testing"
+import			"google.golang.org/grpc/codes"
+import			"github.com/panghostlin/Members/Extended"
+
+/******************************************************************************
+**	An expired reservation frees it's bytes on the next reservation and can
+**	not be committed anymore, with both the SQL and the in-memory stores
+******************************************************************************/
+func	TestStorageReservationExpiry(t *testing.T) {
+	sqliteStore, closeSQLite := newSQLiteTestStore(t)
+	defer closeSQLite()
+
+	stores := map[string]sStore{`memory`: newMemoryStore(), `sqlite`: sqliteStore}
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			member := &sMember{ID: `6f1c2a52-8d3e-4b7a-9c1d-0e5f4a3b2c1d`, Email: `quota@example.com`}
+			if err := store.CreateMember(ctx, member, &sSession{}, nil); err != nil {
+				t.Fatal(err)
+			}
+			if err := store.SetStorageQuota(ctx, member.ID, 100); err != nil {
+				t.Fatal(err)
+			}
+
+			first := &sReservation{ID: `reservation-1`, MemberID: member.ID, Bytes: 80, ExpiresAt: 1000}
+			if err := store.ReserveStorage(ctx, first, 0, 900); err != nil {
+				t.Fatalf("first reservation: %v", err)
+			}
+			second := &sReservation{ID: `reservation-2`, MemberID: member.ID, Bytes: 50, ExpiresAt: 2000}
+			if err := store.ReserveStorage(ctx, second, 0, 999); err != ErrQuotaExceeded {
+				t.Fatalf("before the expiry: expected ErrQuotaExceeded, got %v", err)
+			}
+			if err := store.ReserveStorage(ctx, second, 0, 1000); err != nil {
+				t.Fatalf("after the expiry: %v", err)
+			}
+			if stored, _ := store.GetMemberByID(ctx, member.ID); stored.ReservedStorage != 50 {
+				t.Errorf("expected 50 reserved bytes, got %d", stored.ReservedStorage)
+			}
+
+			if err := store.CommitStorage(ctx, member.ID, first.ID, 80, 1000); err != ErrReservationNotFound {
+				t.Errorf("the expired reservation: expected ErrReservationNotFound, got %v", err)
+			}
+			if err := store.CommitStorage(ctx, member.ID, second.ID, 70, 1999); err != nil {
+				t.Fatalf("the valid reservation: %v", err)
+			}
+			stored, _ := store.GetMemberByID(ctx, member.ID)
+			if (stored.ReservedStorage != 0 || stored.UsedStorage != 50 || stored.FullUsedStorage != 70) {
+				t.Errorf("unexpected storage %d reserved, %v used, %v full", stored.ReservedStorage, stored.UsedStorage, stored.FullUsedStorage)
+			}
+		})
+	}
+}
+
+func	TestStorageRPCs(t *testing.T) {
+	client := newExtendedTestClient(t)
+	defer client.close()
+	created := createTestMember(t, client.service, `storage@example.com`)
+
+	if code := client.call(`SetStorageQuota`, &extended.SetStorageQuotaRequest{MemberID: created.MemberID, Quota: 100}, &extended.StorageResponse{}); code != codes.OK {
+		t.Fatalf("SetStorageQuota: expected OK, got %v", code)
+	}
+	reserved := &extended.ReserveStorageResponse{}
+	if code := client.call(`ReserveStorage`, &extended.ReserveStorageRequest{MemberID: created.MemberID, Bytes: 60}, reserved); code != codes.OK || reserved.ReservationID == `` {
+		t.Fatalf("ReserveStorage: expected a reservation, got %v %v", code, reserved)
+	}
+	if code := client.call(`ReserveStorage`, &extended.ReserveStorageRequest{MemberID: created.MemberID, Bytes: 60}, &extended.ReserveStorageResponse{}); code != codes.ResourceExhausted {
+		t.Errorf("over the quota: expected ResourceExhausted, got %v", code)
+	}
+	if code := client.call(`CommitStorage`, &extended.CommitStorageRequest{MemberID: created.MemberID, ReservationID: reserved.ReservationID, FullBytes: 90}, &extended.StorageResponse{}); code != codes.OK {
+		t.Fatalf("CommitStorage: expected OK, got %v", code)
+	}
+	if code := client.call(`ReleaseStorage`, &extended.ReleaseStorageRequest{MemberID: created.MemberID, ReservationID: reserved.ReservationID}, &extended.StorageResponse{}); code != codes.NotFound {
+		t.Errorf("a committed reservation: expected NotFound, got %v", code)
+	}
+	if code := client.call(`ReleaseStorage`, &extended.ReleaseStorageRequest{MemberID: created.MemberID, Bytes: 10, FullBytes: 20}, &extended.StorageResponse{}); code != codes.OK {
+		t.Fatalf("ReleaseStorage: expected OK, got %v", code)
+	}
+	member, _ := client.store.GetMemberByID(context.Background(), created.MemberID)
+	if (member.StorageQuota != 100 || member.UsedStorage != 50 || member.FullUsedStorage != 70 || member.ReservedStorage != 0) {
+		t.Errorf("unexpected stored storage %+v", member)
+	}
+}
+
+func	TestStoragePolicy(t *testing.T) {
+	policy := defaultAuthorizationPolicy()
+	for _, method := range []string{`ReserveStorage`, `CommitStorage`, `ReleaseStorage`} {
+		fullMethod := `/` + EXTENDED_SERVICE_NAME + `/` + method
+		if (!policy.allows(fullMethod, []string{`pictures`}) || policy.allows(fullMethod, []string{`proxy`, `admin`})) {
+			t.Errorf("%s must only be allowed to pictures", method)
+		}
+	}
+	if (policy.allows(`/` + EXTENDED_SERVICE_NAME + `/SetStorageQuota`, []string{`pictures`, `proxy`})) {
+		t.Errorf("SetStorageQuota must only be allowed to admin")
+	}
+}
@@ -0,0 +1,123 @@
+/*******************************************************************************
+** @Author:					Thomas Bouder <Tbouder>
+** @Email:					Tbouder@protonmail.com
+** @Date:					Saturday 09 May 2020 - 14:02:18
+** @Filename:				Store.audit.go
+**
+** @Last modified by:		Tbouder
+** @Last modified time:		Saturday 09 May 2020 - 14:02:18
+*******************************************************************************/
+
+
+package			main
+
+import			"context"
+import			"database/sql"
+
+/******************************************************************************
+**	The audit events are only appended, in order, by the audit outbox. The
+**	store computes the hash chain : each event is chained to the hash of the
+**	previous one, in the same transaction as the insert.
+******************************************************************************/
+type	sAuditEvent struct {
+	ID				int64
+	Type			string
+	ActorID			string
+	TargetID		string
+	Reason			string
+	IP				string
+	UserAgent		string
+	CreatedAt		int64
+	PreviousHash	string
+	Hash			string
+}
+
+type	sAuditFilter struct {
+	MemberID	string
+	Type		string
+	BeforeID	int64
+	Limit		int
+}
+
+type	AuditStore interface {
+	AppendAuditEvents(ctx context.Context, events []*sAuditEvent) (error)
+	/**************************************************************************
+	**	List the events matching the filter, the most recent first. The
+	**	MemberID matches both the actor and the target.
+	**************************************************************************/
+	ListAuditEvents(ctx context.Context, filter sAuditFilter) ([]*sAuditEvent, error)
+	/**************************************************************************
+	**	List the events with an ID greater than afterID, the oldest first, to
+	**	verify the chain
+	**************************************************************************/
+	ScanAuditEvents(ctx context.Context, afterID int64, limit int) ([]*sAuditEvent, error)
+}
+
+/******************************************************************************
+**	The dialect serializes the appends, in the transaction
+******************************************************************************/
+func	(s *sSQLStore) AppendAuditEvents(ctx context.Context, events []*sAuditEvent) (error) {
+	return s.transaction(ctx, func(tx *sql.Tx) error {
+		if err := s.dialect.lockAuditEvents(ctx, tx); err != nil {
+			return err
+		}
+
+		var	previousHash string
+		err := tx.QueryRowContext(ctx, `SELECT Hash FROM audit_events ORDER BY ID DESC LIMIT 1`).Scan(&previousHash)
+		if (err != nil && err != sql.ErrNoRows) {
+			return err
+		}
+		for _, event := range events {
+			event.PreviousHash = previousHash
+			event.Hash = hashAuditEvent(event)
+			_, err := tx.ExecContext(ctx, `INSERT INTO audit_events (
+				Type, ActorID, TargetID, Reason, IP, UserAgent, CreatedAt, PreviousHash, Hash
+			) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`,
+				event.Type, event.ActorID, event.TargetID, event.Reason, event.IP, event.UserAgent,
+				event.CreatedAt, event.PreviousHash, event.Hash,
+			)
+			if (err != nil) {
+				return err
+			}
+			previousHash = event.Hash
+		}
+		return nil
+	})
+}
+
+func	(s *sSQLStore) queryAuditEvents(ctx context.Context, query string, args ...interface{}) ([]*sAuditEvent, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT
+		ID, Type, ActorID, TargetID, Reason, IP, UserAgent, CreatedAt, PreviousHash, Hash
+		FROM audit_events ` + query, args...)
+	if (err != nil) {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []*sAuditEvent{}
+	for rows.Next() {
+		event := &sAuditEvent{}
+		err := rows.Scan(
+			&event.ID, &event.Type, &event.ActorID, &event.TargetID, &event.Reason, &event.IP, &event.UserAgent,
+			&event.CreatedAt, &event.PreviousHash, &event.Hash,
+		)
+		if (err != nil) {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
+
+func	(s *sSQLStore) ListAuditEvents(ctx context.Context, filter sAuditFilter) ([]*sAuditEvent, error) {
+	return s.queryAuditEvents(ctx, `WHERE
+		(CAST($1 AS text) = '' OR ActorID = $1 OR TargetID = $1) AND (CAST($2 AS text) = '' OR Type = $2)
+		AND (CAST($3 AS bigint) = 0 OR ID < $3)
+		ORDER BY ID DESC LIMIT $4`,
+		filter.MemberID, filter.Type, filter.BeforeID, filter.Limit,
+	)
+}
+
+func	(s *sSQLStore) ScanAuditEvents(ctx context.Context, afterID int64, limit int) ([]*sAuditEvent, error) {
+	return s.queryAuditEvents(ctx, `WHERE ID > $1 ORDER BY ID ASC LIMIT $2`, afterID, limit)
+}
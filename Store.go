@@ -0,0 +1,99 @@
+/*******************************************************************************
+** @Author:					Thomas Bouder <Tbouder>
+** @Email:					Tbouder@protonmail.com
+** @Date:					Tuesday 14 April 2020 - 09:40:26
+** @Filename:				Store.go
+**
+** @Last modified by:		Tbouder
+** @Last modified time:		Saturday 09 May 2020 - 17:31:02
+*******************************************************************************/
+
+package			main
+
+import			"fmt"
+import			"errors"
+import			"context"
+import			"database/sql"
+
+/******************************************************************************
+**	The RPCs never talk to the database directly, but through the stores,
+**	which are injected in the server. This allows to swap the SQL
+**	implementation with the in-memory one. Each domain declares it's store
+**	interface and it's queries in it's own file (Store.members.go,
+**	Store.storage.go ...).
+******************************************************************************/
+var (
+	ErrMemberNotFound		= errors.New("member not found")
+	ErrMemberAlreadyExists	= errors.New("member already exists")
+	ErrLoginAlertNotFound	= errors.New("login alert not found")
+	ErrReservationNotFound	= errors.New("storage reservation not found")
+	ErrQuotaExceeded		= errors.New("storage quota exceeded")
+	ErrUsageChanged			= errors.New("storage usage changed")
+	ErrPlanNotFound			= errors.New("plan not found")
+	ErrPlanAlreadyExists	= errors.New("plan already exists")
+	ErrInvitationNotFound	= errors.New("invitation not found")
+	ErrInvitationInvalid	= errors.New("invitation invalid")
+)
+
+/******************************************************************************
+**	The SQL implementation of every store, shared by the Postgre and the
+**	SQLite databases. The queries are written once, with the $N placeholders
+**	both drivers understand : only the dialect differs.
+******************************************************************************/
+type	sSQLDialect interface {
+	/**************************************************************************
+	**	The error is the violation of a unique constraint : an email used by
+	**	another member, or a name used by another plan
+	**************************************************************************/
+	isUniqueViolation(err error) (bool)
+	/**************************************************************************
+	**	Serialize the audit appends until the end of the transaction
+	**************************************************************************/
+	lockAuditEvents(ctx context.Context, tx *sql.Tx) (error)
+}
+
+type	sSQLStore struct {
+	db		*sql.DB
+	dialect	sSQLDialect
+}
+
+/******************************************************************************
+**	Run the operation in a transaction, committed if the operation succeeds
+**	and rolled back otherwise
+******************************************************************************/
+func	(s *sSQLStore) transaction(ctx context.Context, operation func(tx *sql.Tx) error) (error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if (err != nil) {
+		return err
+	}
+	if err := operation(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func	rowsAffectedOrNotFound(result sql.Result, err error) (error) {
+	if (err != nil) {
+		return err
+	}
+	if updated, err := result.RowsAffected(); err != nil {
+		return err
+	} else if (updated == 0) {
+		return ErrMemberNotFound
+	}
+	return nil
+}
+
+/******************************************************************************
+**	Generate a random (version 4) UUID, as uuid_generate_v4 would
+******************************************************************************/
+func	newUUID() (string, error) {
+	b, err := generateNonce(16)
+	if (err != nil) {
+		return ``, err
+	}
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]), nil
+}
@@ -0,0 +1,116 @@
+/*******************************************************************************
+** @Author:					Thomas Bouder <Tbouder>
+** @Email:					Tbouder@protonmail.com
+** @Date:					Saturday 09 May 2020 - 14:02:18
+** @Filename:				Store.invitations.go
+**
+** @Last modified by:		Tbouder
+** @Last modified time:		Saturday 09 May 2020 - 14:02:18
+*******************************************************************************/
+
+
+package			main
+
+import			"context"
+import			"strings"
+import			"database/sql"
+
+/******************************************************************************
+**	The invitations allow to sign up when the registration is invite-only.
+**	Only the hash of the code is stored. An invitation can be used MaxUses
+**	times before it expires, by anyone, or only by it's Email if set, and
+**	gives it's plan to the new members.
+******************************************************************************/
+type	sInvitation struct {
+	ID			string
+	CodeHash	string
+	Email		string
+	PlanID		string
+	MaxUses		int64
+	Uses		int64
+	ExpiresAt	int64
+	CreatedBy	string
+	CreatedAt	int64
+	RevokedAt	int64
+}
+
+/******************************************************************************
+**	The code given at sign up. The store sets the ID of the invitation once
+**	it is used.
+******************************************************************************/
+type	sRedemption struct {
+	CodeHash		string
+	Now				int64
+	InvitationID	string
+}
+
+type	InvitationStore interface {
+	CreateInvitation(ctx context.Context, invitation *sInvitation) (error)
+	/**************************************************************************
+	**	List the invitations, the most recent first
+	**************************************************************************/
+	ListInvitations(ctx context.Context, limit int) ([]*sInvitation, error)
+	RevokeInvitation(ctx context.Context, invitationID string, now int64) (error)
+}
+
+/******************************************************************************
+**	An invitation is used by incrementing it's uses, only if it is still
+**	valid for the email : two concurrent sign ups can not exceed MaxUses.
+******************************************************************************/
+func	redeemInvitationTx(ctx context.Context, tx *sql.Tx, member *sMember, redemption *sRedemption) (error) {
+	err := tx.QueryRowContext(ctx, `UPDATE invitations SET Uses = Uses + 1
+		WHERE CodeHash=$1 AND RevokedAt=0 AND ExpiresAt > $2 AND Uses < MaxUses
+		AND (Email = '' OR Email = lower(CAST($3 AS text)))
+		RETURNING CAST(ID AS text), COALESCE(CAST(PlanID AS text), '')`,
+		redemption.CodeHash, redemption.Now, member.Email,
+	).Scan(&redemption.InvitationID, &member.PlanID)
+	if (err == sql.ErrNoRows) {
+		return ErrInvitationInvalid
+	}
+	return err
+}
+
+func	(s *sSQLStore) CreateInvitation(ctx context.Context, invitation *sInvitation) (error) {
+	_, err := s.db.ExecContext(ctx, `INSERT INTO invitations (
+		ID, CodeHash, Email, PlanID, MaxUses, Uses, ExpiresAt, CreatedBy, CreatedAt, RevokedAt
+	) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`,
+		invitation.ID, invitation.CodeHash, strings.ToLower(invitation.Email),
+		sql.NullString{String: invitation.PlanID, Valid: invitation.PlanID != ``},
+		invitation.MaxUses, invitation.Uses, invitation.ExpiresAt, invitation.CreatedBy, invitation.CreatedAt, invitation.RevokedAt,
+	)
+	return err
+}
+
+func	(s *sSQLStore) ListInvitations(ctx context.Context, limit int) ([]*sInvitation, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT
+		ID, Email, COALESCE(CAST(PlanID AS text), ''), MaxUses, Uses, ExpiresAt, CreatedBy, CreatedAt, RevokedAt
+		FROM invitations ORDER BY CreatedAt DESC, ID LIMIT $1`, limit)
+	if (err != nil) {
+		return nil, err
+	}
+	defer rows.Close()
+
+	invitations := []*sInvitation{}
+	for rows.Next() {
+		invitation := &sInvitation{}
+		err := rows.Scan(
+			&invitation.ID, &invitation.Email, &invitation.PlanID, &invitation.MaxUses, &invitation.Uses,
+			&invitation.ExpiresAt, &invitation.CreatedBy, &invitation.CreatedAt, &invitation.RevokedAt,
+		)
+		if (err != nil) {
+			return nil, err
+		}
+		invitations = append(invitations, invitation)
+	}
+	return invitations, rows.Err()
+}
+
+func	(s *sSQLStore) RevokeInvitation(ctx context.Context, invitationID string, now int64) (error) {
+	result, err := s.db.ExecContext(ctx, `UPDATE invitations SET RevokedAt=$2 WHERE ID=$1 AND RevokedAt=0`, invitationID, now)
+	if err := rowsAffectedOrNotFound(result, err); err == ErrMemberNotFound {
+		return ErrInvitationNotFound
+	} else if (err != nil) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+/*******************************************************************************
+** @Author:					Thomas Bouder <Tbouder>
+** @Email:					Tbouder@protonmail.com
+** @Date:					Saturday 09 May 2020 - 14:02:18
+** @Filename:				Store.logins.go
+**
+** @Last modified by:		Tbouder
+** @Last modified time:		Saturday 09 May 2020 - 16:47:10
+*******************************************************************************/
+
+
+package			main
+
+import			"context"
+import			"database/sql"
+
+/******************************************************************************
+**	Every successful login, with the device it came from. The fingerprint
+**	identifies the device, from its address and its user agent.
+******************************************************************************/
+type	sLogin struct {
+	ID				int64
+	MemberID		string
+	IP				string
+	UserAgent		string
+	Fingerprint		string
+	NewDevice		bool
+	CreatedAt		int64
+	AlertTokenHash	string
+	AlertExp		int64
+}
+
+type	LoginStore interface {
+	/**************************************************************************
+	**	Record the login and remember its device. NewDevice is set if the
+	**	member already had known devices, but not this one : the alert token
+	**	is only kept in this case.
+	**************************************************************************/
+	RecordLogin(ctx context.Context, login *sLogin) (error)
+	ListLogins(ctx context.Context, memberID string, limit int) ([]*sLogin, error)
+	/**************************************************************************
+	**	Consume the alert token of a login, revoke the sessions of the member
+	**	and require a password change, atomically. The hash of the token is
+	**	kept on the member : the same token authorizes the password change.
+	**	Returns the member ID.
+	**************************************************************************/
+	ReportUnrecognizedLogin(ctx context.Context, alertTokenHash string, now int64) (string, error)
+}
+
+func	(s *sSQLStore) RecordLogin(ctx context.Context, login *sLogin) (error) {
+	return s.transaction(ctx, func(tx *sql.Tx) error {
+		var	known, seen int64
+		err := tx.QueryRowContext(ctx, `SELECT COUNT(*), COALESCE(SUM(CASE WHEN Fingerprint = $2 THEN 1 ELSE 0 END), 0)
+			FROM known_devices WHERE MemberID=$1`, login.MemberID, login.Fingerprint,
+		).Scan(&known, &seen)
+		if (err != nil) {
+			return err
+		}
+		login.NewDevice = known > 0 && seen == 0
+		if (!login.NewDevice) {
+			login.AlertTokenHash = ``
+			login.AlertExp = 0
+		}
+
+		_, err = tx.ExecContext(ctx, `INSERT INTO known_devices (MemberID, Fingerprint, FirstSeen, LastSeen) VALUES ($1, $2, $3, $3)
+			ON CONFLICT (MemberID, Fingerprint) DO UPDATE SET LastSeen = excluded.LastSeen`,
+			login.MemberID, login.Fingerprint, login.CreatedAt,
+		)
+		if (err != nil) {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, `INSERT INTO login_history (
+			MemberID, IP, UserAgent, Fingerprint, NewDevice, CreatedAt, AlertTokenHash, AlertExp
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+			login.MemberID, login.IP, login.UserAgent, login.Fingerprint, login.NewDevice,
+			login.CreatedAt, login.AlertTokenHash, login.AlertExp,
+		)
+		return err
+	})
+}
+
+func	(s *sSQLStore) ListLogins(ctx context.Context, memberID string, limit int) ([]*sLogin, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT ID, MemberID, IP, UserAgent, Fingerprint, NewDevice, CreatedAt
+		FROM login_history WHERE MemberID=$1 ORDER BY ID DESC LIMIT $2`, memberID, limit)
+	if (err != nil) {
+		return nil, err
+	}
+	defer rows.Close()
+
+	logins := []*sLogin{}
+	for rows.Next() {
+		login := &sLogin{}
+		err := rows.Scan(&login.ID, &login.MemberID, &login.IP, &login.UserAgent, &login.Fingerprint, &login.NewDevice, &login.CreatedAt)
+		if (err != nil) {
+			return nil, err
+		}
+		logins = append(logins, login)
+	}
+	return logins, rows.Err()
+}
+
+func	(s *sSQLStore) ReportUnrecognizedLogin(ctx context.Context, alertTokenHash string, now int64) (string, error) {
+	var	memberID string
+	err := s.transaction(ctx, func(tx *sql.Tx) error {
+		err := tx.QueryRowContext(ctx, `SELECT MemberID FROM login_history WHERE AlertTokenHash=$1 AND AlertExp > $2`,
+			alertTokenHash, now,
+		).Scan(&memberID)
+		if (err == sql.ErrNoRows) {
+			return ErrLoginAlertNotFound
+		} else if (err != nil) {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, `UPDATE login_history SET AlertTokenHash='', AlertExp=0 WHERE MemberID=$1 AND AlertTokenHash<>''`, memberID)
+		if (err != nil) {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, `UPDATE members SET
+			AccessToken='', AccessExp=0, RefreshToken='', RefreshExp=0, PasswordChangeRequired=true, PasswordChangeTokenHash=$2
+			WHERE ID=$1`, memberID, alertTokenHash,
+		)
+		return err
+	})
+	if (err != nil) {
+		return ``, err
+	}
+	return memberID, nil
+}
@@ -0,0 +1,232 @@
+/*******************************************************************************
+** @Author:					Thomas Bouder <Tbouder>
+** @Email:					Tbouder@protonmail.com
+** @Date:					Saturday 09 May 2020 - 14:02:18
+** @Filename:				Store.members.go
+**
+** @Last modified by:		Tbouder
+** @Last modified time:		Saturday 09 May 2020 - 17:31:02
+*******************************************************************************/
+
+
+package			main
+
+import			"context"
+import			"strings"
+import			"database/sql"
+
+type	sMember struct {
+	ID					string
+	Email				string
+
+	PublicKey			string
+	PrivateKey			string
+	PrivateKeyIV		string
+	PrivateKeySalt		string
+
+	PasswordArgon2Hash	string
+	PasswordArgon2IV	string
+	PasswordScryptHash	string
+	PasswordScryptIV	string
+
+	UsedStorage			float64
+	FullUsedStorage		float64
+	StorageQuota		int64
+	ReservedStorage		int64
+
+	PasswordChangeRequired	bool
+	PasswordChangeTokenHash	string
+	PlanID				string
+}
+
+type	sSession struct {
+	AccessToken		string
+	AccessExp		int64
+	RefreshToken	string
+	RefreshExp		int64
+}
+
+type	MemberStore interface {
+	/**************************************************************************
+	**	Create the member, with it's ID, keys, password hashes and session,
+	**	atomically : either everything is stored, or nothing is. With a
+	**	redemption, the invitation is used in the same transaction, and it's
+	**	plan is given to the member.
+	**************************************************************************/
+	CreateMember(ctx context.Context, member *sMember, session *sSession, redemption *sRedemption) (error)
+	UpdateMember(ctx context.Context, member *sMember) (error)
+	/**************************************************************************
+	**	Replace the password hashes, the private key and the session of the
+	**	member, and clear PasswordChangeRequired. The change is refused with
+	**	ErrMemberNotFound if the token of the reported login was used in the
+	**	meantime.
+	**************************************************************************/
+	ChangePassword(ctx context.Context, member *sMember, session *sSession) (error)
+	GetMemberByID(ctx context.Context, memberID string) (*sMember, error)
+	GetMemberByEmail(ctx context.Context, email string) (*sMember, error)
+	CountMembers(ctx context.Context) (int64, error)
+	/**************************************************************************
+	**	List the IDs of the members, in order, after afterID
+	**************************************************************************/
+	ListMemberIDs(ctx context.Context, afterID string, limit int) ([]string, error)
+}
+
+type	SessionStore interface {
+	GetSession(ctx context.Context, memberID string) (*sSession, error)
+	SetSession(ctx context.Context, memberID string, session *sSession) (error)
+	SetAccessToken(ctx context.Context, memberID, accessToken string, accessExp int64) (error)
+	/**************************************************************************
+	**	Count the sessions whose refresh token expires after now
+	**************************************************************************/
+	CountActiveSessions(ctx context.Context, now int64) (int64, error)
+}
+
+/******************************************************************************
+**	Insert the member and it's session in a single transaction, after using
+**	the invitation if any
+******************************************************************************/
+func	(s *sSQLStore) CreateMember(ctx context.Context, member *sMember, session *sSession, redemption *sRedemption) (error) {
+	err := s.transaction(ctx, func(tx *sql.Tx) error {
+		if (redemption != nil) {
+			if err := redeemInvitationTx(ctx, tx, member, redemption); err != nil {
+				return err
+			}
+		}
+		_, err := tx.ExecContext(ctx, `INSERT INTO members (
+			ID, Email,
+			AccessToken, AccessExp, RefreshToken, RefreshExp,
+			PublicKey, PrivateKey, PrivateKeyIV, PrivateKeySalt,
+			PasswordArgon2Hash, PasswordArgon2IV, PasswordScryptHash, PasswordScryptIV,
+			PlanID
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)`,
+			member.ID, strings.ToLower(member.Email),
+			session.AccessToken, session.AccessExp, session.RefreshToken, session.RefreshExp,
+			member.PublicKey, member.PrivateKey, member.PrivateKeyIV, member.PrivateKeySalt,
+			member.PasswordArgon2Hash, member.PasswordArgon2IV, member.PasswordScryptHash, member.PasswordScryptIV,
+			sql.NullString{String: member.PlanID, Valid: member.PlanID != ``},
+		)
+		return err
+	})
+	if (s.dialect.isUniqueViolation(err)) {
+		return ErrMemberAlreadyExists
+	}
+	return err
+}
+
+func	(s *sSQLStore) UpdateMember(ctx context.Context, member *sMember) (error) {
+	_, err := s.db.ExecContext(ctx, `UPDATE members SET
+		PublicKey=$1, PrivateKey=$2, PrivateKeyIV=$3, PrivateKeySalt=$4,
+		PasswordArgon2Hash=$5, PasswordArgon2IV=$6, PasswordScryptHash=$7, PasswordScryptIV=$8
+		WHERE ID=$9`,
+		member.PublicKey, member.PrivateKey, member.PrivateKeyIV, member.PrivateKeySalt,
+		member.PasswordArgon2Hash, member.PasswordArgon2IV, member.PasswordScryptHash, member.PasswordScryptIV,
+		member.ID,
+	)
+	return err
+}
+
+func	(s *sSQLStore) ChangePassword(ctx context.Context, member *sMember, session *sSession) (error) {
+	return rowsAffectedOrNotFound(s.db.ExecContext(ctx, `UPDATE members SET
+		PrivateKey=$1, PrivateKeyIV=$2, PrivateKeySalt=$3,
+		PasswordArgon2Hash=$4, PasswordArgon2IV=$5, PasswordScryptHash=$6, PasswordScryptIV=$7,
+		AccessToken=$8, AccessExp=$9, RefreshToken=$10, RefreshExp=$11, PasswordChangeRequired=false, PasswordChangeTokenHash=''
+		WHERE ID=$12 AND PasswordChangeTokenHash=$13`,
+		member.PrivateKey, member.PrivateKeyIV, member.PrivateKeySalt,
+		member.PasswordArgon2Hash, member.PasswordArgon2IV, member.PasswordScryptHash, member.PasswordScryptIV,
+		session.AccessToken, session.AccessExp, session.RefreshToken, session.RefreshExp,
+		member.ID, member.PasswordChangeTokenHash,
+	))
+}
+
+/******************************************************************************
+**	The nullable columns are read with COALESCE, and the key is always a
+**	column name set by the store, never a value from the request
+******************************************************************************/
+func	(s *sSQLStore) getMember(ctx context.Context, key, value string) (*sMember, error) {
+	member := &sMember{}
+	err := s.db.QueryRowContext(ctx, `SELECT
+		CAST(ID AS text), Email,
+		COALESCE(PublicKey, ''), COALESCE(PrivateKey, ''), COALESCE(PrivateKeyIV, ''), COALESCE(PrivateKeySalt, ''),
+		COALESCE(PasswordArgon2Hash, ''), COALESCE(PasswordArgon2IV, ''), COALESCE(PasswordScryptHash, ''), COALESCE(PasswordScryptIV, ''),
+		UsedStorage, FullUsedStorage, StorageQuota, ReservedStorage, PasswordChangeRequired, PasswordChangeTokenHash, COALESCE(CAST(PlanID AS text), '')
+		FROM members WHERE ` + key + `=$1`, value,
+	).Scan(
+		&member.ID, &member.Email,
+		&member.PublicKey, &member.PrivateKey, &member.PrivateKeyIV, &member.PrivateKeySalt,
+		&member.PasswordArgon2Hash, &member.PasswordArgon2IV, &member.PasswordScryptHash, &member.PasswordScryptIV,
+		&member.UsedStorage, &member.FullUsedStorage, &member.StorageQuota, &member.ReservedStorage, &member.PasswordChangeRequired, &member.PasswordChangeTokenHash, &member.PlanID,
+	)
+	if (err == sql.ErrNoRows) {
+		return nil, ErrMemberNotFound
+	} else if (err != nil) {
+		return nil, err
+	}
+	return member, nil
+}
+
+func	(s *sSQLStore) GetMemberByID(ctx context.Context, memberID string) (*sMember, error) {
+	return s.getMember(ctx, `ID`, memberID)
+}
+func	(s *sSQLStore) GetMemberByEmail(ctx context.Context, email string) (*sMember, error) {
+	return s.getMember(ctx, `Email`, strings.ToLower(email))
+}
+
+func	(s *sSQLStore) CountMembers(ctx context.Context) (int64, error) {
+	var	count int64
+	err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM members`).Scan(&count)
+	return count, err
+}
+
+func	(s *sSQLStore) ListMemberIDs(ctx context.Context, afterID string, limit int) ([]string, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT CAST(ID AS text) FROM members
+		WHERE CAST(ID AS text) > $1 ORDER BY CAST(ID AS text) LIMIT $2`, afterID, limit)
+	if (err != nil) {
+		return nil, err
+	}
+	defer rows.Close()
+
+	IDs := []string{}
+	for rows.Next() {
+		var	ID string
+		if err := rows.Scan(&ID); err != nil {
+			return nil, err
+		}
+		IDs = append(IDs, ID)
+	}
+	return IDs, rows.Err()
+}
+
+/******************************************************************************
+**	The session is stored in the row of the member
+******************************************************************************/
+func	(s *sSQLStore) GetSession(ctx context.Context, memberID string) (*sSession, error) {
+	session := &sSession{}
+	err := s.db.QueryRowContext(ctx, `SELECT
+		COALESCE(AccessToken, ''), COALESCE(AccessExp, 0), COALESCE(RefreshToken, ''), COALESCE(RefreshExp, 0)
+		FROM members WHERE ID=$1`, memberID,
+	).Scan(&session.AccessToken, &session.AccessExp, &session.RefreshToken, &session.RefreshExp)
+	if (err == sql.ErrNoRows) {
+		return nil, ErrMemberNotFound
+	} else if (err != nil) {
+		return nil, err
+	}
+	return session, nil
+}
+
+func	(s *sSQLStore) SetSession(ctx context.Context, memberID string, session *sSession) (error) {
+	_, err := s.db.ExecContext(ctx, `UPDATE members SET AccessToken=$1, AccessExp=$2, RefreshToken=$3, RefreshExp=$4 WHERE ID=$5`,
+		session.AccessToken, session.AccessExp, session.RefreshToken, session.RefreshExp, memberID,
+	)
+	return err
+}
+
+func	(s *sSQLStore) SetAccessToken(ctx context.Context, memberID, accessToken string, accessExp int64) (error) {
+	_, err := s.db.ExecContext(ctx, `UPDATE members SET AccessToken=$1, AccessExp=$2 WHERE ID=$3`, accessToken, accessExp, memberID)
+	return err
+}
+
+func	(s *sSQLStore) CountActiveSessions(ctx context.Context, now int64) (int64, error) {
+	var	count int64
+	err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM members WHERE RefreshExp > $1`, now).Scan(&count)
+	return count, err
+}
@@ -0,0 +1,557 @@
+/*******************************************************************************
+** @Author:					Thomas Bouder <Tbouder>
+** @Email:					Tbouder@protonmail.com
+** @Date:					Tuesday 14 April 2020 - 11:05:17
+** @Filename:				Store.memory.go
+**
+** @Last modified by:		Tbouder
+** @Last modified time:		Saturday 09 May 2020 - 17:31:02
+*******************************************************************************/
+
+package			main
+
+import			"math"
+import			"sort"
+import			"sync"
+import			"context"
+import			"strings"
+
+/******************************************************************************
+**	In-memory implementation of the MemberStore and the SessionStore, with
+**	the same behavior as the Postgre one : the emails are unique and stored
+**	in lowercase. It is meant for the tests and for local development, as
+**	nothing is persisted.
+******************************************************************************/
+type	sMemoryStore struct {
+	mu			sync.RWMutex
+	members		map[string]*sMember
+	sessions	map[string]*sSession
+	audit		[]*sAuditEvent
+	logins		[]*sLogin
+	devices		map[string]map[string]int64
+	reservations	map[string]*sReservation
+	plans		map[string]*sPlan
+	invitations	[]*sInvitation
+}
+
+func	newMemoryStore() (*sMemoryStore) {
+	return &sMemoryStore{
+		members:	map[string]*sMember{},
+		sessions:	map[string]*sSession{},
+		devices:	map[string]map[string]int64{},
+		reservations:	map[string]*sReservation{},
+		plans:		map[string]*sPlan{},
+	}
+}
+
+func	(s *sMemoryStore) CreateMember(ctx context.Context, member *sMember, session *sSession, redemption *sRedemption) (error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	email := strings.ToLower(member.Email)
+	for ID, stored := range s.members {
+		if (stored.Email == email || ID == member.ID) {
+			return ErrMemberAlreadyExists
+		}
+	}
+	if (redemption != nil) {
+		invitation := s.findInvitation(redemption, email)
+		if (invitation == nil) {
+			return ErrInvitationInvalid
+		}
+		invitation.Uses++
+		redemption.InvitationID = invitation.ID
+		member.PlanID = invitation.PlanID
+	}
+
+	copiedMember := *member
+	copiedMember.Email = email
+	copiedSession := *session
+	s.members[member.ID] = &copiedMember
+	s.sessions[member.ID] = &copiedSession
+	return nil
+}
+
+func	(s *sMemoryStore) UpdateMember(ctx context.Context, member *sMember) (error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.members[member.ID]
+	if (!ok) {
+		return nil
+	}
+	stored.PublicKey = member.PublicKey
+	stored.PrivateKey = member.PrivateKey
+	stored.PrivateKeyIV = member.PrivateKeyIV
+	stored.PrivateKeySalt = member.PrivateKeySalt
+	stored.PasswordArgon2Hash = member.PasswordArgon2Hash
+	stored.PasswordArgon2IV = member.PasswordArgon2IV
+	stored.PasswordScryptHash = member.PasswordScryptHash
+	stored.PasswordScryptIV = member.PasswordScryptIV
+	return nil
+}
+
+func	(s *sMemoryStore) ChangePassword(ctx context.Context, member *sMember, session *sSession) (error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.members[member.ID]
+	if (!ok || stored.PasswordChangeTokenHash != member.PasswordChangeTokenHash) {
+		return ErrMemberNotFound
+	}
+	stored.PrivateKey = member.PrivateKey
+	stored.PrivateKeyIV = member.PrivateKeyIV
+	stored.PrivateKeySalt = member.PrivateKeySalt
+	stored.PasswordArgon2Hash = member.PasswordArgon2Hash
+	stored.PasswordArgon2IV = member.PasswordArgon2IV
+	stored.PasswordScryptHash = member.PasswordScryptHash
+	stored.PasswordScryptIV = member.PasswordScryptIV
+	stored.PasswordChangeRequired = false
+	stored.PasswordChangeTokenHash = ``
+	copied := *session
+	s.sessions[member.ID] = &copied
+	return nil
+}
+
+func	(s *sMemoryStore) GetMemberByID(ctx context.Context, memberID string) (*sMember, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	member, ok := s.members[memberID]
+	if (!ok) {
+		return nil, ErrMemberNotFound
+	}
+	copied := *member
+	return &copied, nil
+}
+
+func	(s *sMemoryStore) GetMemberByEmail(ctx context.Context, email string) (*sMember, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	email = strings.ToLower(email)
+	for _, member := range s.members {
+		if (member.Email == email) {
+			copied := *member
+			return &copied, nil
+		}
+	}
+	return nil, ErrMemberNotFound
+}
+
+func	(s *sMemoryStore) GetSession(ctx context.Context, memberID string) (*sSession, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	session, ok := s.sessions[memberID]
+	if (!ok) {
+		return nil, ErrMemberNotFound
+	}
+	copied := *session
+	return &copied, nil
+}
+
+func	(s *sMemoryStore) SetSession(ctx context.Context, memberID string, session *sSession) (error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.sessions[memberID]; ok {
+		copied := *session
+		s.sessions[memberID] = &copied
+	}
+	return nil
+}
+
+func	(s *sMemoryStore) SetAccessToken(ctx context.Context, memberID, accessToken string, accessExp int64) (error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if session, ok := s.sessions[memberID]; ok {
+		session.AccessToken = accessToken
+		session.AccessExp = accessExp
+	}
+	return nil
+}
+
+func	(s *sMemoryStore) CountMembers(ctx context.Context) (int64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return int64(len(s.members)), nil
+}
+
+func	(s *sMemoryStore) ListMemberIDs(ctx context.Context, afterID string, limit int) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	IDs := []string{}
+	for ID := range s.members {
+		if (ID > afterID) {
+			IDs = append(IDs, ID)
+		}
+	}
+	sort.Strings(IDs)
+	if (len(IDs) > limit) {
+		IDs = IDs[:limit]
+	}
+	return IDs, nil
+}
+
+func	(s *sMemoryStore) CountActiveSessions(ctx context.Context, now int64) (int64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var	count int64
+	for _, session := range s.sessions {
+		if (session.RefreshExp > now) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func	(s *sMemoryStore) AppendAuditEvents(ctx context.Context, events []*sAuditEvent) (error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var	previousHash string
+	if (len(s.audit) > 0) {
+		previousHash = s.audit[len(s.audit) - 1].Hash
+	}
+	for _, event := range events {
+		event.PreviousHash = previousHash
+		event.Hash = hashAuditEvent(event)
+		copied := *event
+		copied.ID = int64(len(s.audit) + 1)
+		s.audit = append(s.audit, &copied)
+		previousHash = event.Hash
+	}
+	return nil
+}
+
+func	(s *sMemoryStore) ListAuditEvents(ctx context.Context, filter sAuditFilter) ([]*sAuditEvent, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	events := []*sAuditEvent{}
+	for index := len(s.audit) - 1; index >= 0 && len(events) < filter.Limit; index-- {
+		event := s.audit[index]
+		if (filter.MemberID != `` && event.ActorID != filter.MemberID && event.TargetID != filter.MemberID) {
+			continue
+		}
+		if ((filter.Type != `` && event.Type != filter.Type) || (filter.BeforeID != 0 && event.ID >= filter.BeforeID)) {
+			continue
+		}
+		copied := *event
+		events = append(events, &copied)
+	}
+	return events, nil
+}
+
+func	(s *sMemoryStore) ScanAuditEvents(ctx context.Context, afterID int64, limit int) ([]*sAuditEvent, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	events := []*sAuditEvent{}
+	for _, event := range s.audit {
+		if (event.ID > afterID && len(events) < limit) {
+			copied := *event
+			events = append(events, &copied)
+		}
+	}
+	return events, nil
+}
+
+func	(s *sMemoryStore) RecordLogin(ctx context.Context, login *sLogin) (error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	devices, ok := s.devices[login.MemberID]
+	if (!ok) {
+		devices = map[string]int64{}
+		s.devices[login.MemberID] = devices
+	}
+	_, seen := devices[login.Fingerprint]
+	login.NewDevice = len(devices) > 0 && !seen
+	if (!login.NewDevice) {
+		login.AlertTokenHash = ``
+		login.AlertExp = 0
+	}
+	devices[login.Fingerprint] = login.CreatedAt
+
+	copied := *login
+	copied.ID = int64(len(s.logins) + 1)
+	s.logins = append(s.logins, &copied)
+	return nil
+}
+
+func	(s *sMemoryStore) ListLogins(ctx context.Context, memberID string, limit int) ([]*sLogin, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	logins := []*sLogin{}
+	for index := len(s.logins) - 1; index >= 0 && len(logins) < limit; index-- {
+		if (s.logins[index].MemberID == memberID) {
+			copied := *s.logins[index]
+			copied.AlertTokenHash = ``
+			copied.AlertExp = 0
+			logins = append(logins, &copied)
+		}
+	}
+	return logins, nil
+}
+
+func	(s *sMemoryStore) ReportUnrecognizedLogin(ctx context.Context, alertTokenHash string, now int64) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var	memberID string
+	for _, login := range s.logins {
+		if (login.AlertTokenHash == alertTokenHash && login.AlertExp > now) {
+			memberID = login.MemberID
+		}
+	}
+	if (memberID == ``) {
+		return ``, ErrLoginAlertNotFound
+	}
+	for _, login := range s.logins {
+		if (login.MemberID == memberID) {
+			login.AlertTokenHash = ``
+			login.AlertExp = 0
+		}
+	}
+	if session, ok := s.sessions[memberID]; ok {
+		*session = sSession{}
+	}
+	if member, ok := s.members[memberID]; ok {
+		member.PasswordChangeRequired = true
+		member.PasswordChangeTokenHash = alertTokenHash
+	}
+	return memberID, nil
+}
+
+func	(s *sMemoryStore) ReserveStorage(ctx context.Context, reservation *sReservation, defaultQuota, now int64) (error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	member, ok := s.members[reservation.MemberID]
+	if (!ok) {
+		return ErrMemberNotFound
+	}
+	for ID, stored := range s.reservations {
+		if (stored.MemberID == member.ID && stored.ExpiresAt <= now) {
+			member.ReservedStorage -= stored.Bytes
+			delete(s.reservations, ID)
+		}
+	}
+	quota := member.StorageQuota
+	if plan, ok := s.plans[member.PlanID]; ok && quota <= 0 {
+		quota = plan.StorageQuota
+	}
+	if (quota <= 0) {
+		quota = defaultQuota
+	}
+	if (quota > 0 && member.UsedStorage + float64(member.ReservedStorage + reservation.Bytes) > float64(quota)) {
+		return ErrQuotaExceeded
+	}
+	member.ReservedStorage += reservation.Bytes
+	copied := *reservation
+	s.reservations[reservation.ID] = &copied
+	return nil
+}
+
+func	(s *sMemoryStore) CommitStorage(ctx context.Context, memberID, reservationID string, fullBytes, now int64) (error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	reservation, ok := s.reservations[reservationID]
+	if (!ok || reservation.MemberID != memberID || reservation.ExpiresAt <= now) {
+		return ErrReservationNotFound
+	}
+	delete(s.reservations, reservationID)
+	if (fullBytes < reservation.Bytes) {
+		fullBytes = reservation.Bytes
+	}
+	if member, ok := s.members[memberID]; ok {
+		member.ReservedStorage -= reservation.Bytes
+		member.UsedStorage += float64(reservation.Bytes)
+		member.FullUsedStorage += float64(fullBytes)
+	}
+	return nil
+}
+
+func	(s *sMemoryStore) ReleaseReservation(ctx context.Context, memberID, reservationID string) (error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	reservation, ok := s.reservations[reservationID]
+	if (!ok || reservation.MemberID != memberID) {
+		return ErrReservationNotFound
+	}
+	delete(s.reservations, reservationID)
+	if member, ok := s.members[memberID]; ok {
+		member.ReservedStorage -= reservation.Bytes
+	}
+	return nil
+}
+
+func	(s *sMemoryStore) ReleaseStorage(ctx context.Context, memberID string, bytes, fullBytes int64) (error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	member, ok := s.members[memberID]
+	if (!ok) {
+		return ErrMemberNotFound
+	}
+	member.UsedStorage = math.Max(0, member.UsedStorage - float64(bytes))
+	member.FullUsedStorage = math.Max(0, member.FullUsedStorage - float64(fullBytes))
+	return nil
+}
+
+func	(s *sMemoryStore) SetStorageQuota(ctx context.Context, memberID string, quota int64) (error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	member, ok := s.members[memberID]
+	if (!ok) {
+		return ErrMemberNotFound
+	}
+	member.StorageQuota = quota
+	return nil
+}
+
+func	(s *sMemoryStore) CorrectStorageUsage(ctx context.Context, memberID string, previous, corrected sStorageUsage) (error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	member, ok := s.members[memberID]
+	if (!ok || member.UsedStorage != previous.Used || member.FullUsedStorage != previous.Full) {
+		return ErrUsageChanged
+	}
+	member.UsedStorage = corrected.Used
+	member.FullUsedStorage = corrected.Full
+	return nil
+}
+
+func	copyPlan(plan *sPlan) (*sPlan) {
+	copied := *plan
+	copied.Features = append([]string{}, plan.Features...)
+	return &copied
+}
+
+func	(s *sMemoryStore) CreatePlan(ctx context.Context, plan *sPlan) (error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for ID, stored := range s.plans {
+		if (stored.Name == plan.Name || ID == plan.ID) {
+			return ErrPlanAlreadyExists
+		}
+	}
+	s.plans[plan.ID] = copyPlan(plan)
+	return nil
+}
+
+func	(s *sMemoryStore) UpdatePlan(ctx context.Context, plan *sPlan) (error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.plans[plan.ID]; !ok {
+		return ErrPlanNotFound
+	}
+	for ID, stored := range s.plans {
+		if (stored.Name == plan.Name && ID != plan.ID) {
+			return ErrPlanAlreadyExists
+		}
+	}
+	s.plans[plan.ID] = copyPlan(plan)
+	return nil
+}
+
+func	(s *sMemoryStore) GetPlan(ctx context.Context, planID string) (*sPlan, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	plan, ok := s.plans[planID]
+	if (!ok) {
+		return nil, ErrPlanNotFound
+	}
+	return copyPlan(plan), nil
+}
+
+func	(s *sMemoryStore) ListPlans(ctx context.Context) ([]*sPlan, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	plans := []*sPlan{}
+	for _, plan := range s.plans {
+		plans = append(plans, copyPlan(plan))
+	}
+	sort.Slice(plans, func(i, j int) bool {return plans[i].Name < plans[j].Name})
+	return plans, nil
+}
+
+func	(s *sMemoryStore) SetMemberPlan(ctx context.Context, memberID, planID string) (error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.plans[planID]; !ok && planID != `` {
+		return ErrPlanNotFound
+	}
+	member, ok := s.members[memberID]
+	if (!ok) {
+		return ErrMemberNotFound
+	}
+	member.PlanID = planID
+	return nil
+}
+
+func	(s *sMemoryStore) findInvitation(redemption *sRedemption, email string) (*sInvitation) {
+	for _, invitation := range s.invitations {
+		if (invitation.CodeHash != redemption.CodeHash || invitation.RevokedAt != 0) {
+			continue
+		}
+		if (invitation.ExpiresAt <= redemption.Now || invitation.Uses >= invitation.MaxUses) {
+			continue
+		}
+		if (invitation.Email == `` || invitation.Email == email) {
+			return invitation
+		}
+	}
+	return nil
+}
+
+func	(s *sMemoryStore) CreateInvitation(ctx context.Context, invitation *sInvitation) (error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	copied := *invitation
+	copied.Email = strings.ToLower(invitation.Email)
+	s.invitations = append(s.invitations, &copied)
+	return nil
+}
+
+func	(s *sMemoryStore) ListInvitations(ctx context.Context, limit int) ([]*sInvitation, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	invitations := []*sInvitation{}
+	for index := len(s.invitations) - 1; index >= 0 && len(invitations) < limit; index-- {
+		copied := *s.invitations[index]
+		copied.CodeHash = ``
+		invitations = append(invitations, &copied)
+	}
+	return invitations, nil
+}
+
+func	(s *sMemoryStore) RevokeInvitation(ctx context.Context, invitationID string, now int64) (error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, invitation := range s.invitations {
+		if (invitation.ID == invitationID && invitation.RevokedAt == 0) {
+			invitation.RevokedAt = now
+			return nil
+		}
+	}
+	return ErrInvitationNotFound
+}
@@ -0,0 +1,306 @@
+/*******************************************************************************
+** @Author:					Thomas Bouder <Tbouder>
+** @Email:					Tbouder@protonmail.com
+** @Date:					Wednesday 29 April 2020 - 15:02:36
+** @Filename:				Store.metrics.go
+**
+** @Last modified by:		Tbouder
+** @Last modified time:		Saturday 09 May 2020 - 17:31:02
+*******************************************************************************/
+
+
+package			main
+
+import			"time"
+import			"context"
+import			"go.opentelemetry.io/otel/api/key"
+import			"go.opentelemetry.io/otel/api/trace"
+import			"github.com/prometheus/client_golang/prometheus"
+import			"github.com/prometheus/client_golang/prometheus/promauto"
+
+/******************************************************************************
+**	Decorator of the stores, measuring the latency and the errors of every
+**	query, and tracing it as a child span of the RPC. The expected errors,
+**	like a missing member or an exceeded quota, are not counted.
+******************************************************************************/
+var		dbQueryDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+	Name:		`members_db_query_duration_seconds`,
+	Help:		`Latency of the database queries, by store operation.`,
+	Buckets:	[]float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5},
+}, []string{`operation`})
+var		dbQueryErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name:		`members_db_query_errors_total`,
+	Help:		`Failed database queries, by store operation.`,
+}, []string{`operation`})
+
+type	sInstrumentedStore struct {
+	members		MemberStore
+	sessions	SessionStore
+	audit		AuditStore
+	logins		LoginStore
+	storage		StorageStore
+	plans		PlanStore
+	invitations	InvitationStore
+}
+
+/******************************************************************************
+**	The SQL stores implement every interface
+******************************************************************************/
+type	sStore interface {
+	MemberStore
+	SessionStore
+	AuditStore
+	LoginStore
+	StorageStore
+	PlanStore
+	InvitationStore
+}
+
+func	newInstrumentedStore(store sStore) (*sInstrumentedStore) {
+	return &sInstrumentedStore{members: store, sessions: store, audit: store, logins: store, storage: store, plans: store, invitations: store}
+}
+
+func	isExpectedStoreError(err error) (bool) {
+	switch err {
+	case ErrMemberNotFound, ErrMemberAlreadyExists, ErrLoginAlertNotFound, ErrReservationNotFound, ErrQuotaExceeded,
+		ErrUsageChanged, ErrPlanNotFound, ErrPlanAlreadyExists, ErrInvitationNotFound, ErrInvitationInvalid:
+		return true
+	}
+	return false
+}
+
+func	startQuery(ctx context.Context, operation string) (context.Context, func(error)) {
+	start := time.Now()
+	ctx, span := startSpan(ctx, `db ` + operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(key.String(`db.type`, `sql`), key.String(`db.instance`, databaseDriver)),
+	)
+
+	return ctx, func(err error) {
+		dbQueryDuration.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+		if (isExpectedStoreError(err)) {
+			err = nil
+		}
+		if (err != nil) {
+			dbQueryErrors.WithLabelValues(operation).Inc()
+		}
+		endSpan(span, err)
+	}
+}
+
+func	(s *sInstrumentedStore) CreateMember(ctx context.Context, member *sMember, session *sSession, redemption *sRedemption) (error) {
+	ctx, done := startQuery(ctx, `CreateMember`)
+	err := s.members.CreateMember(ctx, member, session, redemption)
+	done(err)
+	return err
+}
+
+func	(s *sInstrumentedStore) UpdateMember(ctx context.Context, member *sMember) (error) {
+	ctx, done := startQuery(ctx, `UpdateMember`)
+	err := s.members.UpdateMember(ctx, member)
+	done(err)
+	return err
+}
+
+func	(s *sInstrumentedStore) ChangePassword(ctx context.Context, member *sMember, session *sSession) (error) {
+	ctx, done := startQuery(ctx, `ChangePassword`)
+	err := s.members.ChangePassword(ctx, member, session)
+	done(err)
+	return err
+}
+
+func	(s *sInstrumentedStore) GetMemberByID(ctx context.Context, memberID string) (*sMember, error) {
+	ctx, done := startQuery(ctx, `GetMemberByID`)
+	result, err := s.members.GetMemberByID(ctx, memberID)
+	done(err)
+	return result, err
+}
+
+func	(s *sInstrumentedStore) GetMemberByEmail(ctx context.Context, email string) (*sMember, error) {
+	ctx, done := startQuery(ctx, `GetMemberByEmail`)
+	result, err := s.members.GetMemberByEmail(ctx, email)
+	done(err)
+	return result, err
+}
+
+func	(s *sInstrumentedStore) CountMembers(ctx context.Context) (int64, error) {
+	ctx, done := startQuery(ctx, `CountMembers`)
+	result, err := s.members.CountMembers(ctx)
+	done(err)
+	return result, err
+}
+
+func	(s *sInstrumentedStore) ListMemberIDs(ctx context.Context, afterID string, limit int) ([]string, error) {
+	ctx, done := startQuery(ctx, `ListMemberIDs`)
+	result, err := s.members.ListMemberIDs(ctx, afterID, limit)
+	done(err)
+	return result, err
+}
+
+func	(s *sInstrumentedStore) GetSession(ctx context.Context, memberID string) (*sSession, error) {
+	ctx, done := startQuery(ctx, `GetSession`)
+	result, err := s.sessions.GetSession(ctx, memberID)
+	done(err)
+	return result, err
+}
+
+func	(s *sInstrumentedStore) SetSession(ctx context.Context, memberID string, session *sSession) (error) {
+	ctx, done := startQuery(ctx, `SetSession`)
+	err := s.sessions.SetSession(ctx, memberID, session)
+	done(err)
+	return err
+}
+
+func	(s *sInstrumentedStore) SetAccessToken(ctx context.Context, memberID, accessToken string, accessExp int64) (error) {
+	ctx, done := startQuery(ctx, `SetAccessToken`)
+	err := s.sessions.SetAccessToken(ctx, memberID, accessToken, accessExp)
+	done(err)
+	return err
+}
+
+func	(s *sInstrumentedStore) CountActiveSessions(ctx context.Context, now int64) (int64, error) {
+	ctx, done := startQuery(ctx, `CountActiveSessions`)
+	result, err := s.sessions.CountActiveSessions(ctx, now)
+	done(err)
+	return result, err
+}
+
+func	(s *sInstrumentedStore) AppendAuditEvents(ctx context.Context, events []*sAuditEvent) (error) {
+	ctx, done := startQuery(ctx, `AppendAuditEvents`)
+	err := s.audit.AppendAuditEvents(ctx, events)
+	done(err)
+	return err
+}
+
+func	(s *sInstrumentedStore) ListAuditEvents(ctx context.Context, filter sAuditFilter) ([]*sAuditEvent, error) {
+	ctx, done := startQuery(ctx, `ListAuditEvents`)
+	result, err := s.audit.ListAuditEvents(ctx, filter)
+	done(err)
+	return result, err
+}
+
+func	(s *sInstrumentedStore) ScanAuditEvents(ctx context.Context, afterID int64, limit int) ([]*sAuditEvent, error) {
+	ctx, done := startQuery(ctx, `ScanAuditEvents`)
+	result, err := s.audit.ScanAuditEvents(ctx, afterID, limit)
+	done(err)
+	return result, err
+}
+
+func	(s *sInstrumentedStore) RecordLogin(ctx context.Context, login *sLogin) (error) {
+	ctx, done := startQuery(ctx, `RecordLogin`)
+	err := s.logins.RecordLogin(ctx, login)
+	done(err)
+	return err
+}
+
+func	(s *sInstrumentedStore) ListLogins(ctx context.Context, memberID string, limit int) ([]*sLogin, error) {
+	ctx, done := startQuery(ctx, `ListLogins`)
+	result, err := s.logins.ListLogins(ctx, memberID, limit)
+	done(err)
+	return result, err
+}
+
+func	(s *sInstrumentedStore) ReportUnrecognizedLogin(ctx context.Context, alertTokenHash string, now int64) (string, error) {
+	ctx, done := startQuery(ctx, `ReportUnrecognizedLogin`)
+	result, err := s.logins.ReportUnrecognizedLogin(ctx, alertTokenHash, now)
+	done(err)
+	return result, err
+}
+
+func	(s *sInstrumentedStore) ReserveStorage(ctx context.Context, reservation *sReservation, defaultQuota, now int64) (error) {
+	ctx, done := startQuery(ctx, `ReserveStorage`)
+	err := s.storage.ReserveStorage(ctx, reservation, defaultQuota, now)
+	done(err)
+	return err
+}
+
+func	(s *sInstrumentedStore) CommitStorage(ctx context.Context, memberID, reservationID string, fullBytes, now int64) (error) {
+	ctx, done := startQuery(ctx, `CommitStorage`)
+	err := s.storage.CommitStorage(ctx, memberID, reservationID, fullBytes, now)
+	done(err)
+	return err
+}
+
+func	(s *sInstrumentedStore) ReleaseReservation(ctx context.Context, memberID, reservationID string) (error) {
+	ctx, done := startQuery(ctx, `ReleaseReservation`)
+	err := s.storage.ReleaseReservation(ctx, memberID, reservationID)
+	done(err)
+	return err
+}
+
+func	(s *sInstrumentedStore) ReleaseStorage(ctx context.Context, memberID string, bytes, fullBytes int64) (error) {
+	ctx, done := startQuery(ctx, `ReleaseStorage`)
+	err := s.storage.ReleaseStorage(ctx, memberID, bytes, fullBytes)
+	done(err)
+	return err
+}
+
+func	(s *sInstrumentedStore) SetStorageQuota(ctx context.Context, memberID string, quota int64) (error) {
+	ctx, done := startQuery(ctx, `SetStorageQuota`)
+	err := s.storage.SetStorageQuota(ctx, memberID, quota)
+	done(err)
+	return err
+}
+
+func	(s *sInstrumentedStore) CorrectStorageUsage(ctx context.Context, memberID string, previous, corrected sStorageUsage) (error) {
+	ctx, done := startQuery(ctx, `CorrectStorageUsage`)
+	err := s.storage.CorrectStorageUsage(ctx, memberID, previous, corrected)
+	done(err)
+	return err
+}
+
+func	(s *sInstrumentedStore) CreatePlan(ctx context.Context, plan *sPlan) (error) {
+	ctx, done := startQuery(ctx, `CreatePlan`)
+	err := s.plans.CreatePlan(ctx, plan)
+	done(err)
+	return err
+}
+
+func	(s *sInstrumentedStore) UpdatePlan(ctx context.Context, plan *sPlan) (error) {
+	ctx, done := startQuery(ctx, `UpdatePlan`)
+	err := s.plans.UpdatePlan(ctx, plan)
+	done(err)
+	return err
+}
+
+func	(s *sInstrumentedStore) GetPlan(ctx context.Context, planID string) (*sPlan, error) {
+	ctx, done := startQuery(ctx, `GetPlan`)
+	result, err := s.plans.GetPlan(ctx, planID)
+	done(err)
+	return result, err
+}
+
+func	(s *sInstrumentedStore) ListPlans(ctx context.Context) ([]*sPlan, error) {
+	ctx, done := startQuery(ctx, `ListPlans`)
+	result, err := s.plans.ListPlans(ctx)
+	done(err)
+	return result, err
+}
+
+func	(s *sInstrumentedStore) SetMemberPlan(ctx context.Context, memberID, planID string) (error) {
+	ctx, done := startQuery(ctx, `SetMemberPlan`)
+	err := s.plans.SetMemberPlan(ctx, memberID, planID)
+	done(err)
+	return err
+}
+
+func	(s *sInstrumentedStore) CreateInvitation(ctx context.Context, invitation *sInvitation) (error) {
+	ctx, done := startQuery(ctx, `CreateInvitation`)
+	err := s.invitations.CreateInvitation(ctx, invitation)
+	done(err)
+	return err
+}
+
+func	(s *sInstrumentedStore) ListInvitations(ctx context.Context, limit int) ([]*sInvitation, error) {
+	ctx, done := startQuery(ctx, `ListInvitations`)
+	result, err := s.invitations.ListInvitations(ctx, limit)
+	done(err)
+	return result, err
+}
+
+func	(s *sInstrumentedStore) RevokeInvitation(ctx context.Context, invitationID string, now int64) (error) {
+	ctx, done := startQuery(ctx, `RevokeInvitation`)
+	err := s.invitations.RevokeInvitation(ctx, invitationID, now)
+	done(err)
+	return err
+}
@@ -0,0 +1,132 @@
+/*******************************************************************************
+** @Author:					Thomas Bouder <Tbouder>
+** @Email:					Tbouder@protonmail.com
+** @Date:					Saturday 09 May 2020 - 14:02:18
+** @Filename:				Store.plans.go
+**
+** @Last modified by:		Tbouder
+** @Last modified time:		Saturday 09 May 2020 - 14:02:18
+*******************************************************************************/
+
+
+package			main
+
+import			"context"
+import			"strings"
+import			"database/sql"
+
+/******************************************************************************
+**	The plans group the limits of the members : a member without plan has
+**	no limit but the default storage quota. A limit of 0 means unlimited.
+**	The features are the names of the optional features, like sharing.
+******************************************************************************/
+type	sPlan struct {
+	ID				string
+	Name			string
+	StorageQuota	int64
+	MaxPictures		int64
+	MaxAlbums		int64
+	MaxSessions		int64
+	Features		[]string
+}
+
+type	PlanStore interface {
+	CreatePlan(ctx context.Context, plan *sPlan) (error)
+	UpdatePlan(ctx context.Context, plan *sPlan) (error)
+	GetPlan(ctx context.Context, planID string) (*sPlan, error)
+	ListPlans(ctx context.Context) ([]*sPlan, error)
+	/**************************************************************************
+	**	Move the member to the plan, or out of any plan if planID is empty
+	**************************************************************************/
+	SetMemberPlan(ctx context.Context, memberID, planID string) (error)
+}
+
+/******************************************************************************
+**	The features are stored as a comma separated list
+******************************************************************************/
+func	(s *sSQLStore) CreatePlan(ctx context.Context, plan *sPlan) (error) {
+	_, err := s.db.ExecContext(ctx, `INSERT INTO plans (
+		ID, Name, StorageQuota, MaxPictures, MaxAlbums, MaxSessions, Features
+	) VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		plan.ID, plan.Name, plan.StorageQuota, plan.MaxPictures, plan.MaxAlbums, plan.MaxSessions,
+		strings.Join(plan.Features, `,`),
+	)
+	if (s.dialect.isUniqueViolation(err)) {
+		return ErrPlanAlreadyExists
+	}
+	return err
+}
+
+func	(s *sSQLStore) UpdatePlan(ctx context.Context, plan *sPlan) (error) {
+	result, err := s.db.ExecContext(ctx, `UPDATE plans SET
+		Name=$2, StorageQuota=$3, MaxPictures=$4, MaxAlbums=$5, MaxSessions=$6, Features=$7
+		WHERE ID=$1`,
+		plan.ID, plan.Name, plan.StorageQuota, plan.MaxPictures, plan.MaxAlbums, plan.MaxSessions,
+		strings.Join(plan.Features, `,`),
+	)
+	if (s.dialect.isUniqueViolation(err)) {
+		return ErrPlanAlreadyExists
+	} else if err := rowsAffectedOrNotFound(result, err); err == ErrMemberNotFound {
+		return ErrPlanNotFound
+	} else if (err != nil) {
+		return err
+	}
+	return nil
+}
+
+func	(s *sSQLStore) queryPlans(ctx context.Context, query string, args ...interface{}) ([]*sPlan, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT
+		ID, Name, StorageQuota, MaxPictures, MaxAlbums, MaxSessions, Features
+		FROM plans ` + query, args...)
+	if (err != nil) {
+		return nil, err
+	}
+	defer rows.Close()
+
+	plans := []*sPlan{}
+	for rows.Next() {
+		var	features string
+		plan := &sPlan{}
+		err := rows.Scan(&plan.ID, &plan.Name, &plan.StorageQuota, &plan.MaxPictures, &plan.MaxAlbums, &plan.MaxSessions, &features)
+		if (err != nil) {
+			return nil, err
+		}
+		plan.Features = []string{}
+		if (features != ``) {
+			plan.Features = strings.Split(features, `,`)
+		}
+		plans = append(plans, plan)
+	}
+	return plans, rows.Err()
+}
+
+func	(s *sSQLStore) GetPlan(ctx context.Context, planID string) (*sPlan, error) {
+	plans, err := s.queryPlans(ctx, `WHERE ID=$1`, planID)
+	if (err != nil) {
+		return nil, err
+	} else if (len(plans) == 0) {
+		return nil, ErrPlanNotFound
+	}
+	return plans[0], nil
+}
+
+func	(s *sSQLStore) ListPlans(ctx context.Context) ([]*sPlan, error) {
+	return s.queryPlans(ctx, `ORDER BY Name`)
+}
+
+func	(s *sSQLStore) SetMemberPlan(ctx context.Context, memberID, planID string) (error) {
+	return s.transaction(ctx, func(tx *sql.Tx) error {
+		plan := sql.NullString{String: planID, Valid: planID != ``}
+		if (plan.Valid) {
+			var	exists int
+			err := tx.QueryRowContext(ctx, `SELECT 1 FROM plans WHERE ID=$1`, planID).Scan(&exists)
+			if (err == sql.ErrNoRows) {
+				return ErrPlanNotFound
+			} else if (err != nil) {
+				return err
+			}
+		}
+		result, err := tx.ExecContext(ctx, `UPDATE members SET PlanID=$2 WHERE ID=$1`, memberID, plan)
+		return rowsAffectedOrNotFound(result, err)
+	})
+}
@@ -0,0 +1,41 @@
+/*******************************************************************************
+** @Author:					Thomas Bouder <Tbouder>
+** @Email:					Tbouder@protonmail.com
+** @Date:					Tuesday 14 April 2020 - 10:12:58
+** @Filename:				Store.postgre.go
+**
+** @Last modified by:		Tbouder
+** @Last modified time:		Saturday 09 May 2020 - 14:02:18
+*******************************************************************************/
+
+package			main
+
+import			"context"
+import			"database/sql"
+import			"github.com/lib/pq"
+
+/******************************************************************************
+**	The Postgre dialect of the SQL store, for the installs with several
+**	replicas
+******************************************************************************/
+type	sPostgreDialect struct {}
+
+func	newPostgreStore(db *sql.DB) (*sSQLStore) {
+	return &sSQLStore{db: db, dialect: sPostgreDialect{}}
+}
+
+func	(sPostgreDialect) isUniqueViolation(err error) (bool) {
+	pqErr, ok := err.(*pq.Error)
+	return ok && pqErr.Code == `23505`
+}
+
+/******************************************************************************
+**	The replicas append concurrently : the advisory lock, held until the end
+**	of the transaction, keeps the chain linear
+******************************************************************************/
+const	AUDIT_EVENTS_ADVISORY_LOCK = 80100002
+
+func	(sPostgreDialect) lockAuditEvents(ctx context.Context, tx *sql.Tx) (error) {
+	_, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock($1)`, AUDIT_EVENTS_ADVISORY_LOCK)
+	return err
+}
@@ -0,0 +1,36 @@
+/*******************************************************************************
+** @Author:					Thomas Bouder <Tbouder>
+** @Email:					Tbouder@protonmail.com
+** @Date:					Thursday 16 April 2020 - 18:02:44
+** @Filename:				Store.sqlite.go
+**
+** @Last modified by:		Tbouder
+** @Last modified time:		Saturday 09 May 2020 - 14:02:18
+*******************************************************************************/
+
+package			main
+
+import			"context"
+import			"strings"
+import			"database/sql"
+
+/******************************************************************************
+**	The SQLite dialect of the SQL store, for the single-node installs
+******************************************************************************/
+type	sSQLiteDialect struct {}
+
+func	newSQLiteStore(db *sql.DB) (*sSQLStore) {
+	return &sSQLStore{db: db, dialect: sSQLiteDialect{}}
+}
+
+func	(sSQLiteDialect) isUniqueViolation(err error) (bool) {
+	return err != nil && strings.Contains(err.Error(), `UNIQUE constraint failed`)
+}
+
+/******************************************************************************
+**	SQLite serializes the writers : the transaction alone keeps the chain
+**	linear
+******************************************************************************/
+func	(sSQLiteDialect) lockAuditEvents(ctx context.Context, tx *sql.Tx) (error) {
+	return nil
+}
@@ -0,0 +1,195 @@
+/*******************************************************************************
+** @Author:					Thomas Bouder <Tbouder>
+** @Email:					Tbouder@protonmail.com
+** @Date:					Saturday 09 May 2020 - 14:02:18
+** @Filename:				Store.storage.go
+**
+** @Last modified by:		Tbouder
+** @Last modified time:		Saturday 09 May 2020 - 17:31:02
+*******************************************************************************/
+
+
+package			main
+
+import			"context"
+import			"database/sql"
+
+/******************************************************************************
+**	The storage of a member is reserved before an upload, and the
+**	reservation is committed to the used storage once the upload succeeded,
+**	or released if it failed. The reservations which are never committed
+**	nor released expire. A quota of 0 is replaced by the quota of the plan
+**	of the member, then by the default quota, and a default quota of 0 means
+**	no quota.
+******************************************************************************/
+type	sReservation struct {
+	ID			string
+	MemberID	string
+	Bytes		int64
+	ExpiresAt	int64
+}
+
+type	StorageStore interface {
+	/**************************************************************************
+	**	Reserve the bytes if the used and the reserved storage stay within
+	**	the quota, or return ErrQuotaExceeded
+	**************************************************************************/
+	ReserveStorage(ctx context.Context, reservation *sReservation, defaultQuota, now int64) (error)
+	/**************************************************************************
+	**	Move the bytes of the reservation to the used storage. fullBytes is
+	**	added to the full used storage.
+	**************************************************************************/
+	CommitStorage(ctx context.Context, memberID, reservationID string, fullBytes, now int64) (error)
+	ReleaseReservation(ctx context.Context, memberID, reservationID string) (error)
+	/**************************************************************************
+	**	Free the storage of deleted content, without going below 0
+	**************************************************************************/
+	ReleaseStorage(ctx context.Context, memberID string, bytes, fullBytes int64) (error)
+	SetStorageQuota(ctx context.Context, memberID string, quota int64) (error)
+	/**************************************************************************
+	**	Replace the used storage, if it is still the previous one. Returns
+	**	ErrUsageChanged if the storage was used in the meantime.
+	**************************************************************************/
+	CorrectStorageUsage(ctx context.Context, memberID string, previous, corrected sStorageUsage) (error)
+}
+
+type	sStorageUsage struct {
+	Used	float64
+	Full	float64
+}
+
+/******************************************************************************
+**	The quota is checked by the UPDATE itself, which locks the row of the
+**	member : two concurrent reservations can not both exceed the quota.
+******************************************************************************/
+func	deleteReservationsTx(ctx context.Context, tx *sql.Tx, memberID, condition string, args ...interface{}) (int64, error) {
+	rows, err := tx.QueryContext(ctx, `DELETE FROM storage_reservations WHERE MemberID=$1 AND ` + condition + ` RETURNING Bytes`,
+		append([]interface{}{memberID}, args...)...)
+	if (err != nil) {
+		return 0, err
+	}
+	defer rows.Close()
+
+	var	total, count int64
+	for rows.Next() {
+		var	bytes int64
+		if err := rows.Scan(&bytes); err != nil {
+			return 0, err
+		}
+		total += bytes
+		count++
+	}
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	if (count == 0) {
+		return 0, sql.ErrNoRows
+	}
+	return total, nil
+}
+func	unreserveTx(ctx context.Context, tx *sql.Tx, memberID string, bytes int64) (error) {
+	_, err := tx.ExecContext(ctx, `UPDATE members SET
+		ReservedStorage = CASE WHEN ReservedStorage > $2 THEN ReservedStorage - $2 ELSE 0 END
+		WHERE ID=$1`, memberID, bytes,
+	)
+	return err
+}
+const	EFFECTIVE_QUOTA_SQL = `(CASE WHEN StorageQuota > 0 THEN StorageQuota ELSE COALESCE(
+	(SELECT plans.StorageQuota FROM plans WHERE plans.ID = members.PlanID AND plans.StorageQuota > 0), CAST($3 AS bigint)
+) END)`
+
+func	(s *sSQLStore) ReserveStorage(ctx context.Context, reservation *sReservation, defaultQuota, now int64) (error) {
+	return s.transaction(ctx, func(tx *sql.Tx) error {
+		expired, err := deleteReservationsTx(ctx, tx, reservation.MemberID, `ExpiresAt <= $2`, now)
+		if (err == nil) {
+			err = unreserveTx(ctx, tx, reservation.MemberID, expired)
+		}
+		if (err != nil && err != sql.ErrNoRows) {
+			return err
+		}
+
+		result, err := tx.ExecContext(ctx, `UPDATE members SET ReservedStorage = ReservedStorage + $2
+			WHERE ID=$1 AND (` + EFFECTIVE_QUOTA_SQL + ` <= 0 OR UsedStorage + ReservedStorage + $2 <= ` + EFFECTIVE_QUOTA_SQL + `)`, reservation.MemberID, reservation.Bytes, defaultQuota,
+		)
+		if (err != nil) {
+			return err
+		}
+		if updated, err := result.RowsAffected(); err != nil {
+			return err
+		} else if (updated == 0) {
+			var	exists int
+			err := tx.QueryRowContext(ctx, `SELECT 1 FROM members WHERE ID=$1`, reservation.MemberID).Scan(&exists)
+			if (err == sql.ErrNoRows) {
+				return ErrMemberNotFound
+			} else if (err != nil) {
+				return err
+			}
+			return ErrQuotaExceeded
+		}
+
+		_, err = tx.ExecContext(ctx, `INSERT INTO storage_reservations (ID, MemberID, Bytes, ExpiresAt) VALUES ($1, $2, $3, $4)`,
+			reservation.ID, reservation.MemberID, reservation.Bytes, reservation.ExpiresAt,
+		)
+		return err
+	})
+}
+
+func	(s *sSQLStore) CommitStorage(ctx context.Context, memberID, reservationID string, fullBytes, now int64) (error) {
+	return s.transaction(ctx, func(tx *sql.Tx) error {
+		bytes, err := deleteReservationsTx(ctx, tx, memberID, `ID=$2 AND ExpiresAt > $3`, reservationID, now)
+		if (err == sql.ErrNoRows) {
+			return ErrReservationNotFound
+		} else if (err != nil) {
+			return err
+		}
+		if (fullBytes < bytes) {
+			fullBytes = bytes
+		}
+		_, err = tx.ExecContext(ctx, `UPDATE members SET
+			ReservedStorage = CASE WHEN ReservedStorage > $2 THEN ReservedStorage - $2 ELSE 0 END,
+			UsedStorage = UsedStorage + $2,
+			FullUsedStorage = FullUsedStorage + $3
+			WHERE ID=$1`, memberID, bytes, fullBytes,
+		)
+		return err
+	})
+}
+
+func	(s *sSQLStore) ReleaseReservation(ctx context.Context, memberID, reservationID string) (error) {
+	return s.transaction(ctx, func(tx *sql.Tx) error {
+		bytes, err := deleteReservationsTx(ctx, tx, memberID, `ID=$2`, reservationID)
+		if (err == sql.ErrNoRows) {
+			return ErrReservationNotFound
+		} else if (err != nil) {
+			return err
+		}
+		return unreserveTx(ctx, tx, memberID, bytes)
+	})
+}
+
+func	(s *sSQLStore) ReleaseStorage(ctx context.Context, memberID string, bytes, fullBytes int64) (error) {
+	result, err := s.db.ExecContext(ctx, `UPDATE members SET
+		UsedStorage = CASE WHEN UsedStorage > $2 THEN UsedStorage - $2 ELSE 0 END,
+		FullUsedStorage = CASE WHEN FullUsedStorage > $3 THEN FullUsedStorage - $3 ELSE 0 END
+		WHERE ID=$1`, memberID, bytes, fullBytes,
+	)
+	return rowsAffectedOrNotFound(result, err)
+}
+
+func	(s *sSQLStore) SetStorageQuota(ctx context.Context, memberID string, quota int64) (error) {
+	result, err := s.db.ExecContext(ctx, `UPDATE members SET StorageQuota=$2 WHERE ID=$1`, memberID, quota)
+	return rowsAffectedOrNotFound(result, err)
+}
+
+func	(s *sSQLStore) CorrectStorageUsage(ctx context.Context, memberID string, previous, corrected sStorageUsage) (error) {
+	result, err := s.db.ExecContext(ctx, `UPDATE members SET UsedStorage=$4, FullUsedStorage=$5
+		WHERE ID=$1 AND UsedStorage=$2 AND FullUsedStorage=$3`,
+		memberID, previous.Used, previous.Full, corrected.Used, corrected.Full,
+	)
+	if err := rowsAffectedOrNotFound(result, err); err == ErrMemberNotFound {
+		return ErrUsageChanged
+	} else if (err != nil) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,158 @@
+/*******************************************************************************
+** @Author:					Thomas Bouder <Tbouder>
+** @Email:					Tbouder@protonmail.com
+** @Date:					Friday 24 April 2020 - 09:12:50
+** @Filename:				TLS.go
+**
+** @Last modified by:		Tbouder
+** @Last modified time:		Saturday 02 May 2020 - 09:36:14
+*******************************************************************************/
+
+package			main
+
+import			"net"
+import			"sync"
+import			"time"
+import			"errors"
+import			"context"
+import			"crypto/tls"
+import			"crypto/x509"
+import			"encoding/pem"
+import			"io/ioutil"
+import			"google.golang.org/grpc/credentials"
+import			"github.com/prometheus/client_golang/prometheus"
+import			"github.com/prometheus/client_golang/prometheus/promauto"
+
+/******************************************************************************
+**	The tls.mode setting decides what happens when the certificates cannot
+**	be loaded :
+**	- required : the service refuses to start, and the bridges are not
+**	  created. This is the default.
+**	- optional : the service and the bridges fall back to plaintext, with a
+**	  warning.
+**	- disabled : plaintext only, for local development.
+******************************************************************************/
+const	TLS_MODE_REQUIRED = `required`
+const	TLS_MODE_OPTIONAL = `optional`
+const	TLS_MODE_DISABLED = `disabled`
+
+var		ErrInvalidCA = errors.New("no certificate found in the CA bundle")
+
+/******************************************************************************
+**	The effective security of the server, once started : `mutual-tls` or
+**	`plaintext`
+******************************************************************************/
+const	TLS_EFFECTIVE_MUTUAL = `mutual-tls`
+const	TLS_EFFECTIVE_PLAINTEXT = `plaintext`
+
+var		tlsEffectiveMode string
+var		tlsEffectiveModeGauge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+	Name:		`members_tls_effective_mode`,
+	Help:		`Effective security of the server : 1 for the current mode.`,
+}, []string{`mode`})
+
+func	setTLSEffectiveMode(mode string) {
+	tlsEffectiveMode = mode
+	tlsEffectiveModeGauge.Reset()
+	tlsEffectiveModeGauge.WithLabelValues(mode).Set(1)
+}
+
+/******************************************************************************
+**	Load the CA bundle and return the earliest expiry of its certificates
+******************************************************************************/
+func	loadCertPool(caCert string) (*x509.CertPool, time.Time, error) {
+	var	notAfter time.Time
+
+	ca, err := ioutil.ReadFile(caCert)
+	if (err != nil) {
+		return nil, notAfter, err
+	}
+	certPool := x509.NewCertPool()
+	for rest := ca; len(rest) > 0; {
+		var	block *pem.Block
+		block, rest = pem.Decode(rest)
+		if (block == nil) {
+			break
+		}
+		certificate, err := x509.ParseCertificate(block.Bytes)
+		if (block.Type != `CERTIFICATE` || err != nil) {
+			continue
+		}
+		certPool.AddCert(certificate)
+		if (notAfter.IsZero() || certificate.NotAfter.Before(notAfter)) {
+			notAfter = certificate.NotAfter
+		}
+	}
+	if (notAfter.IsZero()) {
+		return nil, notAfter, ErrInvalidCA
+	}
+	return certPool, notAfter, nil
+}
+
+/******************************************************************************
+**	TLS configuration of the server : the clients must present a certificate
+**	signed by the CA. The certificate and the CA are picked for each
+**	handshake, so a renewal is used without restarting the service.
+******************************************************************************/
+func	loadServerTLSConfig() (*tls.Config, error) {
+	reloader, err := newCertReloader(`server`, config.TLS.ServerCert, config.TLS.ServerKey, config.TLS.CA)
+	if (err != nil) {
+		return nil, err
+	}
+	return &tls.Config{
+		MinVersion:			tls.VersionTLS12,
+		GetConfigForClient:	func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			certificate, certPool := reloader.current()
+			return &tls.Config{
+				ClientAuth:		tls.RequireAndVerifyClientCert,
+				Certificates:	[]tls.Certificate{*certificate},
+				ClientCAs:		certPool,
+				NextProtos:		[]string{`h2`},
+				MinVersion:		tls.VersionTLS12,
+			}, nil
+		},
+	}, nil
+}
+
+/******************************************************************************
+**	TLS credentials of the bridges : the server certificate is verified
+**	against the CA and against the host name of the bridged service. All the
+**	bridges share the same client certificate.
+******************************************************************************/
+var		clientCertReloader *sCertReloader
+var		clientCertReloaderMutex sync.Mutex
+
+func	loadClientCredentials(serverName string) (credentials.TransportCredentials, error) {
+	clientCertReloaderMutex.Lock()
+	defer clientCertReloaderMutex.Unlock()
+
+	if (clientCertReloader == nil) {
+		reloader, err := newCertReloader(`client`, config.TLS.ClientCert, config.TLS.ClientKey, config.TLS.CA)
+		if (err != nil) {
+			return nil, err
+		}
+		clientCertReloader = reloader
+	}
+
+	host, _, err := net.SplitHostPort(serverName)
+	if (err != nil) {
+		host = serverName
+	}
+	return &sReloadingCredentials{reloader: clientCertReloader, serverName: host}, nil
+}
+
+/******************************************************************************
+**	Decide, according to the TLS mode, if a failure to load the certificates
+**	is fatal. Returns true if the caller can continue in plaintext.
+******************************************************************************/
+func	canFallbackToPlaintext(err error) (bool) {
+	switch config.TLS.Mode {
+	case TLS_MODE_DISABLED:
+		return true
+	case TLS_MODE_OPTIONAL:
+		logWarning(context.Background(), `Could not load the TLS certificates, using plaintext`, `error`, err)
+		return true
+	}
+	logError(context.Background(), `Could not load the TLS certificates`, `error`, err)
+	return false
+}
@@ -0,0 +1,231 @@
+/*******************************************************************************
+** @Author:					Thomas Bouder <Tbouder>
+** @Email:					Tbouder@protonmail.com
+** @Date:					Saturday 25 April 2020 - 10:04:18
+** @Filename:				TLS.reload.go
+**
+** @Last modified by:		Tbouder
+** @Last modified time:		Saturday 09 May 2020 - 15:41:09
+*******************************************************************************/
+
+
+package			main
+
+import			"os"
+import			"net"
+import			"sync"
+import			"time"
+import			"errors"
+import			"context"
+import			"strconv"
+import			"crypto/tls"
+import			"crypto/x509"
+import			"google.golang.org/grpc/credentials"
+import			"github.com/prometheus/client_golang/prometheus"
+import			"github.com/prometheus/client_golang/prometheus/promauto"
+
+/******************************************************************************
+**	The certificates are short-lived : the files are polled every
+**	tls.reloadInterval seconds, and reloaded when one of them changes. A
+**	failed reload keeps the previous certificate, and is retried once the
+**	files change again (they may be in the middle of their renewal).
+******************************************************************************/
+const	DEFAULT_TLS_RELOAD_INTERVAL = 30
+const	TLS_EXPIRY_WARNING = 24 * time.Hour
+
+/******************************************************************************
+**	The expiry (unix timestamp) of the loaded certificates, by name : server,
+**	server_ca, client and client_ca.
+******************************************************************************/
+var		tlsCertExpiry = promauto.NewGaugeVec(prometheus.GaugeOpts{
+	Name:		`members_tls_cert_expiry_timestamp_seconds`,
+	Help:		`Expiry of the loaded certificates, as a unix timestamp.`,
+}, []string{`certificate`})
+var		tlsCertReloads = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name:		`members_tls_cert_reloads_total`,
+	Help:		`Successful reloads of the certificates.`,
+}, []string{`certificate`})
+var		tlsCertReloadErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name:		`members_tls_cert_reload_errors_total`,
+	Help:		`Failed reloads of the certificates.`,
+}, []string{`certificate`})
+
+var		ErrClientOnlyCredentials = errors.New("the bridge credentials can only be used by a client")
+
+type	sCertReloader struct {
+	name			string
+	certFile		string
+	keyFile			string
+	caFile			string
+
+	mutex			sync.RWMutex
+	certificate		*tls.Certificate
+	certPool		*x509.CertPool
+	fingerprint		string
+	failed			string
+	stop			chan struct{}
+	stopped			chan struct{}
+}
+
+func	newCertReloader(name, certFile, keyFile, caFile string) (*sCertReloader, error) {
+	reloader := &sCertReloader{name: name, certFile: certFile, keyFile: keyFile, caFile: caFile}
+	if err := reloader.reload(); err != nil {
+		return nil, err
+	}
+	reloader.stop = make(chan struct{})
+	reloader.stopped = make(chan struct{})
+	go reloader.watch(time.Duration(config.TLS.ReloadInterval) * time.Second)
+	onShutdown(`tls_` + name, reloader.close)
+	return reloader, nil
+}
+
+/******************************************************************************
+**	The modification time and the size of the files, to detect a change
+**	without reading them
+******************************************************************************/
+func	(r *sCertReloader) stat() (string, error) {
+	fingerprint := ``
+	for _, path := range []string{r.certFile, r.keyFile, r.caFile} {
+		info, err := os.Stat(path)
+		if (err != nil) {
+			return ``, err
+		}
+		fingerprint += strconv.FormatInt(info.ModTime().UnixNano(), 10) + `/` + strconv.FormatInt(info.Size(), 10) + `;`
+	}
+	return fingerprint, nil
+}
+
+func	(r *sCertReloader) reload() (error) {
+	fingerprint, err := r.stat()
+	if (err != nil) {
+		return err
+	}
+	certificate, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if (err != nil) {
+		return err
+	}
+	leaf, err := x509.ParseCertificate(certificate.Certificate[0])
+	if (err != nil) {
+		return err
+	}
+	certificate.Leaf = leaf
+	certPool, caNotAfter, err := loadCertPool(r.caFile)
+	if (err != nil) {
+		return err
+	}
+
+	r.mutex.Lock()
+	r.certificate = &certificate
+	r.certPool = certPool
+	r.fingerprint = fingerprint
+	r.mutex.Unlock()
+
+	r.setExpiry(r.name, leaf.NotAfter)
+	r.setExpiry(r.name + `_ca`, caNotAfter)
+	return nil
+}
+
+func	(r *sCertReloader) setExpiry(name string, notAfter time.Time) {
+	tlsCertExpiry.WithLabelValues(name).Set(float64(notAfter.Unix()))
+
+	if remaining := time.Until(notAfter); remaining < TLS_EXPIRY_WARNING {
+		logWarning(context.Background(), `Certificate expires soon`, `certificate`, name, `remaining`, remaining.Round(time.Second))
+	}
+}
+
+/******************************************************************************
+**	Poll the files until the reloader is closed, on shutdown
+******************************************************************************/
+func	(r *sCertReloader) watch(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	defer close(r.stopped)
+
+	for {
+		select {
+		case <-ticker.C:
+			r.poll()
+		case <-r.stop:
+			return
+		}
+	}
+}
+
+func	(r *sCertReloader) close(ctx context.Context) (error) {
+	close(r.stop)
+	select {
+	case <-r.stopped:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func	(r *sCertReloader) poll() {
+	fingerprint, err := r.stat()
+	r.mutex.RLock()
+	unchanged := err == nil && fingerprint == r.fingerprint
+	r.mutex.RUnlock()
+	if (unchanged || (err == nil && fingerprint == r.failed)) {
+		return
+	}
+	if err := r.reload(); err != nil {
+		r.failed = fingerprint
+		tlsCertReloadErrors.WithLabelValues(r.name).Inc()
+		logError(context.Background(), `Could not reload the certificate, keeping the previous one`, `certificate`, r.name, `error`, err)
+		return
+	}
+	tlsCertReloads.WithLabelValues(r.name).Inc()
+	logInfo(context.Background(), `Reloaded the certificate`, `certificate`, r.name)
+}
+
+func	(r *sCertReloader) current() (*tls.Certificate, *x509.CertPool) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	return r.certificate, r.certPool
+}
+
+/******************************************************************************
+**	The gRPC TLS credentials copy their configuration, and the CA used to
+**	verify the server cannot be swapped afterwards : the bridges build a
+**	fresh configuration for each handshake instead, with the current CA.
+******************************************************************************/
+type	sReloadingCredentials struct {
+	reloader		*sCertReloader
+	serverName		string
+}
+
+func	(c *sReloadingCredentials) config() (*tls.Config) {
+	_, certPool := c.reloader.current()
+	return &tls.Config{
+		ServerName:				c.serverName,
+		RootCAs:				certPool,
+		MinVersion:				tls.VersionTLS12,
+		GetClientCertificate:	func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			certificate, _ := c.reloader.current()
+			return certificate, nil
+		},
+	}
+}
+
+func	(c *sReloadingCredentials) ClientHandshake(ctx context.Context, authority string, rawConn net.Conn) (net.Conn, credentials.AuthInfo, error) {
+	return credentials.NewTLS(c.config()).ClientHandshake(ctx, authority, rawConn)
+}
+
+func	(c *sReloadingCredentials) ServerHandshake(rawConn net.Conn) (net.Conn, credentials.AuthInfo, error) {
+	return nil, nil, ErrClientOnlyCredentials
+}
+
+func	(c *sReloadingCredentials) Info() (credentials.ProtocolInfo) {
+	return credentials.ProtocolInfo{SecurityProtocol: `tls`, SecurityVersion: `1.2`, ServerName: c.serverName}
+}
+
+func	(c *sReloadingCredentials) Clone() (credentials.TransportCredentials) {
+	clone := *c
+	return &clone
+}
+
+func	(c *sReloadingCredentials) OverrideServerName(serverName string) (error) {
+	c.serverName = serverName
+	return nil
+}
@@ -0,0 +1,188 @@
+/*******************************************************************************
+** @Author:					Thomas Bouder <Tbouder>
+** @Email:					Tbouder@protonmail.com
+** @Date:					Saturday 09 May 2020 - 15:27:51
+** @Filename:				TLS_test.go
+**
+** @Last modified by:		Tbouder
+** @Last modified time:		Saturday 09 May 2020 - 15:41:09
+*******************************************************************************/
+
+
+package			main
+
+import			"os"
+import			"time"
+import			"strings"
+import			"context"
+import			"testing"
+import			"math/big"
+import			"io/ioutil"
+import			"crypto/rand"
+import			"crypto/x509"
+import			"crypto/ecdsa"
+import			"crypto/elliptic"
+import			"encoding/pem"
+import			"path/filepath"
+import			"crypto/x509/pkix"
+
+/******************************************************************************
+**	Write a CA, and a server certificate signed by it, with the given serial
+**	number : ca.crt, server.crt and server.key
+******************************************************************************/
+func	writeTestCertificates(t *testing.T, dir string, serial int64) {
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if (err != nil) {
+		t.Fatal(err)
+	}
+	caTemplate := &x509.Certificate{
+		SerialNumber:			big.NewInt(serial),
+		Subject:				pkix.Name{CommonName: `members-test-ca`},
+		NotBefore:				time.Now().Add(-time.Hour),
+		NotAfter:				time.Now().Add(48 * time.Hour),
+		IsCA:					true,
+		KeyUsage:				x509.KeyUsageCertSign,
+		BasicConstraintsValid:	true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	if (err != nil) {
+		t.Fatal(err)
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if (err != nil) {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:	big.NewInt(serial),
+		Subject:		pkix.Name{CommonName: `members`},
+		DNSNames:		[]string{`members`},
+		NotBefore:		time.Now().Add(-time.Hour),
+		NotAfter:		time.Now().Add(48 * time.Hour),
+		KeyUsage:		x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:	[]x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, caTemplate, &key.PublicKey, caKey)
+	if (err != nil) {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if (err != nil) {
+		t.Fatal(err)
+	}
+
+	files := map[string]*pem.Block{
+		`ca.crt`:		{Type: `CERTIFICATE`, Bytes: caDER},
+		`server.crt`:	{Type: `CERTIFICATE`, Bytes: der},
+		`server.key`:	{Type: `EC PRIVATE KEY`, Bytes: keyDER},
+	}
+	modified := time.Now().Add(time.Duration(serial) * time.Minute)
+	for name, block := range files {
+		path := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(path, pem.EncodeToMemory(block), 0600); err != nil {
+			t.Fatal(err)
+		}
+		// The reloader detects a change by the modification time and the
+		// size : make sure a rotation is seen, whatever the file system
+		if err := os.Chtimes(path, modified, modified); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+/******************************************************************************
+**	Swap the TLS configuration for the duration of a test, with the files
+**	of the given directory
+******************************************************************************/
+func	useTestTLSConfig(mode, dir string) (func()) {
+	previous := config
+	config = defaultConfig()
+	config.TLS.Mode = mode
+	config.TLS.ServerCert = filepath.Join(dir, `server.crt`)
+	config.TLS.ServerKey = filepath.Join(dir, `server.key`)
+	config.TLS.CA = filepath.Join(dir, `ca.crt`)
+	config.TLS.ReloadInterval = 3600
+	return func() {config = previous}
+}
+
+func	TestTLSRequiredRefusesMissingCertificates(t *testing.T) {
+	dir, err := ioutil.TempDir(``, `tls`)
+	if (err != nil) {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer useTestTLSConfig(TLS_MODE_REQUIRED, dir)()
+
+	tlsErrors := 0
+	for _, err := range config.validate() {
+		if (strings.Contains(err.Error(), `tls.mode=required`)) {
+			tlsErrors++
+		}
+	}
+	if (tlsErrors != 3) {
+		t.Errorf("expected the three missing files to be refused, got %d errors", tlsErrors)
+	}
+
+	_, err = loadServerTLSConfig()
+	if (err == nil) {
+		t.Fatal("expected an error without the certificates")
+	}
+	if (canFallbackToPlaintext(err)) {
+		t.Errorf("tls.mode=required must not fall back to plaintext")
+	}
+	config.TLS.Mode = TLS_MODE_OPTIONAL
+	if (!canFallbackToPlaintext(err)) {
+		t.Errorf("tls.mode=optional must fall back to plaintext")
+	}
+}
+
+func	TestCertReloaderPicksUpRotatedCertificate(t *testing.T) {
+	dir, err := ioutil.TempDir(``, `tls`)
+	if (err != nil) {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer useTestTLSConfig(TLS_MODE_REQUIRED, dir)()
+	writeTestCertificates(t, dir, 1)
+
+	previousHooks := shutdownHooks
+	shutdownHooks = nil
+	defer func() {shutdownHooks = previousHooks}()
+
+	reloader, err := newCertReloader(`server`, config.TLS.ServerCert, config.TLS.ServerKey, config.TLS.CA)
+	if (err != nil) {
+		t.Fatal(err)
+	}
+	serial := func() (int64) {
+		certificate, _ := reloader.current()
+		return certificate.Leaf.SerialNumber.Int64()
+	}
+	if (serial() != 1) {
+		t.Fatalf("expected the first certificate, got the serial %d", serial())
+	}
+
+	writeTestCertificates(t, dir, 2)
+	reloader.poll()
+	if (serial() != 2) {
+		t.Errorf("expected the rotated certificate, got the serial %d", serial())
+	}
+
+	modified := time.Now().Add(time.Hour)
+	if err := ioutil.WriteFile(config.TLS.ServerCert, []byte(`renewing`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	os.Chtimes(config.TLS.ServerCert, modified, modified)
+	reloader.poll()
+	if (serial() != 2) {
+		t.Errorf("a failed reload must keep the previous certificate, got the serial %d", serial())
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	runShutdownHooks(ctx)
+	select {
+	case <-reloader.stopped:
+	case <-ctx.Done():
+		t.Errorf("the watch must stop on shutdown")
+	}
+}
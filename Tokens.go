@@ -5,14 +5,12 @@
 ** @Filename:				Tokens.go
 **
 ** @Last modified by:		Tbouder
-** @Last modified time:		Tuesday 28 January 2020 - 18:15:52
+** @Last modified time:		Saturday 02 May 2020 - 09:36:14
 *******************************************************************************/
 
 package			main
 
-import			"os"
 import			"time"
-import			"github.com/microgolang/logs"
 import			jwtGo "github.com/dgrijalva/jwt-go"
 
 const	ACCESS_TOKEN_EXPIRATION_DURATION = 5 * time.Minute
@@ -32,7 +30,7 @@ func	SetAccessToken(memberID string) (string, int64, error) {
 		},
 	}
 	token := jwtGo.NewWithClaims(jwtGo.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString([]byte(os.Getenv("JWT_ACCESS_TOKEN_KEY")))
+	tokenString, err := token.SignedString([]byte(config.Keys.JWTAccess))
 	if (err != nil) {
 		return ``, 0, err
 	}
@@ -42,7 +40,7 @@ func	GetAccessToken(accessTokenStr string) (*jwtGo.Token, *JWTClaims, error) {
 	claims := &JWTClaims{}
 
 	token, err := jwtGo.ParseWithClaims(accessTokenStr, claims, func(token *jwtGo.Token) (interface{}, error) {
-		return []byte(os.Getenv("JWT_ACCESS_TOKEN_KEY")), nil
+		return []byte(config.Keys.JWTAccess), nil
 	})
 	if (err != nil) {
 		return token, claims, err
@@ -59,7 +57,7 @@ func	SetRefreshToken(memberID string) (string, int64, error) {
 		},
 	}
 	token := jwtGo.NewWithClaims(jwtGo.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString([]byte(os.Getenv("JWT_REFRESH_TOKEN_KEY")))
+	tokenString, err := token.SignedString([]byte(config.Keys.JWTRefresh))
 	if (err != nil) {
 		return ``, 0, err
 	}
@@ -69,10 +67,9 @@ func	GetRefreshToken(refreshTokenStr string) (*jwtGo.Token, *JWTClaims, error) {
 	claims := &JWTClaims{}
 
 	token, err := jwtGo.ParseWithClaims(refreshTokenStr, claims, func(token *jwtGo.Token) (interface{}, error) {
-		return []byte(os.Getenv("JWT_REFRESH_TOKEN_KEY")), nil
+		return []byte(config.Keys.JWTRefresh), nil
 	})
 	if (err != nil) {
-		logs.Error(err)
 		return &jwtGo.Token{}, &JWTClaims{}, err
 	}
 	return token, claims, nil
@@ -0,0 +1,160 @@
+/*******************************************************************************
+** @Author:					Thomas Bouder <Tbouder>
+** @Email:					Tbouder@protonmail.com
+** @Date:					Thursday 30 April 2020 - 10:12:45
+** @Filename:				Tracing.go
+**
+** @Last modified by:		Tbouder
+** @Last modified time:		Thursday 30 April 2020 - 10:12:45
+*******************************************************************************/
+
+
+package			main
+
+import			"os"
+import			"context"
+import			"google.golang.org/grpc"
+import			"google.golang.org/grpc/codes"
+import			"google.golang.org/grpc/status"
+import			"google.golang.org/grpc/metadata"
+import			"go.opentelemetry.io/otel/api/key"
+import			"go.opentelemetry.io/otel/api/trace"
+import			"go.opentelemetry.io/otel/api/global"
+import			"go.opentelemetry.io/otel/exporters/otlp"
+import			"go.opentelemetry.io/otel/plugin/grpctrace"
+import			"go.opentelemetry.io/otel/exporters/trace/stdout"
+import			sdktrace "go.opentelemetry.io/otel/sdk/trace"
+
+/******************************************************************************
+**	The RPCs, the password hashing, the database queries and the calls to
+**	the bridges are traced with OpenTelemetry. The W3C trace context is read
+**	from the incoming gRPC metadata and written in the outgoing one, so a
+**	login can be followed from the Proxy. The spans are sent according to
+**	tracing.exporter :
+**	- none : no span is recorded. This is the default.
+**	- otlp : to the OTLP collector at tracing.endpoint.
+**	- stdout : as JSON, to tracing.file, or to the standard output if empty,
+**	  for local testing.
+******************************************************************************/
+const	TRACING_NONE = `none`
+const	TRACING_OTLP = `otlp`
+const	TRACING_STDOUT = `stdout`
+const	DEFAULT_TRACING_ENDPOINT = `localhost:55680`
+const	TRACER_NAME = `github.com/panghostlin/Members`
+
+var		tracer = global.Tracer(TRACER_NAME)
+
+func	initTracing() (error) {
+	var	processor sdktrace.SpanProcessor
+	var	stop func() error
+
+	switch config.Tracing.Exporter {
+	case TRACING_OTLP:
+		exporter, err := otlp.NewExporter(otlp.WithInsecure(), otlp.WithAddress(config.Tracing.Endpoint))
+		if (err != nil) {
+			return err
+		}
+		batcher, err := sdktrace.NewBatchSpanProcessor(exporter)
+		if (err != nil) {
+			return err
+		}
+		processor, stop = batcher, exporter.Stop
+	case TRACING_STDOUT:
+		output := os.Stdout
+		stop = func() error {return nil}
+		if (config.Tracing.File != ``) {
+			file, err := os.OpenFile(config.Tracing.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+			if (err != nil) {
+				return err
+			}
+			output, stop = file, file.Close
+		}
+		exporter, err := stdout.NewExporter(stdout.Options{Writer: output})
+		if (err != nil) {
+			return err
+		}
+		processor = sdktrace.NewSimpleSpanProcessor(exporter)
+	default:
+		return nil
+	}
+
+	provider, err := sdktrace.NewProvider(sdktrace.WithResourceAttributes(key.String(`service.name`, `members`)))
+	if (err != nil) {
+		return err
+	}
+	provider.RegisterSpanProcessor(processor)
+	global.SetTraceProvider(provider)
+
+	/**************************************************************************
+	**	Unregistering the processor flushes the pending spans
+	**************************************************************************/
+	onShutdown(`tracing`, func(ctx context.Context) error {
+		provider.UnregisterSpanProcessor(processor)
+		return stop()
+	})
+	return nil
+}
+
+/******************************************************************************
+**	Start a child span of the span in the context. endSpan records the error,
+**	if any, before ending it.
+******************************************************************************/
+func	startSpan(ctx context.Context, name string, options ...trace.StartOption) (context.Context, trace.Span) {
+	return tracer.Start(ctx, name, options...)
+}
+
+func	endSpan(span trace.Span, err error) {
+	if (err != nil) {
+		span.SetStatus(codes.Unknown, err.Error())
+	}
+	span.End()
+}
+
+/******************************************************************************
+**	gRPC interceptors creating a span for each RPC, as a child of the caller
+**	span, and propagating the trace context to the bridged services. The
+**	grpctrace interceptors cannot be used, as they fail on the methods of
+**	services without a package, like MembersService. Only the unary RPCs are
+**	traced.
+******************************************************************************/
+func	tracingInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	incoming, _ := metadata.FromIncomingContext(ctx)
+	incomingCopy := incoming.Copy()
+	_, remoteSpan := grpctrace.Extract(ctx, &incomingCopy)
+
+	ctx, span := tracer.Start(trace.ContextWithRemoteSpanContext(ctx, remoteSpan), info.FullMethod,
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(key.String(`rpc.method`, info.FullMethod)),
+	)
+	defer span.End()
+
+	resp, err := handler(ctx, req)
+	if (err != nil) {
+		st, _ := status.FromError(err)
+		span.SetStatus(st.Code(), st.Message())
+	}
+	return resp, err
+}
+
+func	tracingClientInterceptor(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) (error) {
+	ctx, span := tracer.Start(ctx, method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(key.String(`rpc.method`, method), key.String(`peer.address`, cc.Target())),
+	)
+	defer span.End()
+
+	outgoing, ok := metadata.FromOutgoingContext(ctx)
+	if (ok) {
+		outgoing = outgoing.Copy()
+	} else {
+		outgoing = metadata.MD{}
+	}
+	grpctrace.Inject(ctx, &outgoing)
+
+	err := invoker(metadata.NewOutgoingContext(ctx, outgoing), method, req, reply, cc, opts...)
+	if (err != nil) {
+		st, _ := status.FromError(err)
+		span.SetStatus(st.Code(), st.Message())
+	}
+	return err
+}
@@ -3,13 +3,18 @@ module github.com/panghostlin/Members
 go 1.13
 
 require (
-	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/dgrijalva/jwt-go v3.2.0+incompatible
-	github.com/fatih/color v1.9.0 // indirect
+	github.com/golang/protobuf v1.3.4
+	github.com/grpc-ecosystem/go-grpc-prometheus v1.2.0
 	github.com/lib/pq v1.3.0
-	github.com/microgolang/logs v0.0.0-20191128163715-df5826543c89
-	github.com/microgolang/postgre v0.0.0-20200206183946-fb501c758fd4
 	github.com/panghostlin/SDK v0.0.0-20200309180857-7ead012a6dd5
-	golang.org/x/crypto v0.0.0-20190308221718-c2843e01d9a2
+	github.com/prometheus/client_golang v1.5.1
+	go.opentelemetry.io/otel v0.4.3
+	go.opentelemetry.io/otel/exporters/otlp v0.4.3
+	golang.org/x/crypto v0.0.0-20200622213623-75b288015ac9
+	golang.org/x/sync v0.0.0-20201020160332-67f06af15bc9
+	google.golang.org/genproto v0.0.0-20191009194640-548a555dbc03
 	google.golang.org/grpc v1.28.1
+	gopkg.in/yaml.v2 v2.2.8
+	modernc.org/sqlite v1.10.6
 )
@@ -5,27 +5,56 @@
 ** @Filename:				main.go
 **
 ** @Last modified by:		Tbouder
-** @Last modified time:		Monday 09 March 2020 - 12:11:06
+** @Last modified time:		Saturday 09 May 2020 - 17:31:02
 *******************************************************************************/
 
 package			main
 
-import			"log"
 import			"os"
 import			"net"
-import			"crypto/tls"
-import			"crypto/x509"
-import			"io/ioutil"
+import			"context"
 import			"database/sql"
-import			"github.com/microgolang/logs"
 import			"google.golang.org/grpc"
 import			"google.golang.org/grpc/credentials"
 import			"github.com/panghostlin/SDK/Members"
 import			"github.com/panghostlin/SDK/Pictures"
 import			_ "github.com/lib/pq"
+import			_ "modernc.org/sqlite"
 
-type	server struct {}
-var		PGR *sql.DB
+type	server struct {
+	memberStore		MemberStore
+	sessionStore	SessionStore
+	auditStore		AuditStore
+	loginStore		LoginStore
+	storageStore	StorageStore
+	planStore		PlanStore
+	invitationStore	InvitationStore
+}
+
+/******************************************************************************
+**	The database is Postgre by default. Single-node installs can use an
+**	embedded SQLite database instead, with database.driver set to sqlite and
+**	the path of the database file in database.sqlitePath.
+******************************************************************************/
+const	DRIVER_POSTGRE = `postgres`
+const	DRIVER_SQLITE = `sqlite`
+const	DEFAULT_SQLITE_PATH = `/data/members.db`
+
+var		DB *sql.DB
+var		databaseDriver = DRIVER_POSTGRE
+
+func	newStore() (*sInstrumentedStore) {
+	if (databaseDriver == DRIVER_SQLITE) {
+		return newInstrumentedStore(newSQLiteStore(DB))
+	}
+	return newInstrumentedStore(newPostgreStore(DB))
+}
+func	newServer() (*server) {
+	return newServerWithStore(newStore())
+}
+func	newServerWithStore(store sStore) (*server) {
+	return &server{memberStore: store, sessionStore: store, auditStore: store, loginStore: store, storageStore: store, planStore: store, invitationStore: store}
+}
 
 type	sClients	struct {
 	members		members.MembersServiceClient
@@ -36,174 +65,230 @@ var		bridges map[string](*grpc.ClientConn)
 var		clients = &sClients{}
 
 func	connectToDatabase() {
-	username := os.Getenv("POSTGRE_USERNAME")
-	password := os.Getenv("POSTGRE_PWD")
-	host := os.Getenv("POSTGRE_URI")
-	dbName := os.Getenv("POSTGRE_DB")
-	connStr := "user=" + username + " password=" + password + " dbname=" + dbName + " host=" + host + " sslmode=disable"
-	PGR, _ = sql.Open("postgres", connStr)
-
-	PGR.Exec(`CREATE extension if not exists "uuid-ossp";`)
-	PGR.Exec(`CREATE TABLE if not exists members(
-		ID uuid NOT NULL DEFAULT uuid_generate_v4(),
-		Email varchar NULL,
-		AccessToken varchar NULL,
-		AccessExp bigint,
-		RefreshToken varchar,
-		RefreshExp bigint,
-
-		PublicKey varchar NULL,
-		PrivateKey varchar NULL,
-		PrivateKeyIV varchar NULL,
-		PrivateKeySalt varchar NULL,
-
-		PasswordArgon2Hash varchar NULL,
-		PasswordArgon2IV varchar NULL,
-		PasswordScryptHash varchar NULL,
-		PasswordScryptIV varchar NULL,
-
-		UsedStorage float8 NOT NULL DEFAULT 0,
-		FullUsedStorage float8 NOT NULL DEFAULT 0,
-
-		CONSTRAINT members_pk PRIMARY KEY (ID),
-		CONSTRAINT members_un UNIQUE (Email)
-	);`)
-
-	/**************************************************************************
-	**	Create a function to transform all user emails to lowercase on insert
-	**	or update
-	**************************************************************************/
-	PGR.Exec(`CREATE or REPLACE function tolowercase() RETURNS trigger language plpgsql as $$ BEGIN new.Email := lower(new.Email); return new; END; $$;;`)
-	PGR.Exec(`CREATE trigger emailToLowerCase BEFORE INSERT or UPDATE on members for each row execute function tolowercase();`)
+	var	db *sql.DB
+	var	err error
 
-	logs.Success(`Connected to DB - Localhost`)
-}
-func	bridgeInsecureMicroservice(serverName string, clientMS string) (*grpc.ClientConn) {
-	logs.Warning("Using insecure connection")
-	conn, err := grpc.Dial(serverName, grpc.WithInsecure())
-    if err != nil {
-		logs.Error("Did not connect", err)
-		return nil
+	databaseDriver = config.Database.Driver
+	if (databaseDriver == DRIVER_SQLITE) {
+		db, err = sql.Open(DRIVER_SQLITE, config.Database.SQLitePath)
+		if (err == nil) {
+			/******************************************************************
+			**	SQLite allows only one writer at a time : a single connection
+			**	avoids the `database is locked` errors. The foreign keys are
+			**	enforced per connection, the single one is enough.
+			******************************************************************/
+			db.SetMaxOpenConns(1)
+			_, err = db.Exec(`PRAGMA journal_mode=WAL; PRAGMA busy_timeout=5000; PRAGMA foreign_keys=ON;`)
+		}
+	} else {
+		username := config.Database.Username
+		password := config.Database.Password
+		host := config.Database.Host
+		dbName := config.Database.Name
+		connStr := "user=" + username + " password=" + password + " dbname=" + dbName + " host=" + host + " sslmode=disable"
+		db, err = sql.Open(DRIVER_POSTGRE, connStr)
 	}
-
-	if (clientMS == `members`) {
-		clients.members = members.NewMembersServiceClient(conn)
-	} else if (clientMS == `pictures`) {
-		clients.pictures = pictures.NewPicturesServiceClient(conn)
-		clients.albums = pictures.NewAlbumsServiceClient(conn)
+	if (err != nil) {
+		logFatal(`Failed to open the database`, `error`, err)
 	}
+	if err := db.Ping(); err != nil {
+		logFatal(`Failed to connect to the database`, `error`, err)
+	}
+	DB = db
+	onShutdown(`database`, func(ctx context.Context) error {return DB.Close()})
 
-	return conn
+	logInfo(context.Background(), `Connected to the database`, `driver`, databaseDriver)
 }
 func	bridgeMicroservice(serverName string, clientMS string) (*grpc.ClientConn) {
-	crt := `/env/client.crt`
-    key := `/env/client.key`
-	caCert  := `/env/ca.crt`
+	var	dialOption grpc.DialOption
 
-    certificate, err := tls.LoadX509KeyPair(crt, key)
-    if err != nil {
-		logs.Warning("Did not connect: " + err.Error())
-		return bridgeInsecureMicroservice(serverName, clientMS)
-    }
+	if (config.TLS.Mode == TLS_MODE_DISABLED) {
+		dialOption = grpc.WithInsecure()
+	} else if creds, err := loadClientCredentials(serverName); err == nil {
+		dialOption = grpc.WithTransportCredentials(creds)
+	} else if (canFallbackToPlaintext(err)) {
+		dialOption = grpc.WithInsecure()
+	} else {
+		logError(context.Background(), `Did not connect to the bridge`, `bridge`, clientMS, `error`, err)
+		return nil
+	}
 
-    certPool := x509.NewCertPool()
-    ca, err := ioutil.ReadFile(caCert)
+	conn, err := grpc.Dial(serverName, dialOption, grpc.WithChainUnaryInterceptor(tracingClientInterceptor, requestIDClientInterceptor))
     if err != nil {
-		logs.Warning("Did not connect: " + err.Error())
-		return bridgeInsecureMicroservice(serverName, clientMS)
-    }
-
-    if ok := certPool.AppendCertsFromPEM(ca); !ok {
-		logs.Warning("Did not connect: " + err.Error())
-		return bridgeInsecureMicroservice(serverName, clientMS)
-    }
-
-    creds := credentials.NewTLS(&tls.Config{
-        ServerName:   serverName,
-        Certificates: []tls.Certificate{certificate},
-		RootCAs:      certPool,
-		InsecureSkipVerify: true,
-    })
+		logError(context.Background(), `Did not connect to the bridge`, `bridge`, clientMS, `error`, err)
+		return nil
+	}
 
-	conn, err := grpc.Dial(serverName, grpc.WithTransportCredentials(creds))
-    if err != nil {
-		logs.Warning("Did not connect: " + err.Error())
-		return bridgeInsecureMicroservice(serverName, clientMS)
+	if (bridges == nil) {
+		bridges = map[string](*grpc.ClientConn){}
+		onShutdown(`bridges`, closeBridges)
 	}
+	bridges[clientMS] = conn
 
 	if (clientMS == `members`) {
 		clients.members = members.NewMembersServiceClient(conn)
 	} else if (clientMS == `pictures`) {
 		clients.pictures = pictures.NewPicturesServiceClient(conn)
+		clients.albums = pictures.NewAlbumsServiceClient(conn)
 	}
 
 	return conn
 }
-func	serveInsecureMicroservice() {
-    lis, err := net.Listen(`tcp`, `:8010`)
-    if err != nil {
-		log.Fatalf("Failed to listen: %v", err)
-    }
-
-	srv := grpc.NewServer()
-	members.RegisterMembersServiceServer(srv, &server{})
-	logs.Success(`Running on port: :8010`)
-	if err := srv.Serve(lis); err != nil {
-		logs.Error(err)
-		log.Fatalf("failed to serve: %v", err)
+func	closeBridges(ctx context.Context) (error) {
+	for clientMS, conn := range bridges {
+		if err := conn.Close(); err != nil {
+			logError(ctx, `Failed to close the bridge`, `bridge`, clientMS, `error`, err)
+		}
 	}
+	return nil
 }
-func	serveMicroservice() {
-	crt := `/env/server.crt`
-    key := `/env/server.key`
-	caCert  := `/env/ca.crt`
-	
-	certificate, err := tls.LoadX509KeyPair(crt, key)
-    if err != nil {
-		logs.Warning("could not load server key pair : " + err.Error())
-		logs.Warning("Using insecure connection")
-		serveInsecureMicroservice()
-    }
+func	serveMicroservice() (int) {
+	var	options []grpc.ServerOption
+	interceptors := []grpc.UnaryServerInterceptor{tracingInterceptor, loggingInterceptor, grpcMetrics.UnaryServerInterceptor(), errorsInterceptor}
 
-    // Create a certificate pool from the certificate authority
-    certPool := x509.NewCertPool()
-    ca, err := ioutil.ReadFile(caCert)
-    if err != nil {
-        log.Fatalf("could not read ca certificate: %s", err)
-    }
-
-    // Append the client certificates from the CA
-    if ok := certPool.AppendCertsFromPEM(ca); !ok {
-        log.Fatalf("failed to append client certs")
-    }
+	/**************************************************************************
+	**	Create the TLS credentials, according to the TLS mode
+	**************************************************************************/
+	setTLSEffectiveMode(TLS_EFFECTIVE_PLAINTEXT)
+	if (config.TLS.Mode != TLS_MODE_DISABLED) {
+		tlsConfig, err := loadServerTLSConfig()
+		if (err == nil) {
+			options = append(options, grpc.Creds(credentials.NewTLS(tlsConfig)))
+			setTLSEffectiveMode(TLS_EFFECTIVE_MUTUAL)
+		} else if (!canFallbackToPlaintext(err)) {
+			logFatal(`TLS is required`, `mode`, config.TLS.Mode)
+		}
+	}
+	if (tlsEffectiveMode == TLS_EFFECTIVE_MUTUAL) {
+		logInfo(context.Background(), `Security mode: mutual TLS`, `mode`, config.TLS.Mode)
+		interceptors = append(interceptors, authorizationInterceptor)
+	} else {
+		logWarning(context.Background(), `Security mode: PLAINTEXT, the callers are not authorized`, `mode`, config.TLS.Mode)
+	}
+	options = append(options, grpc.ChainUnaryInterceptor(interceptors...))
+	options = append(options, grpc.StreamInterceptor(grpcMetrics.StreamServerInterceptor()))
 
     // Create the channel to listen on
-    lis, err := net.Listen(`tcp`, `:8010`)
+    lis, err := net.Listen(`tcp`, `:` + config.Server.Port)
     if err != nil {
-		log.Fatalf("Failed to listen: %v", err)
+		logFatal(`Failed to listen`, `port`, config.Server.Port, `error`, err)
     }
 
-    // Create the TLS credentials
-    creds := credentials.NewTLS(&tls.Config{
-    	ClientAuth:   tls.RequireAndVerifyClientCert,
-    	Certificates: []tls.Certificate{certificate},
-    	ClientCAs:    certPool,
-	})
-
     // Create the gRPC server with the credentials
-    srv := grpc.NewServer(grpc.Creds(creds))
+    srv := grpc.NewServer(options...)
 
 	// Register the handler object
-	members.RegisterMembersServiceServer(srv, &server{})
+	service := newServer()
+	members.RegisterMembersServiceServer(srv, service)
+	registerExtendedService(srv, service)
+	auditOutbox = startAuditOutbox(service.auditStore)
+	mailOutbox = startMailOutbox()
+	startReconciler(service)
+	serveHealth(srv)
+	serveMetrics(srv, service)
 
-    // Serve and Listen
-	logs.Success(`Running on port: :8010`)
-	if err := srv.Serve(lis); err != nil {
-		log.Fatalf("failed to serve: %v", err)
+	/**************************************************************************
+	**	Load the authorization policy, once the methods are registered
+	**************************************************************************/
+	policy, err := loadAuthorizationPolicy(config.Authorization.Policy)
+	if (err == nil) {
+		err = policy.check(srv.GetServiceInfo())
+	}
+	if (err != nil) {
+		logFatal(`Invalid authorization policy`, `error`, err)
 	}
+	authorizationPolicy = policy
+
+    // Serve and Listen, until SIGTERM or SIGINT
+	logInfo(context.Background(), `Running`, `port`, config.Server.Port)
+	return serveUntilSignal(srv, func() error {return srv.Serve(lis)})
 }
 
 func	main()	{
+	loadedConfig, args, err := loadConfig(os.Args[1:])
+	if (err != nil) {
+		logFatal(`Failed to load the configuration`, `error`, err)
+	}
+	config = loadedConfig
+	initLogger()
+
+	/**************************************************************************
+	**	`members config check` only validates the configuration
+	**************************************************************************/
+	if (len(args) > 0 && args[0] == `config`) {
+		if err := runConfigCommand(args[1:]); err != nil {
+			logFatal(`Invalid configuration`, `error`, err)
+		}
+		return
+	}
+	if errs := config.validate(); len(errs) > 0 {
+		for _, err := range errs {
+			logError(context.Background(), `Invalid configuration`, `error`, err)
+		}
+		logFatal(`Invalid configuration`, `errors`, len(errs))
+	}
+	hashPool = initHashPool()
+	pepper = initPepper()
+	policy, err := initPasswordPolicy()
+	if (err != nil) {
+		logFatal(`Failed to load the banned passwords`, `path`, config.Password.BannedList, `error`, err)
+	}
+	passwordPolicy = policy
+	if err := initTracing(); err != nil {
+		logFatal(`Failed to initialize the tracing`, `error`, err)
+	}
+
 	connectToDatabase()
-	serveMicroservice()
+
+	/**************************************************************************
+	**	`members migrate up|down|status` only manages the database schema
+	**************************************************************************/
+	if (len(args) > 0 && args[0] == `migrate`) {
+		if err := runMigrateCommand(args[1:]); err != nil {
+			logFatal(`Failed to migrate`, `error`, err)
+		}
+		return
+	}
+
+	/**************************************************************************
+	**	`members audit verify` only checks the audit chain : it runs before
+	**	the automatic migration, and refuses an out of date schema
+	**************************************************************************/
+	if (len(args) > 0 && args[0] == `audit`) {
+		if err := checkMigrationsApplied(context.Background()); err != nil {
+			logFatal(`Failed to verify the audit events`, `error`, err)
+		}
+		if err := runAuditCommand(args[1:], newStore()); err != nil {
+			logFatal(`Failed to verify the audit events`, `error`, err)
+		}
+		return
+	}
+
+	if err := migrateUp(context.Background()); err != nil {
+		logFatal(`Failed to migrate`, `error`, err)
+	}
+	markMigrationsApplied()
+
+	/**************************************************************************
+	**	`members reconcile [--usage FILE] [--member ID]` only reconciles the
+	**	storage usage, and flushes the audit events of the corrections
+	**	before exiting
+	**************************************************************************/
+	if (len(args) > 0 && args[0] == `reconcile`) {
+		service := newServer()
+		auditOutbox = startAuditOutbox(service.auditStore)
+		err := runReconcileCommand(args[1:], service)
+		ctx, cancel := context.WithTimeout(context.Background(), SHUTDOWN_HOOKS_TIMEOUT)
+		runShutdownHooks(ctx)
+		cancel()
+		if (err != nil) {
+			logFatal(`Failed to reconcile the storage`, `error`, err)
+		}
+		return
+	}
+
+	if _, _, err := getDummyPasswordHash(); err != nil {
+		logFatal(`Could not generate the dummy password hash`, `error`, err)
+	}
+	os.Exit(serveMicroservice())
 }
@@ -0,0 +1,86 @@
+/*******************************************************************************
+** @Author:					Thomas Bouder <Tbouder>
+** @Email:					Tbouder@protonmail.com
+** @Date:					Friday 08 May 2020 - 10:41:17
+** @Filename:				main_test.go
+**
+** @Last modified by:		Tbouder
+** @Last modified time:		Saturday 09 May 2020 - 14:51:40
+*******************************************************************************/
+
+
+package			main
+
+import			"os"
+import			"context"
+import			"testing"
+import			"crypto/rand"
+import			"encoding/base64"
+import			"database/sql"
+import			"google.golang.org/grpc/codes"
+import			"google.golang.org/grpc/status"
+
+/******************************************************************************
+**	The tests run the RPCs against the in-memory store, or SQLite in memory,
+**	with a fresh random master key and without pepper. The audit and mail
+**	outboxes are not started : their events are dropped.
+******************************************************************************/
+const	TEST_PASSWORD = `kX9#pL2m-zzQ`
+
+func	TestMain(m *testing.M) {
+	masterKey := make([]byte, 32)
+	if _, err := rand.Read(masterKey); err != nil {
+		panic(err)
+	}
+	config = defaultConfig()
+	config.Log.Level = LOG_ERROR
+	config.Keys.Master = base64.RawStdEncoding.EncodeToString(masterKey)
+	config.Keys.JWTAccess = `test-access-key`
+	config.Keys.JWTRefresh = `test-refresh-key`
+	initLogger()
+
+	hashPool = initHashPool()
+	pepper = initPepper()
+	policy, err := initPasswordPolicy()
+	if (err != nil) {
+		panic(err)
+	}
+	passwordPolicy = policy
+	if _, _, err := getDummyPasswordHash(); err != nil {
+		panic(err)
+	}
+	os.Exit(m.Run())
+}
+
+func	newTestServer() (*server, *sMemoryStore) {
+	store := newMemoryStore()
+	return newServerWithStore(store), store
+}
+
+/******************************************************************************
+**	A SQLite database in memory, with the whole schema
+******************************************************************************/
+func	newSQLiteTestStore(t *testing.T) (*sSQLStore, func()) {
+	db, err := sql.Open(DRIVER_SQLITE, `:memory:`)
+	if (err != nil) {
+		t.Fatal(err)
+	}
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec(`PRAGMA foreign_keys=ON;`); err != nil {
+		t.Fatal(err)
+	}
+	for _, migration := range migrations {
+		if _, err := db.Exec(migration.sqliteUp); err != nil {
+			t.Fatalf("migration %d: %v", migration.version, err)
+		}
+	}
+	return newSQLiteStore(db), func() {db.Close()}
+}
+
+/******************************************************************************
+**	The code of an error returned by a method of the server, as the client
+**	receives it
+******************************************************************************/
+func	statusCode(err error) (codes.Code) {
+	return status.Code(toStatusError(context.Background(), err))
+}